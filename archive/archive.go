@@ -2,24 +2,36 @@ package archive
 
 import (
 	"compress/flate"
+	"errors"
+	"fmt"
 	"io"
+	"runtime"
 
 	"github.com/go-kit/log"
-	"github.com/go-kit/log/level"
 	"github.com/meltwater/drone-cache/archive/gzip"
+	"github.com/meltwater/drone-cache/archive/lz4"
 	"github.com/meltwater/drone-cache/archive/tar"
+	"github.com/meltwater/drone-cache/archive/xz"
+	"github.com/meltwater/drone-cache/archive/zip"
 	"github.com/meltwater/drone-cache/archive/zstd"
 )
 
 const (
 	Gzip = "gzip"
+	Lz4  = "lz4"
 	Tar  = "tar"
+	Xz   = "xz"
+	Zip  = "zip"
 	Zstd = "zstd"
 
-	DefaultCompressionLevel = flate.DefaultCompression
-	DefaultArchiveFormat    = Tar
+	DefaultCompressionLevel   = flate.DefaultCompression
+	DefaultCompressionThreads = 0 // Use all available CPUs.
+	DefaultArchiveFormat      = Tar
 )
 
+// ErrUnknownFormat means that given archive format is not supported.
+var ErrUnknownFormat = errors.New("unknown archive format")
+
 // Archive is an interface that defines exposed behavior of archive formats.
 type Archive interface {
 	// Create writes content of the given source to an archive, returns written bytes.
@@ -32,25 +44,57 @@ type Archive interface {
 }
 
 // FromFormat determines which archive to use from given archive format.
-func FromFormat(logger log.Logger, root string, format string, opts ...Option) Archive {
-	options := options{
-		compressionLevel: DefaultCompressionLevel,
+// Returned archive creates archives using the given format,
+// but detects the actual format of an archive from its magic bytes on extraction.
+func FromFormat(logger log.Logger, root string, format string, opts ...Option) (Archive, error) {
+	a, err := fromFormat(logger, root, format, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &detector{Archive: a, logger: logger, root: root, format: format, opts: opts}, nil
+}
+
+// nolint: gochecknoinits // Built-in formats are registered the same way as custom ones.
+func init() {
+	Register(Gzip, func(logger log.Logger, root string, o Options) (Archive, error) {
+		return gzip.New(logger, root, o.SkipSymlinks, o.CompressionLevel, o.CompressionThreads), nil
+	})
+	Register(Lz4, func(logger log.Logger, root string, o Options) (Archive, error) {
+		return lz4.New(logger, root, o.SkipSymlinks, o.CompressionLevel, o.CompressionThreads), nil
+	})
+	Register(Tar, func(logger log.Logger, root string, o Options) (Archive, error) {
+		return tar.New(logger, root, o.SkipSymlinks), nil
+	})
+	Register(Xz, func(logger log.Logger, root string, o Options) (Archive, error) {
+		return xz.New(logger, root, o.SkipSymlinks), nil
+	})
+	Register(Zip, func(logger log.Logger, root string, o Options) (Archive, error) {
+		return zip.New(logger, root, o.SkipSymlinks, o.CompressionLevel), nil
+	})
+	Register(Zstd, func(logger log.Logger, root string, o Options) (Archive, error) {
+		return zstd.New(logger, root, o.SkipSymlinks, o.CompressionLevel, o.CompressionThreads, o.ZstdWindowSize), nil
+	})
+}
+
+func fromFormat(logger log.Logger, root string, format string, opts ...Option) (Archive, error) {
+	options := Options{
+		CompressionLevel:   DefaultCompressionLevel,
+		CompressionThreads: DefaultCompressionThreads,
 	}
 
 	for _, o := range opts {
 		o.apply(&options)
 	}
 
-	switch format {
-	case Gzip:
-		return gzip.New(logger, root, options.skipSymlinks, options.compressionLevel)
-	case Tar:
-		return tar.New(logger, root, options.skipSymlinks)
-	case Zstd:
-		return zstd.New(logger, root, options.skipSymlinks, options.compressionLevel)
-	default:
-		level.Error(logger).Log("msg", "unknown archive format", "format", format)
-
-		return tar.New(logger, root, options.skipSymlinks) // DefaultArchiveFormat
+	if options.CompressionThreads <= 0 {
+		options.CompressionThreads = runtime.GOMAXPROCS(0)
 	}
+
+	factory, ok := lookup(format)
+	if !ok {
+		return nil, fmt.Errorf("<%s>, %w", format, ErrUnknownFormat)
+	}
+
+	return factory(logger, root, options)
 }
@@ -0,0 +1,161 @@
+package archive
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+
+	"github.com/meltwater/drone-cache/test"
+)
+
+var (
+	testRoot          = "testdata"
+	testRootMounted   = "testdata/mounted"
+	testRootExtracted = "testdata/extracted"
+
+	formats = []string{Gzip, Lz4, Tar, Xz, Zip, Zstd}
+)
+
+func TestFromFormatUnknown(t *testing.T) {
+	_, err := FromFormat(log.NewNopLogger(), testRootMounted, "rar")
+	test.Expected(t, err, ErrUnknownFormat)
+}
+
+func TestRegisterTyped(t *testing.T) {
+	type config struct {
+		Level int `setting:"level"`
+	}
+
+	var got config
+
+	RegisterTyped("custom-typed", func(logger log.Logger, root string, o Options, c config) (Archive, error) {
+		got = c
+
+		test.Equals(t, true, o.SkipSymlinks)
+		test.Assert(t, o.CompressionThreads > 0, "compression threads are not resolved")
+
+		return fromFormat(logger, root, Tar)
+	})
+
+	a, err := FromFormat(log.NewNopLogger(), testRootMounted, "custom-typed",
+		WithSkipSymlinks(true),
+		WithSettings(map[string]string{"level": "7"}),
+	)
+	test.Ok(t, err)
+	test.Assert(t, a != nil, "expected archive")
+	test.Equals(t, config{Level: 7}, got)
+
+	_, err = FromFormat(log.NewNopLogger(), testRootMounted, "custom-typed", WithSettings(map[string]string{"lvl": "7"}))
+	test.NotOk(t, err)
+
+	test.Equals(t, []string{"custom-typed", Gzip, Lz4, Tar, Xz, Zip, Zstd}, Registered())
+}
+
+func TestDetect(t *testing.T) {
+	test.Ok(t, os.MkdirAll(testRootMounted, 0755))
+	t.Cleanup(func() { os.RemoveAll(testRoot) })
+
+	file, fileClean := test.CreateTempFile(t, "detect", []byte("hello\ndrone!\n"), testRootMounted)
+	t.Cleanup(fileClean)
+
+	for _, format := range formats {
+		a, err := FromFormat(log.NewNopLogger(), testRootMounted, format)
+		test.Ok(t, err)
+
+		var buf bytes.Buffer
+		_, err = a.Create([]string{file}, &buf)
+		test.Ok(t, err)
+
+		detected, r, err := Detect(&buf)
+		test.Ok(t, err)
+		test.Equals(t, format, detected)
+
+		// Make sure nothing is lost while sniffing.
+		var rest bytes.Buffer
+		_, err = rest.ReadFrom(r)
+		test.Ok(t, err)
+		test.Assert(t, rest.Len() > 0, "format %q: sniffed reader is empty", format)
+	}
+
+	detected, _, err := Detect(strings.NewReader("hello\ndrone\n"))
+	test.Ok(t, err)
+	test.Equals(t, "", detected)
+}
+
+func TestExtractDetectsFormat(t *testing.T) {
+	test.Ok(t, os.MkdirAll(testRootMounted, 0755))
+	test.Ok(t, os.MkdirAll(testRootExtracted, 0755))
+	t.Cleanup(func() { os.RemoveAll(testRoot) })
+
+	file, fileClean := test.CreateTempFile(t, "detect_extract", []byte("hello\ndrone!\n"), testRootMounted)
+	t.Cleanup(fileClean)
+
+	for _, created := range formats {
+		for _, configured := range formats {
+			created, configured := created, configured
+			t.Run(created+"-as-"+configured, func(t *testing.T) {
+				ca, err := FromFormat(log.NewNopLogger(), testRootMounted, created)
+				test.Ok(t, err)
+
+				var buf bytes.Buffer
+				_, err = ca.Create([]string{file}, &buf)
+				test.Ok(t, err)
+
+				dstDir, dstDirClean := test.CreateTempDir(t, "detect_extract_"+created, testRootExtracted)
+				t.Cleanup(dstDirClean)
+
+				ea, err := FromFormat(log.NewNopLogger(), testRootMounted, configured)
+				test.Ok(t, err)
+
+				written, err := ea.Extract(dstDir, &buf)
+				test.Ok(t, err)
+				test.Equals(t, int64(13), written)
+
+				content, err := os.ReadFile(filepath.Join(dstDir, filepath.Base(file)))
+				test.Ok(t, err)
+				test.Equals(t, []byte("hello\ndrone!\n"), content)
+			})
+		}
+	}
+}
+
+func TestExtractFallsBackToConfiguredFormat(t *testing.T) {
+	a, err := FromFormat(log.NewNopLogger(), testRootMounted, Gzip)
+	test.Ok(t, err)
+
+	_, err = a.Extract(testRootExtracted, strings.NewReader("hello\ndrone\n"))
+	test.NotOk(t, err)
+	test.Assert(t, !errors.Is(err, ErrUnknownFormat), "unexpected error: %v", err)
+}
+
+func TestList(t *testing.T) {
+	test.Ok(t, os.MkdirAll(testRootMounted, 0755))
+	t.Cleanup(func() { os.RemoveAll(testRoot) })
+
+	file, fileClean := test.CreateTempFile(t, "list", []byte("hello\ndrone!\n"), testRootMounted)
+	t.Cleanup(fileClean)
+
+	for _, format := range formats {
+		a, err := FromFormat(log.NewNopLogger(), testRootMounted, format)
+		test.Ok(t, err)
+
+		var buf bytes.Buffer
+		_, err = a.Create([]string{file}, &buf)
+		test.Ok(t, err)
+
+		detected, entries, err := List(&buf)
+		test.Ok(t, err)
+		test.Equals(t, format, detected)
+		test.Equals(t, 1, len(entries))
+		test.Equals(t, filepath.Base(file), entries[0].Name)
+		test.Equals(t, int64(13), entries[0].Size)
+	}
+
+	_, _, err := List(strings.NewReader("hello\ndrone\n"))
+	test.Expected(t, err, ErrUndetectableFormat)
+}
@@ -0,0 +1,91 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+const (
+	tarMagicOffset = 257
+	sniffLen       = 512
+)
+
+// nolint:gochecknoglobals // Magic bytes of the supported formats.
+var (
+	tarMagic = []byte("ustar")
+
+	magics = []struct {
+		format string
+		magic  []byte
+	}{
+		{Gzip, []byte{0x1f, 0x8b}},
+		{Lz4, []byte{0x04, 0x22, 0x4d, 0x18}},
+		{Xz, []byte{0xfd, 0x37, 0x7a, 0x58, 0x5a, 0x00}},
+		{Zip, []byte{0x50, 0x4b, 0x03, 0x04}},
+		{Zip, []byte{0x50, 0x4b, 0x05, 0x06}}, // Empty archive.
+		{Zstd, []byte{0x28, 0xb5, 0x2f, 0xfd}},
+	}
+)
+
+// Detect sniffs the archive format from the magic bytes at the beginning of the given reader.
+// It returns the detected format, or an empty string if the format is not recognized,
+// together with a reader that still yields the whole stream.
+func Detect(r io.Reader) (string, io.Reader, error) {
+	br := bufio.NewReaderSize(r, sniffLen)
+
+	head, err := br.Peek(sniffLen)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", br, fmt.Errorf("peek archive header, %w", err)
+	}
+
+	for _, m := range magics {
+		if bytes.HasPrefix(head, m.magic) {
+			return m.format, br, nil
+		}
+	}
+
+	if len(head) >= tarMagicOffset+len(tarMagic) && bytes.HasPrefix(head[tarMagicOffset:], tarMagic) {
+		return Tar, br, nil
+	}
+
+	return "", br, nil
+}
+
+// detector creates archives using the configured format and extracts archives of any supported format.
+type detector struct {
+	Archive
+
+	logger log.Logger
+
+	root   string
+	format string
+	opts   []Option
+}
+
+// Extract reads content from the given archive reader and restores it to the destination, returns written bytes.
+// The archive format is detected from the magic bytes, if it is not recognized the configured format is used.
+func (d *detector) Extract(dst string, r io.Reader) (int64, error) {
+	format, br, err := Detect(r)
+	if err != nil {
+		return 0, fmt.Errorf("detect archive format, %w", err)
+	}
+
+	if format == "" || format == d.format {
+		return d.Archive.Extract(dst, br) // nolint:wrapcheck
+	}
+
+	level.Info(d.logger).Log("msg", "archive format differs from the configured one", "configured", d.format, "detected", format)
+
+	a, err := fromFormat(d.logger, d.root, format, d.opts...)
+	if err != nil {
+		return 0, fmt.Errorf("initialize detected archive <%s>, %w", format, err)
+	}
+
+	return a.Extract(dst, br) // nolint:wrapcheck
+}
@@ -1,36 +1,48 @@
 package gzip
 
 import (
-	"compress/gzip"
 	"fmt"
 	"io"
 
 	"github.com/go-kit/log"
+	"github.com/klauspost/pgzip"
 	"github.com/meltwater/drone-cache/archive/tar"
 	"github.com/meltwater/drone-cache/internal"
 )
 
+// blockSize is the size of the blocks that are compressed in parallel.
+const blockSize = 1 << 20
+
 // Archive implements archive for gzip.
 type Archive struct {
 	logger log.Logger
 
 	root             string
 	compressionLevel int
+	threads          int
 	skipSymlinks     bool
 }
 
 // New creates an archive that uses the .tar.gz file format.
-func New(logger log.Logger, root string, skipSymlinks bool, compressionLevel int) *Archive {
-	return &Archive{logger, root, compressionLevel, skipSymlinks}
+// Blocks are compressed in parallel using given number of threads,
+// output is still a standard gzip stream.
+func New(logger log.Logger, root string, skipSymlinks bool, compressionLevel int, threads int) *Archive {
+	return &Archive{logger, root, compressionLevel, threads, skipSymlinks}
 }
 
 // Create writes content of the given source to an archive, returns written bytes.
 func (a *Archive) Create(srcs []string, w io.Writer) (int64, error) {
-	gw, err := gzip.NewWriterLevel(w, a.compressionLevel)
+	gw, err := pgzip.NewWriterLevel(w, a.compressionLevel)
 	if err != nil {
 		return 0, fmt.Errorf("create archive writer, %w", err)
 	}
 
+	if a.threads > 0 {
+		if err := gw.SetConcurrency(blockSize, a.threads); err != nil {
+			return 0, fmt.Errorf("set archive writer concurrency, %w", err)
+		}
+	}
+
 	defer internal.CloseWithErrLogf(a.logger, gw, "gzip writer")
 
 	wBytes, err := tar.New(a.logger, a.root, a.skipSymlinks).Create(srcs, gw)
@@ -43,7 +55,7 @@ func (a *Archive) Create(srcs []string, w io.Writer) (int64, error) {
 
 // Extract reads content from the given archive reader and restores it to the destination, returns written bytes.
 func (a *Archive) Extract(dst string, r io.Reader) (int64, error) {
-	gr, err := gzip.NewReader(r)
+	gr, err := newReader(r, a.threads)
 	if err != nil {
 		return 0, fmt.Errorf("create archive extractor: %w", err)
 	}
@@ -57,3 +69,11 @@ func (a *Archive) Extract(dst string, r io.Reader) (int64, error) {
 
 	return eBytes, nil
 }
+
+func newReader(r io.Reader, threads int) (*pgzip.Reader, error) {
+	if threads > 0 {
+		return pgzip.NewReaderN(r, blockSize, threads) // nolint:wrapcheck
+	}
+
+	return pgzip.NewReader(r) // nolint:wrapcheck
+}
@@ -1,6 +1,8 @@
 package gzip
 
 import (
+	"archive/tar"
+	"bytes"
 	"compress/flate"
 	"compress/gzip"
 	"io"
@@ -11,8 +13,9 @@ import (
 	"testing"
 
 	"github.com/go-kit/log"
+	"github.com/klauspost/pgzip"
 
-	"github.com/meltwater/drone-cache/archive/tar"
+	archivetar "github.com/meltwater/drone-cache/archive/tar"
 	"github.com/meltwater/drone-cache/test"
 )
 
@@ -45,45 +48,45 @@ func TestCreate(t *testing.T) {
 	}{
 		{
 			name:    "empty mount paths",
-			tgz:     New(log.NewNopLogger(), testRootMounted, true, flate.DefaultCompression),
+			tgz:     New(log.NewNopLogger(), testRootMounted, true, flate.DefaultCompression, 0),
 			srcs:    []string{},
 			written: 0,
 			err:     nil,
 		},
 		{
 			name: "non-existing mount paths",
-			tgz:  New(log.NewNopLogger(), testRootMounted, true, flate.DefaultCompression),
+			tgz:  New(log.NewNopLogger(), testRootMounted, true, flate.DefaultCompression, 0),
 			srcs: []string{
 				"iamnotexists",
 				"metoo",
 			},
 			written: 0,
-			err:     tar.ErrSourceNotReachable, // os.ErrNotExist || os.ErrPermission
+			err:     archivetar.ErrSourceNotReachable, // os.ErrNotExist || os.ErrPermission
 		},
 		{
 			name:    "existing mount paths",
-			tgz:     New(log.NewNopLogger(), testRootMounted, true, flate.DefaultCompression),
+			tgz:     New(log.NewNopLogger(), testRootMounted, true, flate.DefaultCompression, 0),
 			srcs:    exampleFileTree(t, "gzip_create", testRootMounted),
 			written: 43, // 3 x tmpfile in dir, 1 tmpfile
 			err:     nil,
 		},
 		{
 			name:    "existing mount nested paths",
-			tgz:     New(log.NewNopLogger(), testRootMounted, true, flate.DefaultCompression),
+			tgz:     New(log.NewNopLogger(), testRootMounted, true, flate.DefaultCompression, 0),
 			srcs:    exampleNestedFileTree(t, "tar_create"),
 			written: 56, // 4 x tmpfile in dir, 1 tmpfile
 			err:     nil,
 		},
 		{
 			name:    "existing mount paths with symbolic links",
-			tgz:     New(log.NewNopLogger(), testRootMounted, false, flate.DefaultCompression),
+			tgz:     New(log.NewNopLogger(), testRootMounted, false, flate.DefaultCompression, 0),
 			srcs:    exampleFileTreeWithSymlinks(t, "gzip_create_symlink"),
 			written: 43,
 			err:     nil,
 		},
 		{
 			name:    "absolute mount paths",
-			tgz:     New(log.NewNopLogger(), testRootMounted, true, flate.DefaultCompression),
+			tgz:     New(log.NewNopLogger(), testRootMounted, true, flate.DefaultCompression, 0),
 			srcs:    exampleFileTree(t, "tar_create", testAbs),
 			written: 43,
 			err:     nil,
@@ -151,7 +154,7 @@ func TestExtract(t *testing.T) {
 	})
 
 	// Setup
-	tgz := New(log.NewNopLogger(), testRootMounted, false, flate.DefaultCompression)
+	tgz := New(log.NewNopLogger(), testRootMounted, false, flate.DefaultCompression, 0)
 
 	arcDir, arcDirClean := test.CreateTempDir(t, "gzip_extract_archive")
 	t.Cleanup(arcDirClean)
@@ -193,7 +196,7 @@ func TestExtract(t *testing.T) {
 	}{
 		{
 			name:        "non-existing archive",
-			tgz:         New(log.NewNopLogger(), testRootMounted, true, flate.DefaultCompression),
+			tgz:         New(log.NewNopLogger(), testRootMounted, true, flate.DefaultCompression, 0),
 			archivePath: "iamnotexists",
 			srcs:        []string{},
 			written:     0,
@@ -201,7 +204,7 @@ func TestExtract(t *testing.T) {
 		},
 		{
 			name:        "non-existing root destination",
-			tgz:         New(log.NewNopLogger(), testRootMounted, true, flate.DefaultCompression),
+			tgz:         New(log.NewNopLogger(), testRootMounted, true, flate.DefaultCompression, 0),
 			archivePath: emptyArchivePath,
 			srcs:        []string{},
 			written:     0,
@@ -209,7 +212,7 @@ func TestExtract(t *testing.T) {
 		},
 		{
 			name:        "empty archive",
-			tgz:         New(log.NewNopLogger(), testRootMounted, true, flate.DefaultCompression),
+			tgz:         New(log.NewNopLogger(), testRootMounted, true, flate.DefaultCompression, 0),
 			archivePath: emptyArchivePath,
 			srcs:        []string{},
 			written:     0,
@@ -217,15 +220,15 @@ func TestExtract(t *testing.T) {
 		},
 		{
 			name:        "bad archives",
-			tgz:         New(log.NewNopLogger(), testRootMounted, true, flate.DefaultCompression),
+			tgz:         New(log.NewNopLogger(), testRootMounted, true, flate.DefaultCompression, 0),
 			archivePath: badArchivePath,
 			srcs:        []string{},
 			written:     0,
-			err:         gzip.ErrHeader,
+			err:         pgzip.ErrHeader,
 		},
 		{
 			name:        "existing archive",
-			tgz:         New(log.NewNopLogger(), testRootMounted, true, flate.DefaultCompression),
+			tgz:         New(log.NewNopLogger(), testRootMounted, true, flate.DefaultCompression, 0),
 			archivePath: archivePath,
 			srcs:        files,
 			written:     43,
@@ -233,7 +236,7 @@ func TestExtract(t *testing.T) {
 		},
 		{
 			name:        "existing archive with nested files",
-			tgz:         New(log.NewNopLogger(), testRootMounted, true, flate.DefaultCompression),
+			tgz:         New(log.NewNopLogger(), testRootMounted, true, flate.DefaultCompression, 0),
 			archivePath: nestedArchivePath,
 			srcs:        nestedFiles,
 			written:     56,
@@ -241,7 +244,7 @@ func TestExtract(t *testing.T) {
 		},
 		{
 			name:        "existing archive with symbolic links",
-			tgz:         New(log.NewNopLogger(), testRootMounted, false, flate.DefaultCompression),
+			tgz:         New(log.NewNopLogger(), testRootMounted, false, flate.DefaultCompression, 0),
 			archivePath: archiveWithSymlinkPath,
 			srcs:        filesWithSymlink,
 			written:     43,
@@ -249,7 +252,7 @@ func TestExtract(t *testing.T) {
 		},
 		{
 			name:        "absolute mount paths",
-			tgz:         New(log.NewNopLogger(), testRootMounted, true, flate.DefaultCompression),
+			tgz:         New(log.NewNopLogger(), testRootMounted, true, flate.DefaultCompression, 0),
 			archivePath: archiveAbsPath,
 			srcs:        filesAbs,
 			written:     43,
@@ -294,6 +297,32 @@ func TestExtract(t *testing.T) {
 	}
 }
 
+func TestCreateParallelIsCompatible(t *testing.T) {
+	test.Ok(t, os.MkdirAll(testRootMounted, 0755))
+	t.Cleanup(func() { os.RemoveAll(testRoot) })
+
+	content := bytes.Repeat([]byte("hello\ndrone!\n"), 1024*1024) // 13 MiB, spans multiple blocks.
+	file, fileClean := test.CreateTempFile(t, "gzip_parallel", content, testRootMounted)
+	t.Cleanup(fileClean)
+
+	var buf bytes.Buffer
+	_, err := New(log.NewNopLogger(), testRootMounted, true, flate.DefaultCompression, 4).Create([]string{file}, &buf)
+	test.Ok(t, err)
+
+	// Standard library decompressor should be able to read the parallel compressed stream.
+	gr, err := gzip.NewReader(&buf)
+	test.Ok(t, err)
+
+	tr := tar.NewReader(gr)
+	h, err := tr.Next()
+	test.Ok(t, err)
+	test.Equals(t, filepath.Base(file), h.Name)
+
+	got, err := ioutil.ReadAll(tr)
+	test.Ok(t, err)
+	test.Assert(t, bytes.Equal(content, got), "content mismatch after decompression")
+}
+
 // Helpers
 
 func create(a *Archive, srcs []string, dst string) (int64, error) {
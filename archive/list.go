@@ -0,0 +1,186 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/klauspost/pgzip"
+	"github.com/pierrec/lz4/v4"
+	"github.com/ulikunitz/xz"
+)
+
+// ErrUndetectableFormat means that format of the given archive could not be detected.
+var ErrUndetectableFormat = errors.New("archive format could not be detected")
+
+// Entry describes a single item stored in an archive.
+type Entry struct {
+	Name     string
+	Linkname string
+	Size     int64
+	Mode     os.FileMode
+	ModTime  time.Time
+}
+
+// List reads the whole archive from the given reader and returns the detected format and stored entries.
+// Since every byte is decompressed, integrity checks of the compression formats are performed as well.
+func List(r io.Reader) (string, []Entry, error) {
+	format, br, err := Detect(r)
+	if err != nil {
+		return "", nil, fmt.Errorf("detect archive format, %w", err)
+	}
+
+	var entries []Entry
+
+	switch format {
+	case Zip:
+		entries, err = listZip(br)
+	case "":
+		return "", nil, ErrUndetectableFormat
+	default:
+		entries, err = listTar(format, br)
+	}
+
+	if err != nil {
+		return format, entries, err
+	}
+
+	return format, entries, nil
+}
+
+// nolint: cyclop
+func listTar(format string, r io.Reader) ([]Entry, error) {
+	switch format {
+	case Gzip:
+		gr, err := pgzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("create gzip reader, %w", err)
+		}
+		defer gr.Close()
+
+		r = gr
+	case Lz4:
+		// NOTICE: lz4 reader fails when it is read again after reaching the end of the stream.
+		r = &eofReader{r: lz4.NewReader(r)}
+	case Xz:
+		xr, err := xz.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("create xz reader, %w", err)
+		}
+
+		r = xr
+	case Zstd:
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("create zstd reader, %w", err)
+		}
+		defer zr.Close()
+
+		r = zr
+	}
+
+	var (
+		entries []Entry
+		tr      = tar.NewReader(r)
+	)
+
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return entries, fmt.Errorf("read tar header, %w", err)
+		}
+
+		if _, err := io.Copy(io.Discard, tr); err != nil {
+			return entries, fmt.Errorf("read tar entry <%s>, %w", h.Name, err)
+		}
+
+		entries = append(entries, Entry{
+			Name:     h.Name,
+			Linkname: h.Linkname,
+			Size:     h.Size,
+			Mode:     h.FileInfo().Mode(),
+			ModTime:  h.ModTime,
+		})
+	}
+
+	// Drain trailing padding, so that checksums of the compressed stream are verified as well.
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return entries, fmt.Errorf("read archive trailer, %w", err)
+	}
+
+	return entries, nil
+}
+
+func listZip(r io.Reader) ([]Entry, error) {
+	f, err := os.CreateTemp("", "drone-cache-*.zip")
+	if err != nil {
+		return nil, fmt.Errorf("create temporary archive file, %w", err)
+	}
+
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	size, err := io.Copy(f, r)
+	if err != nil {
+		return nil, fmt.Errorf("spool archive to temporary file, %w", err)
+	}
+
+	zr, err := zip.NewReader(f, size)
+	if err != nil {
+		return nil, fmt.Errorf("create zip reader, %w", err)
+	}
+
+	entries := make([]Entry, 0, len(zr.File))
+
+	for _, zf := range zr.File {
+		rc, err := zf.Open()
+		if err != nil {
+			return entries, fmt.Errorf("open zip entry <%s>, %w", zf.Name, err)
+		}
+
+		// Reading the entry fully verifies its CRC.
+		_, err = io.Copy(io.Discard, rc)
+		rc.Close()
+
+		if err != nil {
+			return entries, fmt.Errorf("read zip entry <%s>, %w", zf.Name, err)
+		}
+
+		entries = append(entries, Entry{
+			Name:    zf.Name,
+			Size:    int64(zf.UncompressedSize64),
+			Mode:    zf.Mode(),
+			ModTime: zf.Modified,
+		})
+	}
+
+	return entries, nil
+}
+
+// eofReader keeps returning io.EOF once the underlying reader is exhausted.
+type eofReader struct {
+	r   io.Reader
+	eof bool
+}
+
+func (e *eofReader) Read(p []byte) (int, error) {
+	if e.eof {
+		return 0, io.EOF
+	}
+
+	n, err := e.r.Read(p)
+	if errors.Is(err, io.EOF) {
+		e.eof = true
+	}
+
+	return n, err // nolint: wrapcheck
+}
@@ -0,0 +1,78 @@
+package lz4
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/go-kit/log"
+	"github.com/meltwater/drone-cache/archive/tar"
+	"github.com/meltwater/drone-cache/internal"
+	"github.com/pierrec/lz4/v4"
+)
+
+// Archive implements archive for lz4.
+type Archive struct {
+	logger log.Logger
+
+	root             string
+	compressionLevel int
+	threads          int
+	skipSymlinks     bool
+}
+
+// New creates an archive that uses the .tar.lz4 file format.
+// Blocks are compressed and decompressed using given number of threads, zero keeps it single threaded.
+func New(logger log.Logger, root string, skipSymlinks bool, compressionLevel int, threads int) *Archive {
+	return &Archive{logger, root, compressionLevel, threads, skipSymlinks}
+}
+
+// Create writes content of the given source to an archive, returns written bytes.
+func (a *Archive) Create(srcs []string, w io.Writer) (int64, error) {
+	lw := lz4.NewWriter(w)
+	opts := []lz4.Option{lz4.CompressionLevelOption(level(a.compressionLevel))}
+	if a.threads > 0 {
+		opts = append(opts, lz4.ConcurrencyOption(a.threads))
+	}
+
+	if err := lw.Apply(opts...); err != nil {
+		return 0, fmt.Errorf("lz4 create archive writer, %w", err)
+	}
+
+	defer internal.CloseWithErrLogf(a.logger, lw, "lz4 writer")
+
+	wBytes, err := tar.New(a.logger, a.root, a.skipSymlinks).Create(srcs, lw)
+	if err != nil {
+		return 0, fmt.Errorf("lz4 create archive, %w", err)
+	}
+
+	return wBytes, nil
+}
+
+// Extract reads content from the given archive reader and restores it to the destination, returns written bytes.
+func (a *Archive) Extract(dst string, r io.Reader) (int64, error) {
+	lr := lz4.NewReader(r)
+	if a.threads > 0 {
+		if err := lr.Apply(lz4.ConcurrencyOption(a.threads)); err != nil {
+			return 0, fmt.Errorf("lz4 create extract archive reader, %w", err)
+		}
+	}
+
+	eBytes, err := tar.New(a.logger, a.root, a.skipSymlinks).Extract(dst, lr)
+	if err != nil {
+		return 0, fmt.Errorf("lz4 extract archive, %w", err)
+	}
+
+	return eBytes, nil
+}
+
+// level maps the shared 0-9 compression level scale onto lz4 levels, anything below 1 uses the fast mode.
+func level(compressionLevel int) lz4.CompressionLevel {
+	switch {
+	case compressionLevel < 1:
+		return lz4.Fast
+	case compressionLevel > 9: // nolint:gomnd
+		return lz4.Level9
+	default:
+		return lz4.CompressionLevel(1 << (8 + compressionLevel)) // nolint:gomnd
+	}
+}
@@ -1,31 +1,58 @@
 package archive
 
-type options struct {
-	compressionLevel int
-	skipSymlinks     bool
+// Options are the options that archives are created with, as given to the factories of the formats.
+type Options struct {
+	CompressionLevel int
+	// CompressionThreads is the number of threads to use for compression and decompression, resolved to all CPUs when not set.
+	CompressionThreads int
+	ZstdWindowSize     int
+	SkipSymlinks       bool
+	// Settings configures the formats registered with RegisterTyped.
+	Settings map[string]string
 }
 
 // Option overrides behavior of Archive.
 type Option interface {
-	apply(*options)
+	apply(*Options)
 }
 
-type optionFunc func(*options)
+type optionFunc func(*Options)
 
-func (f optionFunc) apply(o *options) {
+func (f optionFunc) apply(o *Options) {
 	f(o)
 }
 
 // WithCompressionLevel sets compression level option.
 func WithCompressionLevel(i int) Option {
-	return optionFunc(func(o *options) {
-		o.compressionLevel = i
+	return optionFunc(func(o *Options) {
+		o.CompressionLevel = i
 	})
 }
 
 // WithSkipSymlinks sets skip symlink option.
 func WithSkipSymlinks(b bool) Option {
-	return optionFunc(func(o *options) {
-		o.skipSymlinks = b
+	return optionFunc(func(o *Options) {
+		o.SkipSymlinks = b
+	})
+}
+
+// WithCompressionThreads sets the number of threads to use for compression and decompression option.
+func WithCompressionThreads(i int) Option {
+	return optionFunc(func(o *Options) {
+		o.CompressionThreads = i
+	})
+}
+
+// WithZstdWindowSize sets window size of zstd encoder option.
+func WithZstdWindowSize(i int) Option {
+	return optionFunc(func(o *Options) {
+		o.ZstdWindowSize = i
+	})
+}
+
+// WithSettings sets settings of the formats registered with RegisterTyped option.
+func WithSettings(s map[string]string) Option {
+	return optionFunc(func(o *Options) {
+		o.Settings = s
 	})
 }
@@ -0,0 +1,76 @@
+package archive
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/go-kit/log"
+
+	"github.com/meltwater/drone-cache/internal/settings"
+)
+
+// Factory creates an archive which stores files relative to the given root.
+type Factory func(logger log.Logger, root string, o Options) (Archive, error)
+
+// nolint: gochecknoglobals
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Factory{}
+)
+
+// Register makes an archive format available with the given name.
+// Custom formats are not detected from magic bytes, archives are extracted with the configured format instead.
+// It is meant to be called from init functions, and panics if the format is empty or already registered.
+func Register(format string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if format == "" || factory == nil {
+		panic("archive: register of empty format or nil factory")
+	}
+
+	if _, ok := registry[format]; ok {
+		panic("archive: register of already registered format " + format)
+	}
+
+	registry[format] = factory
+}
+
+// RegisterTyped registers an archive format which is configured with its own type of configuration.
+// The configuration is decoded from Settings of Options, fields are named by their `setting` tag
+// or their lower-cased name, unknown settings are rejected.
+func RegisterTyped[T any](format string, factory func(logger log.Logger, root string, o Options, c T) (Archive, error)) {
+	Register(format, func(logger log.Logger, root string, o Options) (Archive, error) {
+		var c T
+		if err := settings.Decode(o.Settings, &c); err != nil {
+			return nil, fmt.Errorf("decode settings of archive format <%s>, %w", format, err)
+		}
+
+		return factory(logger, root, o, c)
+	})
+}
+
+// Registered returns the registered archive formats in alphabetical order.
+func Registered() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	formats := make([]string, 0, len(registry))
+	for f := range registry {
+		formats = append(formats, f)
+	}
+
+	sort.Strings(formats)
+
+	return formats
+}
+
+func lookup(format string) (Factory, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	f, ok := registry[format]
+
+	return f, ok
+}
@@ -134,6 +134,12 @@ func relative(parent string, path string) (string, error) {
 		rel = strings.TrimPrefix(rel, "../")
 	}
 
+	// NOTICE: filepath.Rel puts ".." when given path is in the parent of parent, e.g. the files at the top of the archive
+	// on extraction, they would be written next to the destination instead of in it.
+	if rel == ".." {
+		rel = "."
+	}
+
 	rel = filepath.ToSlash(rel)
 
 	return strings.TrimPrefix(filepath.Join(rel, name), "/"), nil
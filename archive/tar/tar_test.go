@@ -1,6 +1,7 @@
 package tar
 
 import (
+	"archive/tar"
 	"io"
 	"io/ioutil"
 	"os"
@@ -135,6 +136,55 @@ func TestCreate(t *testing.T) {
 	}
 }
 
+func TestCreateOutsideOfRoot(t *testing.T) {
+	dir := filepath.Join(testRoot, "outside")
+	root := filepath.Join(dir, "root")
+	test.Ok(t, os.MkdirAll(root, 0755))
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	src := filepath.Join(dir, "outside.txt")
+	test.Ok(t, os.WriteFile(src, []byte("hello\ndrone!\n"), 0644))
+
+	archivePath := filepath.Join(dir, "test.tar")
+	_, err := create(New(log.NewNopLogger(), root, true), []string{src}, archivePath)
+	test.Ok(t, err)
+
+	f, err := os.Open(archivePath)
+	test.Ok(t, err)
+	t.Cleanup(func() { f.Close() })
+
+	// NOTICE: Paths in the parent of the root must not be archived as "../<name>", which would escape on extraction.
+	h, err := tar.NewReader(f).Next()
+	test.Ok(t, err)
+	test.Equals(t, "outside.txt", h.Name)
+}
+
+func TestExtractTopLevelFiles(t *testing.T) {
+	dir := filepath.Join(testRoot, "top-level")
+	root := filepath.Join(dir, "root")
+	dst := filepath.Join(dir, "extracted", "dst")
+	test.Ok(t, os.MkdirAll(root, 0755))
+	test.Ok(t, os.MkdirAll(dst, 0755))
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	src := filepath.Join(root, "top.txt")
+	test.Ok(t, os.WriteFile(src, []byte("hello\ndrone!\n"), 0644))
+
+	a := New(log.NewNopLogger(), root, true)
+	archivePath := filepath.Join(dir, "test.tar")
+	_, err := create(a, []string{src}, archivePath)
+	test.Ok(t, err)
+
+	_, err = extract(a, archivePath, dst)
+	test.Ok(t, err)
+
+	// NOTICE: Files at the top of the archive used to be written next to the destination instead of in it.
+	test.Exists(t, filepath.Join(dst, "top.txt"))
+
+	_, err = os.Stat(filepath.Join(dir, "extracted", "top.txt"))
+	test.Assert(t, os.IsNotExist(err), "file is extracted next to the destination: %v", err)
+}
+
 func TestExtract(t *testing.T) {
 	test.Ok(t, os.MkdirAll(testRootMounted, 0755))
 	test.Ok(t, os.MkdirAll(testRootExtracted, 0755))
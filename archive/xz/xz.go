@@ -0,0 +1,57 @@
+package xz
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/go-kit/log"
+	"github.com/meltwater/drone-cache/archive/tar"
+	"github.com/meltwater/drone-cache/internal"
+	"github.com/ulikunitz/xz"
+)
+
+// Archive implements archive for xz.
+type Archive struct {
+	logger log.Logger
+
+	root         string
+	skipSymlinks bool
+}
+
+// New creates an archive that uses the .tar.xz file format.
+// NOTICE: The xz encoder does not support compression levels, it always uses its default (best) settings.
+func New(logger log.Logger, root string, skipSymlinks bool) *Archive {
+	return &Archive{logger, root, skipSymlinks}
+}
+
+// Create writes content of the given source to an archive, returns written bytes.
+func (a *Archive) Create(srcs []string, w io.Writer) (int64, error) {
+	xw, err := xz.NewWriter(w)
+	if err != nil {
+		return 0, fmt.Errorf("xz create archive writer, %w", err)
+	}
+
+	defer internal.CloseWithErrLogf(a.logger, xw, "xz writer")
+
+	wBytes, err := tar.New(a.logger, a.root, a.skipSymlinks).Create(srcs, xw)
+	if err != nil {
+		return 0, fmt.Errorf("xz create archive, %w", err)
+	}
+
+	return wBytes, nil
+}
+
+// Extract reads content from the given archive reader and restores it to the destination, returns written bytes.
+func (a *Archive) Extract(dst string, r io.Reader) (int64, error) {
+	xr, err := xz.NewReader(r)
+	if err != nil {
+		return 0, fmt.Errorf("xz create extract archive reader <%v>, %w", err, tar.ErrArchiveNotReadable)
+	}
+
+	eBytes, err := tar.New(a.logger, a.root, a.skipSymlinks).Extract(dst, xr)
+	if err != nil {
+		return 0, fmt.Errorf("xz extract archive, %w", err)
+	}
+
+	return eBytes, nil
+}
@@ -0,0 +1,311 @@
+package zip
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/meltwater/drone-cache/internal"
+)
+
+const defaultDirPermission = 0o755
+
+var (
+	// ErrSourceNotReachable means that given source is not reachable.
+	ErrSourceNotReachable = errors.New("source not reachable")
+	// ErrArchiveNotReadable means that given archive not readable/corrupted.
+	ErrArchiveNotReadable = errors.New("archive not readable")
+)
+
+// Archive implements archive for zip.
+type Archive struct {
+	logger log.Logger
+
+	root             string
+	compressionLevel int
+	skipSymlinks     bool
+}
+
+// New creates an archive that uses the .zip file format.
+func New(logger log.Logger, root string, skipSymlinks bool, compressionLevel int) *Archive {
+	return &Archive{logger, root, compressionLevel, skipSymlinks}
+}
+
+// Create writes content of the given source to an archive, returns written bytes.
+func (a *Archive) Create(srcs []string, w io.Writer) (int64, error) {
+	zw := zip.NewWriter(w)
+	defer internal.CloseWithErrLogf(a.logger, zw, "zip writer")
+
+	zw.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(out, a.compressionLevel)
+	})
+
+	var written int64
+
+	for _, src := range srcs {
+		_, err := os.Lstat(src)
+		if err != nil {
+			return written, fmt.Errorf("make sure file or directory readable <%s>: %v,, %w", src, err, ErrSourceNotReachable)
+		}
+
+		if err := filepath.Walk(src, writeToArchive(zw, a.root, a.skipSymlinks, &written)); err != nil {
+			return written, fmt.Errorf("walk, add all files to archive, %w", err)
+		}
+	}
+
+	return written, nil
+}
+
+// nolint: cyclop
+func writeToArchive(zw *zip.Writer, root string, skipSymlinks bool, written *int64) func(string, os.FileInfo, error) error {
+	return func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if fi == nil {
+			return errors.New("no file info")
+		}
+
+		isSymlink := fi.Mode()&os.ModeSymlink != 0
+		if isSymlink && skipSymlinks {
+			return nil
+		}
+
+		h, err := zip.FileInfoHeader(fi)
+		if err != nil {
+			return fmt.Errorf("create header for <%s>, %w", path, err)
+		}
+
+		var name string
+
+		if strings.HasPrefix(path, "/") {
+			name, err = filepath.Abs(path)
+		} else {
+			name, err = relative(root, path)
+		}
+
+		if err != nil {
+			return fmt.Errorf("relative name <%s>: <%s>, %w", path, root, err)
+		}
+
+		h.Name = filepath.ToSlash(name)
+		if fi.IsDir() {
+			h.Name += "/"
+		}
+
+		if fi.Mode().IsRegular() {
+			h.Method = zip.Deflate
+		}
+
+		fw, err := zw.CreateHeader(h)
+		if err != nil {
+			return fmt.Errorf("write header for <%s>, %w", path, err)
+		}
+
+		switch {
+		case isSymlink:
+			// NOTICE: Symbolic links are stored the same way as Info-ZIP does, link target as file content.
+			lnk, err := os.Readlink(path)
+			if err != nil {
+				return fmt.Errorf("read link <%s>, %w", path, err)
+			}
+
+			if _, err := io.WriteString(fw, lnk); err != nil {
+				return fmt.Errorf("write symbolic link <%s>, %w", path, err)
+			}
+
+			return nil
+		case !fi.Mode().IsRegular():
+			return nil
+		}
+
+		n, err := writeFileToArchive(fw, path)
+		if err != nil {
+			return fmt.Errorf("write file to archive, %w", err)
+		}
+
+		*written += n
+
+		return nil
+	}
+}
+
+func writeFileToArchive(w io.Writer, path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("open file <%s>, %w", path, err)
+	}
+
+	defer internal.CloseWithErrCapturef(&err, f, "write file to archive <%s>", path)
+
+	written, err := io.Copy(w, f)
+	if err != nil {
+		return written, fmt.Errorf("copy the file <%s> data to the archive, %w", path, err)
+	}
+
+	return written, nil
+}
+
+// Extract reads content from the given archive reader and restores it to the destination, returns written bytes.
+// NOTICE: Zip keeps its central directory at the end of the file,
+// so the archive is spooled to a temporary file before it can be extracted.
+func (a *Archive) Extract(dst string, r io.Reader) (int64, error) {
+	f, err := os.CreateTemp("", "drone-cache-*.zip")
+	if err != nil {
+		return 0, fmt.Errorf("create temporary archive file, %w", err)
+	}
+
+	defer os.Remove(f.Name())
+	defer internal.CloseWithErrLogf(a.logger, f, "temporary archive file")
+
+	size, err := io.Copy(f, r)
+	if err != nil {
+		return 0, fmt.Errorf("spool archive to temporary file, %w", err)
+	}
+
+	zr, err := zip.NewReader(f, size)
+	if err != nil {
+		return 0, fmt.Errorf("zip reader <%v>, %w", err, ErrArchiveNotReadable)
+	}
+
+	var written int64
+
+	for _, zf := range zr.File {
+		n, err := extractFile(dst, zf)
+		written += n
+
+		if err != nil {
+			return written, err
+		}
+	}
+
+	return written, nil
+}
+
+func extractFile(dst string, zf *zip.File) (int64, error) {
+	name := strings.TrimSuffix(zf.Name, "/")
+
+	var target string
+	if dst == name || strings.HasPrefix(name, "/") {
+		target = name
+	} else {
+		rel, err := relative(dst, name)
+		if err != nil {
+			return 0, fmt.Errorf("relative name, %w", err)
+		}
+
+		target = filepath.Join(dst, rel)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), defaultDirPermission); err != nil {
+		return 0, fmt.Errorf("ensure directory <%s>, %w", target, err)
+	}
+
+	mode := zf.Mode()
+
+	switch {
+	case mode.IsDir():
+		if err := os.MkdirAll(target, mode.Perm()); err != nil {
+			return 0, fmt.Errorf("create directory <%s>, %w", target, err)
+		}
+
+		return 0, nil
+	case mode&os.ModeSymlink != 0:
+		if err := extractSymlink(zf, target); err != nil {
+			return 0, fmt.Errorf("extract symbolic link, %w", err)
+		}
+
+		return 0, nil
+	default:
+		n, err := extractRegular(zf, target)
+		if err != nil {
+			return n, fmt.Errorf("extract regular file, %w", err)
+		}
+
+		return n, nil
+	}
+}
+
+func extractRegular(zf *zip.File, target string) (int64, error) {
+	rc, err := zf.Open()
+	if err != nil {
+		return 0, fmt.Errorf("open archived file <%s>, %v, %w", zf.Name, err, ErrArchiveNotReadable)
+	}
+
+	defer internal.CloseWithErrCapturef(&err, rc, "extract regular <%s>", zf.Name)
+
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_RDWR|os.O_TRUNC, zf.Mode().Perm())
+	if err != nil {
+		return 0, fmt.Errorf("open extracted file for writing <%s>, %w", target, err)
+	}
+
+	defer internal.CloseWithErrCapturef(&err, f, "extract regular <%s>", target)
+
+	written, err := io.Copy(f, rc)
+	if err != nil {
+		return written, fmt.Errorf("copy extracted file for writing <%s>, %w", target, err)
+	}
+
+	if mtime := zf.Modified; !mtime.IsZero() {
+		if err = os.Chtimes(target, mtime, mtime); err != nil {
+			return written, fmt.Errorf("set atime/mtime <%s>, %w", target, err)
+		}
+	}
+
+	return written, nil
+}
+
+func extractSymlink(zf *zip.File, target string) error {
+	rc, err := zf.Open()
+	if err != nil {
+		return fmt.Errorf("open archived link <%s>, %v, %w", zf.Name, err, ErrArchiveNotReadable)
+	}
+
+	defer internal.CloseWithErrCapturef(&err, rc, "extract symbolic link <%s>", zf.Name)
+
+	lnk, err := io.ReadAll(rc)
+	if err != nil {
+		return fmt.Errorf("read link target <%s>, %w", zf.Name, err)
+	}
+
+	if _, err := os.Lstat(target); err == nil {
+		if err := os.Remove(target); err != nil {
+			return fmt.Errorf("unlink <%s>, %w", target, err)
+		}
+	}
+
+	if err := os.Symlink(string(lnk), target); err != nil {
+		return fmt.Errorf("create symbolic link <%s>, %w", target, err)
+	}
+
+	return nil
+}
+
+func relative(parent string, path string) (string, error) {
+	name := filepath.Base(path)
+
+	rel, err := filepath.Rel(parent, filepath.Dir(path))
+	if err != nil {
+		return "", fmt.Errorf("relative path <%s>, base <%s>, %w", rel, name, err)
+	}
+
+	// NOTICE: filepath.Rel puts "../" when given path is not under parent.
+	for strings.HasPrefix(rel, "../") {
+		rel = strings.TrimPrefix(rel, "../")
+	}
+
+	if rel == ".." {
+		rel = "."
+	}
+
+	rel = filepath.ToSlash(rel)
+
+	return strings.TrimPrefix(filepath.Join(rel, name), "/"), nil
+}
@@ -16,17 +16,30 @@ type Archive struct {
 
 	root             string
 	compressionLevel int
+	threads          int
+	windowSize       int
 	skipSymlinks     bool
 }
 
 // New creates an archive that uses the .tar.zst file format.
-func New(logger log.Logger, root string, skipSymlinks bool, compressionLevel int) *Archive {
-	return &Archive{logger, root, compressionLevel, skipSymlinks}
+// Encoder and decoder use given number of threads, zero keeps the library defaults.
+// Window size must be a power of two between zstd.MinWindowSize and zstd.MaxWindowSize, zero keeps the default.
+func New(logger log.Logger, root string, skipSymlinks bool, compressionLevel int, threads int, windowSize int) *Archive {
+	return &Archive{logger, root, compressionLevel, threads, windowSize, skipSymlinks}
 }
 
 // Create writes content of the given source to an archive, returns written bytes.
 func (a *Archive) Create(srcs []string, w io.Writer) (int64, error) {
-	zw, err := zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(a.compressionLevel)))
+	opts := []zstd.EOption{zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(a.compressionLevel))}
+	if a.threads > 0 {
+		opts = append(opts, zstd.WithEncoderConcurrency(a.threads))
+	}
+
+	if a.windowSize > 0 {
+		opts = append(opts, zstd.WithWindowSize(a.windowSize))
+	}
+
+	zw, err := zstd.NewWriter(w, opts...)
 	if err != nil {
 		return 0, fmt.Errorf("zstd create archive writer, %w", err)
 	}
@@ -43,7 +56,12 @@ func (a *Archive) Create(srcs []string, w io.Writer) (int64, error) {
 
 // Extract reads content from the given archive reader and restores it to the destination, returns written bytes.
 func (a *Archive) Extract(dst string, r io.Reader) (int64, error) {
-	zr, err := zstd.NewReader(r)
+	var opts []zstd.DOption
+	if a.threads > 0 {
+		opts = append(opts, zstd.WithDecoderConcurrency(a.threads))
+	}
+
+	zr, err := zstd.NewReader(r, opts...)
 	if err != nil {
 		return 0, fmt.Errorf("zstd create extract archive reader, %w", err)
 	}
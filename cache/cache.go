@@ -51,9 +51,9 @@ func New(logger log.Logger, s storage.Storage, a archive.Archive, g key.Generato
 
 	return &cache{
 		NewRebuilder(log.With(logger, "component", "rebuilder"), s, a, g,
-			options.fallbackGenerator, options.namespace, options.override),
+			options.fallbackGenerator, options.namespace, options.override, WithReport(options.report)),
 		NewRestorer(log.With(logger, "component", "restorer"), s, a, g,
-			options.fallbackGenerator, options.namespace),
+			options.fallbackGenerator, options.namespace, WithReport(options.report)),
 		NewFlusher(log.With(logger, "component", "flusher"), s, time.Hour),
 	}
 }
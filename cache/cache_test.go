@@ -1,8 +1,109 @@
 package cache
 
-import "testing"
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/meltwater/drone-cache/archive"
+	"github.com/meltwater/drone-cache/key/generator"
+	"github.com/meltwater/drone-cache/storage"
+	"github.com/meltwater/drone-cache/storage/backend/memory"
+	"github.com/meltwater/drone-cache/test"
+)
+
+var (
+	testRoot        = "testdata"
+	testRootMounted = "testdata/mounted"
+	testRootMoved   = "testdata/moved"
+
+	errGenerate = errors.New("generate failed")
+)
 
 func TestCache(t *testing.T) {
-	// Implement me!
-	t.Skip("skipping unimplemented test.")
+	setupDirs(t)
+
+	s := storage.New(log.NewNopLogger(), memory.New(), time.Minute)
+	mount, _ := exampleFileTree(t, "cache")
+
+	c := New(log.NewNopLogger(), s, newArchive(t), generator.NewStatic("key"), WithNamespace("repo"))
+
+	test.Ok(t, c.Rebuild([]string{mount}))
+
+	exists, err := s.Exists(filepath.Join("repo", "key", mount))
+	test.Ok(t, err)
+	test.Equals(t, true, exists)
+
+	moved := moveMounts(t, mount)
+
+	test.Ok(t, c.Restore([]string{mount}))
+	test.EqualDirs(t, moved, testRootMounted, []string{mount})
+
+	// Entries are kept until they expire.
+	test.Ok(t, c.Flush([]string{"repo"}))
+
+	exists, err = s.Exists(filepath.Join("repo", "key", mount))
+	test.Ok(t, err)
+	test.Equals(t, true, exists)
+}
+
+// Helpers
+
+func setupDirs(t *testing.T) {
+	t.Helper()
+
+	test.Ok(t, os.MkdirAll(testRootMounted, 0755))
+	test.Ok(t, os.MkdirAll(testRootMoved, 0755))
+	t.Cleanup(func() { os.RemoveAll(testRoot) })
+}
+
+func newArchive(t *testing.T) archive.Archive {
+	t.Helper()
+
+	cwd, err := os.Getwd()
+	test.Ok(t, err)
+
+	a, err := archive.FromFormat(log.NewNopLogger(), cwd, archive.Tar)
+	test.Ok(t, err)
+
+	return a
+}
+
+// exampleFileTree creates a directory with files to cache, returns the directory and the content of its files.
+func exampleFileTree(t *testing.T, name string) (string, []byte) {
+	t.Helper()
+
+	content := []byte("Hello world4")
+
+	dir, cleanUp := test.CreateTempFilesInDir(t, name, content, testRootMounted)
+	t.Cleanup(cleanUp)
+
+	return dir, content
 }
+
+// moveMounts moves the given mounts away, to compare them with the restored ones, returns the directory they are moved to.
+func moveMounts(t *testing.T, mounts ...string) string {
+	t.Helper()
+
+	dir, cleanUp := test.CreateTempDir(t, "moved", testRootMoved)
+	t.Cleanup(cleanUp)
+
+	for _, m := range mounts {
+		rel, err := filepath.Rel(testRootMounted, m)
+		test.Ok(t, err)
+		test.Ok(t, os.Rename(m, filepath.Join(dir, rel)))
+	}
+
+	return dir
+}
+
+// failingGenerator is a key generator which always fails, to exercise fallback generators.
+type failingGenerator struct{}
+
+func (failingGenerator) Generate(_ ...string) (string, error) { return "", errGenerate }
+
+func (failingGenerator) Check() error { return nil }
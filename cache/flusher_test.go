@@ -1,8 +1,44 @@
 package cache
 
-import "testing"
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/meltwater/drone-cache/storage"
+	"github.com/meltwater/drone-cache/storage/backend"
+	"github.com/meltwater/drone-cache/storage/backend/memory"
+	"github.com/meltwater/drone-cache/test"
+)
 
 func TestFlush(t *testing.T) {
-	// Implement me!
-	t.Skip("skipping unimplemented test.")
+	s := storage.New(log.NewNopLogger(), memory.New(), time.Minute)
+
+	for _, p := range []string{"repo/a/mount", "repo/b/mount", "repository/a/mount"} {
+		test.Ok(t, s.Put(p, strings.NewReader("Hello world4")))
+	}
+
+	// Nothing is expired yet.
+	test.Ok(t, NewFlusher(log.NewNopLogger(), s, time.Hour).Flush([]string{"repo"}))
+
+	entries, err := s.List("")
+	test.Ok(t, err)
+	test.Equals(t, 3, len(entries))
+
+	// Everything is expired, but only under the given prefix.
+	test.Ok(t, NewFlusher(log.NewNopLogger(), s, -time.Second).Flush([]string{"repo"}))
+
+	entries, err = s.List("")
+	test.Ok(t, err)
+	test.Equals(t, 1, len(entries))
+	test.Equals(t, "repository/a/mount", entries[0].Path)
+}
+
+func TestIsExpired(t *testing.T) {
+	expired := IsExpired(time.Hour)
+
+	test.Equals(t, false, expired(backend.FileEntry{LastModified: time.Now()}))
+	test.Equals(t, true, expired(backend.FileEntry{LastModified: time.Now().Add(-2 * time.Hour)}))
 }
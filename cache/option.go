@@ -6,6 +6,7 @@ type options struct {
 	namespace         string
 	fallbackGenerator key.Generator
 	override          bool
+	report            *Report
 }
 
 // Option overrides behavior of Archive.
@@ -39,3 +40,10 @@ func WithOverride(override bool) Option {
 		o.override = override
 	})
 }
+
+// WithReport sets the report that restores and rebuilds describe what they did in.
+func WithReport(r *Report) Option {
+	return optionFunc(func(o *options) {
+		o.report = r
+	})
+}
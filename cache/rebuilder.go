@@ -27,11 +27,19 @@ type rebuilder struct {
 
 	namespace string
 	override  bool
+	report    *Report
 }
 
 // NewRebuilder creates a new cache.Rebuilder.
-func NewRebuilder(logger log.Logger, s storage.Storage, a archive.Archive, g key.Generator, fg key.Generator, namespace string, override bool) Rebuilder { // nolint:lll
-	return rebuilder{logger, a, s, g, fg, namespace, override}
+// Only the WithReport option is used, others are given as arguments.
+func NewRebuilder(logger log.Logger, s storage.Storage, a archive.Archive, g key.Generator, fg key.Generator, namespace string, override bool, opts ...Option) Rebuilder { // nolint:lll
+	options := options{}
+
+	for _, o := range opts {
+		o.apply(&options)
+	}
+
+	return rebuilder{logger, a, s, g, fg, namespace, override, options.report}
 }
 
 // Rebuild rebuilds cache from the files provided with given paths.
@@ -40,11 +48,13 @@ func (r rebuilder) Rebuild(srcs []string) error {
 
 	now := time.Now()
 
-	key, err := r.generateKey()
+	key, fallback, err := r.generateKey()
 	if err != nil {
 		return fmt.Errorf("generate key, %w", err)
 	}
 
+	r.report.setKey(key, fallback)
+
 	var (
 		wg        sync.WaitGroup
 		errs      = &internal.MultiError{}
@@ -66,6 +76,8 @@ func (r rebuilder) Rebuild(srcs []string) error {
 			}
 
 			if exists {
+				r.report.add(MountReport{Path: src, Remote: dst, Skipped: true})
+
 				continue
 			}
 		}
@@ -78,6 +90,7 @@ func (r rebuilder) Rebuild(srcs []string) error {
 			defer wg.Done()
 
 			if err := r.rebuild(src, dst); err != nil {
+				r.report.fail(src)
 				errs.Add(fmt.Errorf("upload from <%s> to <%s>, %w", src, dst, err))
 			}
 		}(dst, src)
@@ -95,8 +108,10 @@ func (r rebuilder) Rebuild(srcs []string) error {
 }
 
 // rebuild pushes the archived file to the cache.
-func (r rebuilder) rebuild(src, dst string) error {
-	src, err := filepath.Abs(filepath.Clean(src))
+func (r rebuilder) rebuild(mount, dst string) error {
+	start := time.Now()
+
+	src, err := filepath.Abs(filepath.Clean(mount))
 	if err != nil {
 		return fmt.Errorf("clean source path, %w", err)
 	}
@@ -123,7 +138,7 @@ func (r rebuilder) rebuild(src, dst string) error {
 
 	level.Info(r.logger).Log("msg", "uploading archived directory", "local", src, "remote", dst)
 
-	sw := &statWriter{}
+	sw := newStatWriter()
 	tr := io.TeeReader(pr, sw)
 
 	if err := r.s.Put(dst, tr); err != nil {
@@ -144,15 +159,24 @@ func (r rebuilder) rebuild(src, dst string) error {
 		"ratio", fmt.Sprintf("%%%0.2f", float64(sw.written)/float64(written)*100.0), // nolint:gomnd
 	)
 
+	r.report.add(MountReport{
+		Path:     mount,
+		Remote:   dst,
+		Size:     sw.written,
+		RawSize:  written,
+		Duration: time.Since(start),
+		Digest:   sw.digest(),
+	})
+
 	return nil
 }
 
 // Helpers
 
-func (r rebuilder) generateKey(parts ...string) (string, error) {
+func (r rebuilder) generateKey(parts ...string) (string, bool, error) {
 	key, err := r.g.Generate(parts...)
 	if err == nil {
-		return key, nil
+		return key, false, nil
 	}
 
 	if r.fg != nil {
@@ -160,9 +184,9 @@ func (r rebuilder) generateKey(parts ...string) (string, error) {
 
 		key, err = r.fg.Generate(parts...)
 		if err == nil {
-			return key, nil
+			return key, true, nil
 		}
 	}
 
-	return "", fmt.Errorf("rebuilder generate key, %w", err)
+	return "", false, fmt.Errorf("rebuilder generate key, %w", err)
 }
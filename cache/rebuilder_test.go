@@ -1,8 +1,67 @@
 package cache
 
-import "testing"
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/meltwater/drone-cache/key/generator"
+	"github.com/meltwater/drone-cache/storage"
+	"github.com/meltwater/drone-cache/storage/backend/memory"
+	"github.com/meltwater/drone-cache/test"
+)
 
 func TestRebuild(t *testing.T) {
-	// Implement me!
-	t.Skip("skipping unimplemented test.")
+	setupDirs(t)
+
+	s := storage.New(log.NewNopLogger(), memory.New(), time.Minute)
+	mount, _ := exampleFileTree(t, "rebuild")
+	dst := filepath.Join("repo", "key", mount)
+
+	// Existing objects are kept without override.
+	test.Ok(t, s.Put(dst, strings.NewReader("existing")))
+
+	r := NewRebuilder(log.NewNopLogger(), s, newArchive(t), generator.NewStatic("key"), nil, "repo", false)
+	test.Ok(t, r.Rebuild([]string{mount}))
+
+	var buf bytes.Buffer
+	test.Ok(t, s.Get(dst, &buf))
+	test.Equals(t, "existing", buf.String())
+
+	r = NewRebuilder(log.NewNopLogger(), s, newArchive(t), generator.NewStatic("key"), nil, "repo", true)
+	test.Ok(t, r.Rebuild([]string{mount}))
+
+	buf.Reset()
+	test.Ok(t, s.Get(dst, &buf))
+	test.Assert(t, buf.String() != "existing", "object is not overridden")
+}
+
+func TestRebuildMissingSource(t *testing.T) {
+	setupDirs(t)
+
+	s := storage.New(log.NewNopLogger(), memory.New(), time.Minute)
+
+	r := NewRebuilder(log.NewNopLogger(), s, newArchive(t), generator.NewStatic("key"), nil, "repo", true)
+	test.NotOk(t, r.Rebuild([]string{filepath.Join(testRootMounted, "idonotexist")}))
+}
+
+func TestRebuildFallbackGenerator(t *testing.T) {
+	setupDirs(t)
+
+	s := storage.New(log.NewNopLogger(), memory.New(), time.Minute)
+	mount, _ := exampleFileTree(t, "rebuild-fallback")
+
+	r := NewRebuilder(log.NewNopLogger(), s, newArchive(t), failingGenerator{}, nil, "repo", true)
+	test.Expected(t, r.Rebuild([]string{mount}), errGenerate)
+
+	r = NewRebuilder(log.NewNopLogger(), s, newArchive(t), failingGenerator{}, generator.NewStatic("fallback"), "repo", true)
+	test.Ok(t, r.Rebuild([]string{mount}))
+
+	exists, err := s.Exists(filepath.Join("repo", "fallback", mount))
+	test.Ok(t, err)
+	test.Equals(t, true, exists)
 }
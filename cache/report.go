@@ -0,0 +1,168 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Statuses of a restore.
+const (
+	// StatusExactHit means that all mounts are restored with the cache key.
+	StatusExactHit = "exact-hit"
+	// StatusPartialHit means that only some of the mounts are restored, or they are restored with the fallback key
+	// or from archives that the storage fell back to, e.g. of restore keys.
+	StatusPartialHit = "partial-hit"
+	// StatusMiss means that none of the mounts are restored.
+	StatusMiss = "miss"
+)
+
+// Report describes what a restore or a rebuild did, e.g. to let later steps know whether the cache was hit.
+// It is filled by the cache that is created with WithReport.
+type Report struct {
+	mu sync.Mutex
+
+	// Key is the cache key that is used.
+	Key string
+	// Fallback reports whether the key is generated by the fallback generator.
+	Fallback bool
+	// Mounts are the mounts that are restored or rebuilt.
+	Mounts []MountReport
+	// Failed are the mounts that could not be restored or rebuilt.
+	Failed []string
+}
+
+// MountReport describes a single restored or rebuilt mount.
+type MountReport struct {
+	// Path is the local path of the mount.
+	Path string
+	// Remote is the path of the archive in the storage.
+	Remote string
+	// Key is the cache key of the restored archive, it differs from the key of the report
+	// if the storage fell back to the archive of another key, e.g. of a restore key.
+	Key string
+	// Size is the size of the archive in bytes.
+	Size int64
+	// RawSize is the size of the archived files in bytes.
+	RawSize int64
+	// Duration is how long it took to restore or rebuild the mount.
+	Duration time.Duration
+	// Digest is the digest of the archive, empty if it is skipped.
+	Digest string
+	// Skipped reports whether the rebuild is skipped, as the archive already exists.
+	Skipped bool
+	// Fallback is the path of the archive that the storage fell back to instead of Remote, e.g. of a restore key.
+	Fallback string
+}
+
+// Status returns the status of a restore.
+func (r *Report) Status() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch {
+	case len(r.Mounts) == 0:
+		return StatusMiss
+	case r.Fallback || len(r.Failed) > 0 || r.fellBack():
+		return StatusPartialHit
+	default:
+		return StatusExactHit
+	}
+}
+
+// Skipped reports whether a rebuild skipped every mount, as all of their archives already exist.
+func (r *Report) Skipped() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.Mounts) == 0 || len(r.Failed) > 0 {
+		return false
+	}
+
+	for _, m := range r.Mounts {
+		if !m.Skipped {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Size returns the total size of the archives in bytes.
+func (r *Report) Size() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var size int64
+	for _, m := range r.Mounts {
+		size += m.Size
+	}
+
+	return size
+}
+
+// Digest returns the digest of the manifest of the mounts, their paths and the digests of their archives.
+// It is the same for the same contents regardless of the order that the mounts are processed.
+func (r *Report) Digest() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	mounts := make([]MountReport, len(r.Mounts))
+	copy(mounts, r.Mounts)
+	sort.Slice(mounts, func(i, j int) bool { return mounts[i].Path < mounts[j].Path })
+
+	h := sha256.New()
+	for _, m := range mounts {
+		fmt.Fprintf(h, "%s %s %d\n", m.Path, m.Digest, m.Size)
+	}
+
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// Helpers
+
+func (r *Report) fellBack() bool {
+	for _, m := range r.Mounts {
+		if m.Fallback != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *Report) setKey(key string, fallback bool) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Key, r.Fallback = key, fallback
+}
+
+func (r *Report) add(m MountReport) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Mounts = append(r.Mounts, m)
+}
+
+func (r *Report) fail(path string) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Failed = append(r.Failed, path)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -24,11 +25,19 @@ type restorer struct {
 	fg key.Generator
 
 	namespace string
+	report    *Report
 }
 
 // NewRestorer creates a new cache.Restorer.
-func NewRestorer(logger log.Logger, s storage.Storage, a archive.Archive, g key.Generator, fg key.Generator, namespace string) Restorer { // nolint:lll
-	return restorer{logger, a, s, g, fg, namespace}
+// Only the WithReport option is used, others are given as arguments.
+func NewRestorer(logger log.Logger, s storage.Storage, a archive.Archive, g key.Generator, fg key.Generator, namespace string, opts ...Option) Restorer { // nolint:lll
+	options := options{}
+
+	for _, o := range opts {
+		o.apply(&options)
+	}
+
+	return restorer{logger, a, s, g, fg, namespace, options.report}
 }
 
 // Restore restores files from the cache provided with given paths.
@@ -37,11 +46,13 @@ func (r restorer) Restore(dsts []string) error {
 
 	now := time.Now()
 
-	key, err := r.generateKey()
+	key, fallback, err := r.generateKey()
 	if err != nil {
 		return fmt.Errorf("generate key, %w", err)
 	}
 
+	r.report.setKey(key, fallback)
+
 	var (
 		wg        sync.WaitGroup
 		errs      = &internal.MultiError{}
@@ -58,7 +69,8 @@ func (r restorer) Restore(dsts []string) error {
 		go func(src, dst string) {
 			defer wg.Done()
 
-			if err := r.restore(src, dst); err != nil {
+			if err := r.restore(namespace, key, dst); err != nil {
+				r.report.fail(dst)
 				errs.Add(fmt.Errorf("download from <%s> to <%s>, %w", src, dst, err))
 			}
 		}(src, dst)
@@ -76,18 +88,27 @@ func (r restorer) Restore(dsts []string) error {
 }
 
 // restore fetches the archived file from the cache and restores to the host machine's file system.
-func (r restorer) restore(src, dst string) error {
+func (r restorer) restore(namespace, key, dst string) error {
 	var err error
 
+	src := filepath.Join(namespace, key, dst)
+
+	start := time.Now()
+
 	pr, pw := io.Pipe()
 	defer internal.CloseWithErrCapturef(&err, pr, "rebuild, pr close <%s>", dst)
 
+	fallbackCh := make(chan string, 1)
+
 	go func() {
 		defer internal.CloseWithErrLogf(r.logger, pw, "pw close defer")
 
 		level.Info(r.logger).Log("msg", "downloading archived directory", "remote", src, "local", dst)
 
-		if err := r.s.Get(src, pw); err != nil {
+		fallback, err := r.get(src, pw)
+		fallbackCh <- fallback
+
+		if err != nil {
 			if err := pw.CloseWithError(fmt.Errorf("get file from storage backend, pipe writer failed, %w", err)); err != nil {
 				level.Error(r.logger).Log("msg", "pw close", "err", err)
 			}
@@ -96,7 +117,15 @@ func (r restorer) restore(src, dst string) error {
 
 	level.Info(r.logger).Log("msg", "extracting archived directory", "remote", src, "local", dst)
 
-	written, err := r.a.Extract(dst, pr)
+	sw := newStatWriter()
+	tr := io.TeeReader(pr, sw)
+
+	written, err := r.a.Extract(dst, tr)
+	if err == nil {
+		// NOTICE: Archives might have trailing bytes that are not read by extraction, they are part of the digest too.
+		_, err = io.Copy(io.Discard, tr)
+	}
+
 	if err != nil {
 		err = fmt.Errorf("extract files from downloaded archive, pipe reader failed, %w", err)
 		if err := pr.CloseWithError(err); err != nil {
@@ -113,15 +142,56 @@ func (r restorer) restore(src, dst string) error {
 		"raw size", written,
 	)
 
+	matched := key
+
+	fallback := <-fallbackCh
+	if fallback != "" {
+		matched = fallbackKey(namespace, key, src, fallback)
+		level.Info(r.logger).Log("msg", "restored archive of another key", "remote", src, "fallback", fallback,
+			"key", matched)
+	}
+
+	r.report.add(MountReport{
+		Path:     dst,
+		Remote:   src,
+		Key:      matched,
+		Fallback: fallback,
+		Size:     sw.written,
+		RawSize:  written,
+		Duration: time.Since(start),
+		Digest:   sw.digest(),
+	})
+
 	return nil
 }
 
 // Helpers
 
-func (r restorer) generateKey(parts ...string) (string, error) {
+// get downloads the archive, and returns the path of the archive that the storage fell back to, if any.
+func (r restorer) get(src string, w io.Writer) (string, error) {
+	if fg, ok := r.s.(storage.FallbackGetter); ok {
+		return fg.GetFallback(src, w) // nolint: wrapcheck
+	}
+
+	return "", r.s.Get(src, w) // nolint: wrapcheck
+}
+
+// fallbackKey returns the cache key of the archive that the storage fell back to,
+// as its path has the same namespace and mount as the requested one.
+func fallbackKey(namespace, key, src, fallback string) string {
+	mount := strings.TrimPrefix(src, filepath.Join(namespace, key))
+
+	if namespace != "" {
+		fallback = strings.TrimPrefix(fallback, namespace+"/")
+	}
+
+	return strings.TrimSuffix(fallback, mount)
+}
+
+func (r restorer) generateKey(parts ...string) (string, bool, error) {
 	key, err := r.g.Generate(parts...)
 	if err == nil {
-		return key, nil
+		return key, false, nil
 	}
 
 	if r.fg != nil {
@@ -129,9 +199,9 @@ func (r restorer) generateKey(parts ...string) (string, error) {
 
 		key, err = r.fg.Generate(parts...)
 		if err == nil {
-			return key, nil
+			return key, true, nil
 		}
 	}
 
-	return "", fmt.Errorf("restorer generate key, %w", err)
+	return "", false, fmt.Errorf("restorer generate key, %w", err)
 }
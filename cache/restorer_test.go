@@ -1,8 +1,143 @@
 package cache
 
-import "testing"
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/meltwater/drone-cache/key/generator"
+	"github.com/meltwater/drone-cache/storage"
+	"github.com/meltwater/drone-cache/storage/backend/memory"
+	"github.com/meltwater/drone-cache/storage/common"
+	"github.com/meltwater/drone-cache/test"
+)
 
 func TestRestore(t *testing.T) {
-	// Implement me!
-	t.Skip("skipping unimplemented test.")
+	setupDirs(t)
+
+	s := storage.New(log.NewNopLogger(), memory.New(), time.Minute)
+	mount, content := exampleFileTree(t, "restore")
+
+	r := NewRebuilder(log.NewNopLogger(), s, newArchive(t), generator.NewStatic("key"), nil, "repo", true)
+	test.Ok(t, r.Rebuild([]string{mount}))
+
+	moved := moveMounts(t, mount)
+
+	rs := NewRestorer(log.NewNopLogger(), s, newArchive(t), generator.NewStatic("key"), nil, "repo")
+	test.Ok(t, rs.Restore([]string{mount}))
+	test.EqualDirs(t, moved, testRootMounted, []string{mount})
+
+	files, err := filepath.Glob(filepath.Join(mount, "*"))
+	test.Ok(t, err)
+	test.Equals(t, 3, len(files))
+
+	got, err := os.ReadFile(files[0])
+	test.Ok(t, err)
+	test.Equals(t, content, got)
+}
+
+func TestRestoreMissing(t *testing.T) {
+	setupDirs(t)
+
+	s := storage.New(log.NewNopLogger(), memory.New(), time.Minute)
+
+	rs := NewRestorer(log.NewNopLogger(), s, newArchive(t), generator.NewStatic("key"), nil, "repo")
+	test.Expected(t, rs.Restore([]string{filepath.Join(testRootMounted, "missing")}), storage.ErrNotFound)
+}
+
+func TestRestoreReport(t *testing.T) {
+	setupDirs(t)
+
+	s := storage.New(log.NewNopLogger(), memory.New(), time.Minute)
+	mount, _ := exampleFileTree(t, "restore-report")
+	missing := filepath.Join(testRootMounted, "missing")
+
+	built := &Report{}
+	r := NewRebuilder(log.NewNopLogger(), s, newArchive(t), generator.NewStatic("key"), nil, "repo", true, WithReport(built))
+	test.Ok(t, r.Rebuild([]string{mount}))
+
+	moveMounts(t, mount)
+
+	restored := &Report{}
+	rs := NewRestorer(log.NewNopLogger(), s, newArchive(t), generator.NewStatic("key"), nil, "repo", WithReport(restored))
+	test.Ok(t, rs.Restore([]string{mount}))
+	test.Equals(t, StatusExactHit, restored.Status())
+	test.Equals(t, "key", restored.Key)
+	test.Equals(t, built.Size(), restored.Size())
+	test.Equals(t, built.Digest(), restored.Digest())
+
+	partial := &Report{}
+	rs = NewRestorer(log.NewNopLogger(), s, newArchive(t), generator.NewStatic("key"), nil, "repo", WithReport(partial))
+	test.NotOk(t, rs.Restore([]string{mount, missing}))
+	test.Equals(t, StatusPartialHit, partial.Status())
+	test.Equals(t, []string{missing}, partial.Failed)
+
+	miss := &Report{}
+	rs = NewRestorer(log.NewNopLogger(), s, newArchive(t), generator.NewStatic("other"), nil, "repo", WithReport(miss))
+	test.NotOk(t, rs.Restore([]string{mount}))
+	test.Equals(t, StatusMiss, miss.Status())
+}
+
+func TestRestoreFallbackGenerator(t *testing.T) {
+	setupDirs(t)
+
+	s := storage.New(log.NewNopLogger(), memory.New(), time.Minute)
+	mount, _ := exampleFileTree(t, "restore-fallback")
+
+	r := NewRebuilder(log.NewNopLogger(), s, newArchive(t), generator.NewStatic("fallback"), nil, "repo", true)
+	test.Ok(t, r.Rebuild([]string{mount}))
+
+	moved := moveMounts(t, mount)
+
+	report := &Report{}
+	rs := NewRestorer(log.NewNopLogger(), s, newArchive(t), failingGenerator{}, generator.NewStatic("fallback"), "repo", WithReport(report))
+	test.Ok(t, rs.Restore([]string{mount}))
+	test.EqualDirs(t, moved, testRootMounted, []string{mount})
+	test.Equals(t, StatusPartialHit, report.Status())
+}
+
+func TestRestoreStorageFallback(t *testing.T) {
+	setupDirs(t)
+
+	b := memory.New()
+	mount, _ := exampleFileTree(t, "restore-storage-fallback")
+
+	r := NewRebuilder(log.NewNopLogger(), storage.New(log.NewNopLogger(), b, time.Minute), newArchive(t),
+		generator.NewStatic("main"), nil, "repo", true)
+	test.Ok(t, r.Rebuild([]string{mount}))
+
+	moved := moveMounts(t, mount)
+
+	s := storage.New(log.NewNopLogger(), &fallbackBackend{Backend: b, from: "repo/feature/", to: "repo/main/"}, time.Minute)
+	report := &Report{}
+	rs := NewRestorer(log.NewNopLogger(), s, newArchive(t), generator.NewStatic("feature"), nil, "repo", WithReport(report))
+	test.Ok(t, rs.Restore([]string{mount}))
+	test.EqualDirs(t, moved, testRootMounted, []string{mount})
+	test.Equals(t, StatusPartialHit, report.Status())
+	test.Equals(t, filepath.Join("repo", "main", mount), report.Mounts[0].Fallback)
+	test.Equals(t, "main", report.Mounts[0].Key)
+}
+
+// fallbackBackend falls back to the objects of another prefix, as restore keys do.
+type fallbackBackend struct {
+	common.Backend
+
+	from, to string
+}
+
+func (b *fallbackBackend) Get(ctx context.Context, p string, w io.Writer) error {
+	if !strings.HasPrefix(p, b.from) {
+		return b.Backend.Get(ctx, p, w) // nolint: wrapcheck
+	}
+
+	p = b.to + strings.TrimPrefix(p, b.from)
+	common.ReportFallback(ctx, p)
+
+	return b.Backend.Get(ctx, p, w) // nolint: wrapcheck
 }
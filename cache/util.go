@@ -1,13 +1,30 @@
 package cache
 
-// statWriter implements io.Writer and keeps track of the written bytes.
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+)
+
+// statWriter implements io.Writer and keeps track of the written bytes and their digest.
 type statWriter struct {
 	written int64
+	h       hash.Hash
+}
+
+func newStatWriter() *statWriter {
+	return &statWriter{h: sha256.New()}
 }
 
 func (s *statWriter) Write(p []byte) (int, error) {
 	size := len(p)
 	s.written += int64(size)
+	s.h.Write(p)
 
 	return size, nil
 }
+
+// digest returns the digest of the written bytes.
+func (s *statWriter) digest() string {
+	return "sha256:" + hex.EncodeToString(s.h.Sum(nil))
+}
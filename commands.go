@@ -0,0 +1,288 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/urfave/cli/v2"
+
+	"github.com/meltwater/drone-cache/internal/command"
+	"github.com/meltwater/drone-cache/storage"
+	"github.com/meltwater/drone-cache/storage/backend"
+)
+
+var (
+	errMissingArgument = errors.New("missing argument")
+	errUnknownFlag     = errors.New("unknown flag")
+)
+
+func commands() []*cli.Command {
+	return []*cli.Command{
+		{
+			Name:      "ls",
+			Usage:     "list stored caches with their size and age",
+			ArgsUsage: "[prefix]",
+			Action: withStorage(func(_ log.Logger, s storage.Storage, c *cli.Context) error {
+				return command.List(s, os.Stdout, c.Args().First(), time.Now())
+			}),
+		},
+		{
+			Name:      "inspect",
+			Usage:     "print details and archive listing of a stored cache",
+			ArgsUsage: "<key>",
+			Action: withStorage(func(l log.Logger, s storage.Storage, c *cli.Context) error {
+				return command.Inspect(l, s, os.Stdout, c.Args().First())
+			}, requireArgument),
+		},
+		{
+			Name:      "rm",
+			Usage:     "remove a stored cache or every cache under a prefix",
+			ArgsUsage: "<key|prefix>",
+			Action: withStorage(func(_ log.Logger, s storage.Storage, c *cli.Context) error {
+				return command.Remove(s, os.Stdout, c.Args().First())
+			}, requireArgument),
+		},
+		{
+			Name:      "verify",
+			Usage:     "download and decompress a stored cache to make sure it is restorable",
+			ArgsUsage: "<key>",
+			Action: withStorage(func(l log.Logger, s storage.Storage, c *cli.Context) error {
+				return command.Verify(l, s, os.Stdout, c.Args().First())
+			}, requireArgument),
+		},
+		{
+			Name:      "du",
+			Usage:     "summarize storage usage per namespace and cache key",
+			ArgsUsage: "[prefix]",
+			Description: "Caches are grouped by their cache key, not by branch. Keys of the default template are md5 hashes\n" +
+				"of the branch, they are labelled with the branches given with --branch.",
+			Flags: []cli.Flag{
+				&cli.StringSliceFlag{
+					Name:  "branch",
+					Usage: "branch to label the cache keys generated for it with",
+				},
+			},
+			Action: withStorage(func(_ log.Logger, s storage.Storage, c *cli.Context) error {
+				return command.DiskUsage(s, os.Stdout, c.Args().First(), c.StringSlice("branch")...)
+			}),
+		},
+		{
+			Name:      "validate",
+			Usage:     "validate the configuration of the backend and probe it with a canary object",
+			ArgsUsage: "[prefix]",
+			Description: "Every problem of the configuration is reported at once. If the configuration is valid,\n" +
+				"a canary object is written, read back and deleted under the given prefix, or the remote root.",
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:  "skip-probe",
+					Usage: "only validate the configuration, without probing the storage",
+				},
+			},
+			Action: validate,
+		},
+		{
+			Name:  "migrate",
+			Usage: "copy stored caches from one backend to another",
+			Description: "Backends are given as \"<backend>?<flag>=<value>&...\" using the global flag names,\n" +
+				"e.g. \"s3?bucket=caches&region=eu-west-1\". Unspecified flags fall back to the global flags.\n" +
+				"Objects already present in the destination with the same size are skipped, to resume a migration.",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "from",
+					Usage:    "source backend spec",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:     "to",
+					Usage:    "destination backend spec",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:  "prefix",
+					Usage: "only migrate objects under the given prefix",
+				},
+				&cli.IntFlag{
+					Name:  "parallelism",
+					Usage: "number of objects copied concurrently",
+					Value: command.DefaultMigrateParallelism,
+				},
+				&cli.BoolFlag{
+					Name:  "verify",
+					Usage: "read copied objects back and compare their sha256 checksum with the source",
+				},
+			},
+			Action: migrate,
+		},
+	}
+}
+
+func migrate(c *cli.Context) error {
+	logger := newLogger(c)
+
+	var ss [2]storage.Storage
+
+	for i, spec := range []string{c.String("from"), c.String("to")} {
+		sc, err := specContext(c, spec)
+		if err != nil {
+			return err
+		}
+
+		b, s, err := newStorage(logger, sc)
+		if err != nil {
+			return err
+		}
+
+		defer closeBackend(logger, b)
+
+		ss[i] = s
+	}
+
+	return command.Migrate(logger, ss[0], ss[1], os.Stdout, c.String("prefix"),
+		command.WithParallelism(c.Int("parallelism")),
+		command.WithVerify(c.Bool("verify")),
+	)
+}
+
+func validate(c *cli.Context) error {
+	cfg, err := config(c)
+	if err != nil {
+		return fmt.Errorf("parse config, %w", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("validate configuration, %w", err)
+	}
+
+	if err := command.ValidateConfig(os.Stdout, cfg.Backend, cfg.BackendConfig()); err != nil {
+		return err // nolint: wrapcheck
+	}
+
+	if c.Bool("skip-probe") {
+		return nil
+	}
+
+	logger := newLogger(c)
+
+	b, s, err := newStorage(logger, c)
+	if err != nil {
+		return err
+	}
+
+	defer closeBackend(logger, b)
+
+	prefix := c.Args().First()
+	if prefix == "" {
+		prefix = cfg.RemoteRoot
+	}
+
+	return command.Probe(s, os.Stdout, prefix) // nolint: wrapcheck
+}
+
+func requireArgument(c *cli.Context) error {
+	if c.Args().First() == "" {
+		return fmt.Errorf("%s requires %s, %w", c.Command.Name, c.Command.ArgsUsage, errMissingArgument)
+	}
+
+	return nil
+}
+
+// withStorage initializes the configured storage backend for the given command action.
+func withStorage(
+	action func(log.Logger, storage.Storage, *cli.Context) error,
+	checks ...func(*cli.Context) error,
+) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		for _, check := range checks {
+			if err := check(c); err != nil {
+				return err
+			}
+		}
+
+		logger := newLogger(c)
+
+		b, s, err := newStorage(logger, c)
+		if err != nil {
+			return err
+		}
+
+		defer closeBackend(logger, b)
+
+		return action(logger, s, c)
+	}
+}
+
+func newStorage(logger log.Logger, c *cli.Context) (backend.Backend, storage.Storage, error) {
+	cfg, err := config(c)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse config, %w", err)
+	}
+
+	b, err := backend.FromConfig(logger, cfg.Backend, cfg.BackendConfig())
+	if err != nil {
+		return nil, nil, fmt.Errorf("initialize backend <%s>, %w", cfg.Backend, err)
+	}
+
+	return b, storage.New(logger, b, cfg.StorageOperationTimeout), nil
+}
+
+func closeBackend(logger log.Logger, b backend.Backend) {
+	if err := backend.Close(b); err != nil {
+		level.Error(logger).Log("msg", "close backend", "err", err)
+	}
+}
+
+// specContext parses a backend spec in the form of "<backend>?<flag>=<value>&<flag>=<value>"
+// and returns a context where given flags override the ones of the parent context.
+// Flags that are not specified fall back to the global flags and environment variables.
+func specContext(c *cli.Context, spec string) (*cli.Context, error) {
+	typ, query, _ := strings.Cut(spec, "?")
+
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, fmt.Errorf("parse backend spec <%s>, %w", spec, err)
+	}
+
+	if typ != "" {
+		values.Set("backend", typ)
+	}
+
+	set := flag.NewFlagSet(spec, flag.ContinueOnError)
+
+	for name, vs := range values {
+		f := lookupFlag(c.App.Flags, name)
+		if f == nil {
+			return nil, fmt.Errorf("backend spec <%s>, flag <%s>, %w", spec, name, errUnknownFlag)
+		}
+
+		if err := f.Apply(set); err != nil {
+			return nil, fmt.Errorf("apply flag <%s>, %w", name, err)
+		}
+
+		for _, v := range vs {
+			if err := set.Set(name, v); err != nil {
+				return nil, fmt.Errorf("set flag <%s>, %w", name, err)
+			}
+		}
+	}
+
+	return cli.NewContext(c.App, set, c), nil
+}
+
+func lookupFlag(flags []cli.Flag, name string) cli.Flag {
+	for _, f := range flags {
+		for _, n := range f.Names() {
+			if n == name {
+				return f
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,266 @@
+// Package command implements operational commands to manage caches stored in a storage backend.
+package command
+
+import (
+	"crypto/md5" // #nosec
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/meltwater/drone-cache/archive"
+	"github.com/meltwater/drone-cache/internal"
+	keygen "github.com/meltwater/drone-cache/key/generator"
+	"github.com/meltwater/drone-cache/storage"
+	"github.com/meltwater/drone-cache/storage/common"
+)
+
+const (
+	tabMinWidth = 0
+	tabWidth    = 8
+	tabPadding  = 2
+)
+
+// ErrNotFound means that no objects found for the given key or prefix.
+var ErrNotFound = errors.New("no objects found")
+
+// List writes key, size and age of every object under the given prefix.
+func List(s storage.Storage, w io.Writer, prefix string, now time.Time) error {
+	entries, err := list(s, prefix)
+	if err != nil {
+		return err
+	}
+
+	tw := newTabWriter(w)
+	fmt.Fprintln(tw, "KEY\tSIZE\tAGE")
+
+	for _, e := range entries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", e.Path, humanize.Bytes(uint64(e.Size)), now.Sub(e.LastModified).Round(time.Second))
+	}
+
+	return tw.Flush() // nolint:wrapcheck
+}
+
+// Inspect writes details and archive listing of every object under the given key.
+func Inspect(l log.Logger, s storage.Storage, w io.Writer, key string) error {
+	entries, err := list(s, key)
+	if err != nil {
+		return err
+	}
+
+	for i, e := range entries {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+
+		format, items, err := read(l, s, e.Path)
+		if err != nil {
+			return fmt.Errorf("read archive <%s>, %w", e.Path, err)
+		}
+
+		fmt.Fprintf(w, "Object:        %s\n", e.Path)
+		fmt.Fprintf(w, "Size:          %s\n", humanize.Bytes(uint64(e.Size)))
+		fmt.Fprintf(w, "Last Modified: %s\n", e.LastModified.UTC().Format(time.RFC3339))
+		fmt.Fprintf(w, "Format:        %s\n", format)
+		fmt.Fprintf(w, "Entries:       %d\n\n", len(items))
+
+		tw := newTabWriter(w)
+		fmt.Fprintln(tw, "MODE\tSIZE\tMODIFIED\tNAME")
+
+		for _, item := range items {
+			name := item.Name
+			if item.Linkname != "" {
+				name += " -> " + item.Linkname
+			}
+
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n",
+				item.Mode, humanize.Bytes(uint64(item.Size)), item.ModTime.UTC().Format(time.RFC3339), name)
+		}
+
+		if err := tw.Flush(); err != nil {
+			return fmt.Errorf("flush listing, %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Remove deletes every object under the given key or prefix.
+func Remove(s storage.Storage, w io.Writer, prefix string) error {
+	entries, err := list(s, prefix)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if err := s.Delete(e.Path); err != nil {
+			return fmt.Errorf("delete <%s>, %w", e.Path, err)
+		}
+
+		fmt.Fprintf(w, "deleted %s\n", e.Path)
+	}
+
+	return nil
+}
+
+// Verify downloads and fully decompresses every object under the given key, to check they are restorable.
+func Verify(l log.Logger, s storage.Storage, w io.Writer, key string) error {
+	entries, err := list(s, key)
+	if err != nil {
+		return err
+	}
+
+	errs := &internal.MultiError{}
+
+	for _, e := range entries {
+		format, items, err := read(l, s, e.Path)
+		if err != nil {
+			fmt.Fprintf(w, "FAILED %s: %v\n", e.Path, err)
+			errs.Add(fmt.Errorf("verify <%s>, %w", e.Path, err))
+
+			continue
+		}
+
+		fmt.Fprintf(w, "OK     %s (%s, %d entries)\n", e.Path, format, len(items))
+	}
+
+	return errs.Err()
+}
+
+// DiskUsage writes number of objects and total size of the stored caches, per namespace and cache key.
+// Cache keys are not branches, the default keys are md5 hashes of the branch, so they are labelled
+// with the given branches whose default or fallback key they are.
+func DiskUsage(s storage.Storage, w io.Writer, prefix string, branches ...string) error {
+	entries, err := list(s, prefix)
+	if err != nil {
+		return err
+	}
+
+	labels, err := branchKeys(branches)
+	if err != nil {
+		return err
+	}
+
+	type usage struct {
+		namespace, key string
+		objects        int
+		size           int64
+	}
+
+	var (
+		usages = map[string]*usage{}
+		total  usage
+	)
+
+	for _, e := range entries {
+		// Objects are stored as <namespace>/<cache key>/<mount>.
+		parts := strings.SplitN(e.Path, "/", 3) // nolint:gomnd
+
+		u := usage{namespace: parts[0]}
+		if len(parts) > 1 {
+			u.key = parts[1]
+		}
+
+		id := u.namespace + "/" + u.key
+		if _, ok := usages[id]; !ok {
+			usages[id] = &u
+		}
+
+		usages[id].objects++
+		usages[id].size += e.Size
+		total.objects++
+		total.size += e.Size
+	}
+
+	ids := make([]string, 0, len(usages))
+	for id := range usages {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	tw := newTabWriter(w)
+	fmt.Fprintln(tw, "NAMESPACE\tKEY\tBRANCH\tOBJECTS\tSIZE")
+
+	for _, id := range ids {
+		u := usages[id]
+
+		branch, ok := labels[u.key]
+		if !ok {
+			branch = "-"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", u.namespace, u.key, branch, u.objects, humanize.Bytes(uint64(u.size)))
+	}
+
+	fmt.Fprintf(tw, "TOTAL\t\t\t%d\t%s\n", total.objects, humanize.Bytes(uint64(total.size)))
+
+	return tw.Flush() // nolint:wrapcheck
+}
+
+// Helpers
+
+// branchKeys returns the given branches by the keys that the plugin generates for them without a template,
+// the md5 hash of the branch, and the branch itself as the fallback key.
+func branchKeys(branches []string) (map[string]string, error) {
+	labels := map[string]string{}
+
+	for _, b := range branches {
+		k, err := keygen.NewHash(md5.New, b).Generate()
+		if err != nil {
+			return nil, fmt.Errorf("generate key of branch <%s>, %w", b, err)
+		}
+
+		labels[k] = b
+		labels[b] = b
+	}
+
+	return labels, nil
+}
+
+func list(s storage.Storage, prefix string) ([]common.FileEntry, error) {
+	entries, err := s.List(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("list <%s>, %w", prefix, err)
+	}
+
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("<%s>, %w", prefix, ErrNotFound)
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+
+	return entries, nil
+}
+
+func read(l log.Logger, s storage.Storage, p string) (string, []archive.Entry, error) {
+	pr, pw := io.Pipe()
+	defer internal.CloseWithErrLogf(l, pr, "pr close defer")
+
+	go func() {
+		defer internal.CloseWithErrLogf(l, pw, "pw close defer")
+
+		if err := s.Get(p, pw); err != nil {
+			if err := pw.CloseWithError(fmt.Errorf("get file from storage backend, %w", err)); err != nil {
+				level.Error(l).Log("msg", "pw close", "err", err)
+			}
+		}
+	}()
+
+	format, entries, err := archive.List(pr)
+	if err != nil {
+		return format, entries, fmt.Errorf("list archive, %w", err)
+	}
+
+	return format, entries, nil
+}
+
+func newTabWriter(w io.Writer) *tabwriter.Writer {
+	return tabwriter.NewWriter(w, tabMinWidth, tabWidth, tabPadding, ' ', 0)
+}
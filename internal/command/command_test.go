@@ -0,0 +1,157 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/meltwater/drone-cache/archive"
+	"github.com/meltwater/drone-cache/storage"
+	"github.com/meltwater/drone-cache/storage/backend"
+	"github.com/meltwater/drone-cache/storage/backend/filesystem"
+	"github.com/meltwater/drone-cache/test"
+)
+
+var testRoot = "testdata"
+
+func TestList(t *testing.T) {
+	s := setup(t)
+
+	var buf bytes.Buffer
+	test.Ok(t, List(s, &buf, "repo", time.Now()))
+
+	out := buf.String()
+	test.Assert(t, strings.HasPrefix(out, "KEY"), "missing header: %s", out)
+	test.Assert(t, strings.Contains(out, "repo/main/cache"), "missing object: %s", out)
+	test.Assert(t, strings.Contains(out, "repo/feature/cache"), "missing object: %s", out)
+
+	test.Expected(t, List(s, &buf, "missing", time.Now()), ErrNotFound)
+}
+
+func TestInspect(t *testing.T) {
+	s := setup(t)
+
+	var buf bytes.Buffer
+	test.Ok(t, Inspect(log.NewNopLogger(), s, &buf, "repo/main/cache"))
+
+	out := buf.String()
+	test.Assert(t, strings.Contains(out, "Format:        "+archive.Gzip), "missing format: %s", out)
+	test.Assert(t, strings.Contains(out, "hello.txt"), "missing entry: %s", out)
+}
+
+func TestRemove(t *testing.T) {
+	s := setup(t)
+
+	var buf bytes.Buffer
+	test.Ok(t, Remove(s, &buf, "repo/main"))
+	test.Equals(t, "deleted repo/main/cache\n", buf.String())
+
+	exists, err := s.Exists("repo/main/cache")
+	test.Ok(t, err)
+	test.Equals(t, false, exists)
+
+	test.Expected(t, Remove(s, &buf, "repo/main"), ErrNotFound)
+}
+
+func TestVerify(t *testing.T) {
+	s := setup(t)
+
+	var buf bytes.Buffer
+	test.Ok(t, Verify(log.NewNopLogger(), s, &buf, "repo/main"))
+	test.Assert(t, strings.HasPrefix(buf.String(), "OK"), "unexpected output: %s", buf.String())
+
+	test.Ok(t, s.Put("repo/broken/cache", strings.NewReader("definitely not an archive")))
+
+	buf.Reset()
+	test.NotOk(t, Verify(log.NewNopLogger(), s, &buf, "repo/broken"))
+	test.Assert(t, strings.HasPrefix(buf.String(), "FAILED"), "unexpected output: %s", buf.String())
+}
+
+func TestDiskUsage(t *testing.T) {
+	s := setup(t)
+
+	var buf bytes.Buffer
+	test.Ok(t, DiskUsage(s, &buf, ""))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	test.Equals(t, 4, len(lines))
+	test.Assert(t, strings.HasPrefix(lines[1], "repo"), "unexpected output: %s", buf.String())
+	test.Assert(t, strings.Contains(lines[1], "feature"), "unexpected output: %s", buf.String())
+	test.Assert(t, strings.Contains(lines[2], "main"), "unexpected output: %s", buf.String())
+	test.Assert(t, strings.HasPrefix(lines[3], "TOTAL"), "unexpected output: %s", buf.String())
+}
+
+func TestDiskUsageBranches(t *testing.T) {
+	s := setup(t)
+
+	// NOTICE: Key generated by default for the develop branch, the md5 hash of its name.
+	test.Ok(t, s.Put("repo/a19ea622182c63ddc19bb22cde982b82/cache", strings.NewReader("cache")))
+
+	var buf bytes.Buffer
+	test.Ok(t, DiskUsage(s, &buf, "", "develop", "main"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	test.Equals(t, 5, len(lines))
+	test.Equals(t, []string{"repo", "a19ea622182c63ddc19bb22cde982b82", "develop"}, strings.Fields(lines[1])[:3])
+	test.Equals(t, []string{"repo", "feature", "-"}, strings.Fields(lines[2])[:3])
+	test.Equals(t, []string{"repo", "main", "main"}, strings.Fields(lines[3])[:3])
+}
+
+func TestValidateConfig(t *testing.T) {
+	var buf bytes.Buffer
+	test.Ok(t, ValidateConfig(&buf, backend.FileSystem, backend.Config{FileSystem: filesystem.Config{CacheRoot: testRoot}}))
+	test.Equals(t, "OK     configuration of backend <filesystem>\n", buf.String())
+
+	buf.Reset()
+	test.Expected(t, ValidateConfig(&buf, backend.SFTP, backend.Config{}), backend.ErrInvalidConfig)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	test.Assert(t, len(lines) > 1, "expected every problem to be written: %s", buf.String())
+	test.Equals(t, "FAILED host is required", lines[0])
+}
+
+func TestProbe(t *testing.T) {
+	s := setup(t)
+
+	var buf bytes.Buffer
+	test.Ok(t, Probe(s, &buf, "repo"))
+	test.Assert(t, strings.HasPrefix(buf.String(), "OK     probe of storage"), "unexpected output: %s", buf.String())
+}
+
+// Helpers
+
+func setup(t *testing.T) storage.Storage {
+	test.Ok(t, os.MkdirAll(testRoot, 0755))
+	t.Cleanup(func() { os.RemoveAll(testRoot) })
+
+	src, srcClean := test.CreateTempDir(t, "command_src", testRoot)
+	t.Cleanup(srcClean)
+
+	file, fileClean := test.CreateTempFile(t, "hello.txt", []byte("hello\ndrone!\n"), src)
+	t.Cleanup(fileClean)
+
+	dir, dirClean := test.CreateTempDir(t, "command_cache", testRoot)
+	t.Cleanup(dirClean)
+
+	b, err := filesystem.New(log.NewNopLogger(), filesystem.Config{CacheRoot: dir})
+	test.Ok(t, err)
+
+	s := storage.New(log.NewNopLogger(), b, time.Minute)
+
+	a, err := archive.FromFormat(log.NewNopLogger(), src, archive.Gzip)
+	test.Ok(t, err)
+
+	for _, p := range []string{"repo/main/cache", "repo/feature/cache"} {
+		var buf bytes.Buffer
+		_, err := a.Create([]string{file}, &buf)
+		test.Ok(t, err)
+		test.Ok(t, b.Put(context.TODO(), p, &buf))
+	}
+
+	return s
+}
@@ -0,0 +1,173 @@
+package command
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+
+	"github.com/meltwater/drone-cache/internal"
+	"github.com/meltwater/drone-cache/storage"
+	"github.com/meltwater/drone-cache/storage/common"
+)
+
+// DefaultMigrateParallelism is the default number of objects copied concurrently.
+const DefaultMigrateParallelism = 4
+
+// ErrChecksumMismatch means that the copied object differs from its source.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+type migrateOptions struct {
+	parallelism int
+	verify      bool
+}
+
+// MigrateOption overrides behavior of Migrate.
+type MigrateOption interface {
+	apply(*migrateOptions)
+}
+
+type migrateOptionFunc func(*migrateOptions)
+
+func (f migrateOptionFunc) apply(o *migrateOptions) {
+	f(o)
+}
+
+// WithParallelism sets number of objects copied concurrently.
+func WithParallelism(n int) MigrateOption {
+	return migrateOptionFunc(func(o *migrateOptions) {
+		o.parallelism = n
+	})
+}
+
+// WithVerify sets copied objects should be read back and compared with their source.
+func WithVerify(verify bool) MigrateOption {
+	return migrateOptionFunc(func(o *migrateOptions) {
+		o.verify = verify
+	})
+}
+
+// Migrate copies every object under the given prefix from src to dst.
+// Objects already present in dst with the same size are skipped, so an interrupted migration can be resumed.
+func Migrate(l log.Logger, src, dst storage.Storage, w io.Writer, prefix string, opts ...MigrateOption) error {
+	options := migrateOptions{parallelism: DefaultMigrateParallelism}
+	for _, o := range opts {
+		o.apply(&options)
+	}
+
+	if options.parallelism < 1 {
+		options.parallelism = 1
+	}
+
+	entries, err := list(src, prefix)
+	if err != nil {
+		return err
+	}
+
+	existing, err := dst.List(prefix)
+	if err != nil {
+		return fmt.Errorf("list destination <%s>, %w", prefix, err)
+	}
+
+	sizes := make(map[string]int64, len(existing))
+	for _, e := range existing {
+		sizes[e.Path] = e.Size
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs = &internal.MultiError{}
+
+		copied, skipped int
+		jobs            = make(chan common.FileEntry)
+	)
+
+	for i := 0; i < options.parallelism; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for e := range jobs {
+				if size, ok := sizes[e.Path]; ok && size == e.Size {
+					mu.Lock()
+					skipped++
+					fmt.Fprintf(w, "skipped %s\n", e.Path)
+					mu.Unlock()
+
+					continue
+				}
+
+				err := migrate(l, src, dst, e.Path, options.verify)
+
+				mu.Lock()
+				if err != nil {
+					errs.Add(fmt.Errorf("migrate <%s>, %w", e.Path, err))
+					fmt.Fprintf(w, "FAILED  %s: %v\n", e.Path, err)
+				} else {
+					copied++
+					fmt.Fprintf(w, "copied  %s\n", e.Path)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, e := range entries {
+		jobs <- e
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	fmt.Fprintf(w, "%d copied, %d skipped, %d failed\n", copied, skipped, len(entries)-copied-skipped)
+
+	return errs.Err()
+}
+
+// Helpers
+
+func migrate(l log.Logger, src, dst storage.Storage, p string, verify bool) error {
+	pr, pw := io.Pipe()
+	defer internal.CloseWithErrLogf(l, pr, "pr close defer")
+
+	h := sha256.New()
+
+	go func() {
+		defer internal.CloseWithErrLogf(l, pw, "pw close defer")
+
+		if err := src.Get(p, io.MultiWriter(pw, h)); err != nil {
+			if err := pw.CloseWithError(fmt.Errorf("get file from source backend, %w", err)); err != nil {
+				level.Error(l).Log("msg", "pw close", "err", err)
+			}
+		}
+	}()
+
+	if err := dst.Put(p, pr); err != nil {
+		return fmt.Errorf("put file to destination backend, %w", err)
+	}
+
+	if !verify {
+		return nil
+	}
+
+	want := h.Sum(nil)
+
+	h.Reset()
+
+	if err := dst.Get(p, h); err != nil {
+		return fmt.Errorf("get file from destination backend, %w", err)
+	}
+
+	if got := h.Sum(nil); !bytes.Equal(want, got) {
+		return fmt.Errorf("source <%x>, destination <%x>, %w", want, got, ErrChecksumMismatch)
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package command
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/meltwater/drone-cache/storage"
+	"github.com/meltwater/drone-cache/storage/backend/filesystem"
+	"github.com/meltwater/drone-cache/test"
+)
+
+func TestMigrate(t *testing.T) {
+	src := setup(t)
+
+	dir, dirClean := test.CreateTempDir(t, "command_migrate", testRoot)
+	t.Cleanup(dirClean)
+
+	b, err := filesystem.New(log.NewNopLogger(), filesystem.Config{CacheRoot: dir})
+	test.Ok(t, err)
+
+	dst := storage.New(log.NewNopLogger(), b, time.Minute)
+
+	var buf bytes.Buffer
+	test.Ok(t, Migrate(log.NewNopLogger(), src, dst, &buf, "repo", WithParallelism(2), WithVerify(true)))
+	test.Assert(t, strings.HasSuffix(buf.String(), "2 copied, 0 skipped, 0 failed\n"), "unexpected output: %s", buf.String())
+
+	for _, p := range []string{"repo/main/cache", "repo/feature/cache"} {
+		var want, got bytes.Buffer
+		test.Ok(t, src.Get(p, &want))
+		test.Ok(t, dst.Get(p, &got))
+		test.Equals(t, want.Bytes(), got.Bytes())
+	}
+
+	// Resume skips objects which are already migrated.
+	test.Ok(t, dst.Delete("repo/main/cache"))
+
+	buf.Reset()
+	test.Ok(t, Migrate(log.NewNopLogger(), src, dst, &buf, "repo"))
+	test.Assert(t, strings.Contains(buf.String(), "skipped repo/feature/cache"), "unexpected output: %s", buf.String())
+	test.Assert(t, strings.HasSuffix(buf.String(), "1 copied, 1 skipped, 0 failed\n"), "unexpected output: %s", buf.String())
+
+	test.Expected(t, Migrate(log.NewNopLogger(), src, dst, &buf, "missing"), ErrNotFound)
+}
@@ -0,0 +1,50 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/meltwater/drone-cache/storage"
+	"github.com/meltwater/drone-cache/storage/backend"
+	"github.com/meltwater/drone-cache/storage/common"
+)
+
+// ValidateConfig writes every problem of the configuration of the given backend type.
+func ValidateConfig(w io.Writer, backendType string, cfg backend.Config) error {
+	err := backend.Validate(backendType, cfg)
+	if err == nil {
+		fmt.Fprintf(w, "OK     configuration of backend <%s>\n", backendType)
+
+		return nil
+	}
+
+	problems := []string{err.Error()}
+
+	var verr *common.ValidationError
+	if errors.As(err, &verr) {
+		problems = verr.Problems
+	}
+
+	for _, p := range problems {
+		fmt.Fprintf(w, "FAILED %s\n", p)
+	}
+
+	return fmt.Errorf("validate configuration of backend <%s>, %w", backendType, err)
+}
+
+// Probe writes, reads back and deletes a canary object under the given prefix, to check the storage is usable.
+func Probe(s storage.Storage, w io.Writer, prefix string) error {
+	start := time.Now()
+
+	if err := storage.Probe(s, prefix); err != nil {
+		fmt.Fprintf(w, "FAILED probe of storage: %v\n", err)
+
+		return fmt.Errorf("probe storage, %w", err)
+	}
+
+	fmt.Fprintf(w, "OK     probe of storage (%s)\n", time.Since(start).Round(time.Millisecond))
+
+	return nil
+}
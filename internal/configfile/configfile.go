@@ -0,0 +1,269 @@
+// Package configfile reads the settings of the plugin from a YAML or JSON configuration file.
+package configfile
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPaths are the paths, relative to the workspace, that the configuration file is looked up at when none is given.
+// nolint: gochecknoglobals
+var DefaultPaths = []string{".drone-cache.yml", ".drone-cache.yaml", ".drone-cache.json"}
+
+var (
+	// ErrUnknownSetting means that a key of the configuration file does not correspond to any setting.
+	ErrUnknownSetting = errors.New("unknown setting")
+	// ErrUnsetVariable means that a variable referenced with ${VAR} is not set.
+	ErrUnsetVariable = errors.New("variable is not set")
+)
+
+// nolint: gochecknoglobals
+var variable = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// Setting is a setting read from the configuration file, with all of its values for slice settings.
+type Setting struct {
+	Name   string
+	Values []string
+}
+
+// Resolver returns the name of the setting of the given key of the configuration file, false if there is none.
+type Resolver func(key string) (string, bool)
+
+// Lookup returns the value of the given environment variable, as os.LookupEnv does.
+type Lookup func(name string) (string, bool)
+
+// Find returns the first of DefaultPaths that exists in the given directory, empty if none of them does.
+func Find(dir string) string {
+	for _, p := range DefaultPaths {
+		path := filepath.Join(dir, p)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+
+	return ""
+}
+
+// Load reads the configuration file at the given path, and returns its settings sorted by name.
+//
+// Keys of nested mappings are joined with dots, e.g. "azure: {account-name: x}" is "azure.account-name",
+// and resolved to setting names with the given resolver. Mappings of settings that are themselves settings
+// are given as <key>=<value> pairs, and lists of mappings as "<backend>?<setting>=<value>&..." specs.
+// References to environment variables in string values, written as ${VAR}, are expanded with the given lookup.
+func Load(path string, resolve Resolver, lookup Lookup) ([]Setting, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read configuration file, %w", err)
+	}
+
+	var root map[string]interface{}
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("parse configuration file <%s>, %w", path, err)
+	}
+
+	l := loader{resolve: resolve, lookup: lookup, settings: map[string][]string{}}
+	if err := l.mapping("", root); err != nil {
+		return nil, fmt.Errorf("configuration file <%s>, %w", path, err)
+	}
+
+	settings := make([]Setting, 0, len(l.settings))
+	for name, values := range l.settings {
+		settings = append(settings, Setting{Name: name, Values: values})
+	}
+
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Name < settings[j].Name })
+
+	return settings, nil
+}
+
+// Helpers
+
+type loader struct {
+	resolve  Resolver
+	lookup   Lookup
+	settings map[string][]string
+}
+
+func (l *loader) mapping(prefix string, m map[string]interface{}) error {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+
+		if err := l.value(key, m[k]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (l *loader) value(key string, v interface{}) error {
+	if nested, ok := v.(map[string]interface{}); ok {
+		// NOTICE: Mappings are settings themselves only if their keys are not settings, e.g. "backend.settings".
+		if name, ok := l.resolve(key); ok && !l.resolvesAny(key, nested) {
+			pairs, err := l.pairs(key, nested)
+			if err != nil {
+				return err
+			}
+
+			l.settings[name] = append(l.settings[name], pairs...)
+
+			return nil
+		}
+
+		return l.mapping(key, nested)
+	}
+
+	name, ok := l.resolve(key)
+	if !ok {
+		return fmt.Errorf("<%s>, %w", key, ErrUnknownSetting)
+	}
+
+	values, err := l.values(key, v)
+	if err != nil {
+		return err
+	}
+
+	l.settings[name] = append(l.settings[name], values...)
+
+	return nil
+}
+
+func (l *loader) resolvesAny(prefix string, m map[string]interface{}) bool {
+	for k := range m {
+		if _, ok := l.resolve(prefix + "." + k); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (l *loader) values(key string, v interface{}) ([]string, error) {
+	items, ok := v.([]interface{})
+	if !ok {
+		s, err := l.scalar(key, v)
+		if err != nil || v == nil {
+			return nil, err
+		}
+
+		return []string{s}, nil
+	}
+
+	values := make([]string, 0, len(items))
+
+	for i, item := range items {
+		var (
+			s   string
+			err error
+		)
+
+		if m, ok := item.(map[string]interface{}); ok {
+			s, err = l.spec(fmt.Sprintf("%s[%d]", key, i), m)
+		} else {
+			s, err = l.scalar(key, item)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, s)
+	}
+
+	return values, nil
+}
+
+// spec converts a mapping to a "<backend>?<setting>=<value>&..." spec, as composite backends take them.
+func (l *loader) spec(key string, m map[string]interface{}) (string, error) {
+	sub := loader{resolve: l.resolve, lookup: l.lookup, settings: map[string][]string{}}
+	if err := sub.mapping("", m); err != nil {
+		return "", fmt.Errorf("%s, %w", key, err)
+	}
+
+	typ := sub.settings["backend"]
+	delete(sub.settings, "backend")
+
+	query := url.Values(sub.settings).Encode()
+	if len(typ) == 0 {
+		return "?" + query, nil
+	}
+
+	if query == "" {
+		return typ[0], nil
+	}
+
+	return typ[0] + "?" + query, nil
+}
+
+func (l *loader) pairs(key string, m map[string]interface{}) ([]string, error) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+
+	for _, k := range keys {
+		s, err := l.scalar(key+"."+k, m[k])
+		if err != nil {
+			return nil, err
+		}
+
+		pairs = append(pairs, k+"="+s)
+	}
+
+	return pairs, nil
+}
+
+func (l *loader) scalar(key string, v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return l.expand(key, v)
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int, int64, uint64, float64:
+		return fmt.Sprint(v), nil
+	default:
+		return "", fmt.Errorf("<%s>, unsupported value of type <%T>", key, v)
+	}
+}
+
+// expand expands the references to environment variables, only after parsing, so that their values are never parsed.
+func (l *loader) expand(key, s string) (string, error) {
+	var err error
+
+	expanded := variable.ReplaceAllStringFunc(s, func(ref string) string {
+		name := variable.FindStringSubmatch(ref)[1]
+
+		v, ok := l.lookup(name)
+		if !ok && err == nil {
+			err = fmt.Errorf("<%s>, <%s>, %w", key, name, ErrUnsetVariable)
+		}
+
+		return v
+	})
+
+	return expanded, err
+}
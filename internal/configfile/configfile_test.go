@@ -0,0 +1,118 @@
+package configfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/meltwater/drone-cache/test"
+)
+
+// nolint: gochecknoglobals
+var flags = map[string]bool{
+	"backend":                   true,
+	"mount":                     true,
+	"rebuild":                   true,
+	"compression-level":         true,
+	"azure.account-name":        true,
+	"azure.account-key":         true,
+	"backend.settings":          true,
+	"mirror.backends":           true,
+	"filesystem.cache-root":     true,
+	"backend.operation-timeout": true,
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	path := write(t, "config.yml", `
+backend: mirror
+mount: [node_modules, .cache]
+rebuild: true
+compression-level: 4
+azure:
+  account-name: account
+  account-key: ${ACCOUNT_KEY}
+backend.settings:
+  region: eu-west-1
+backend.operation-timeout: 1m
+mirror:
+  backends:
+    - backend: filesystem
+      filesystem:
+        cache-root: /tmp/cache
+`)
+
+	settings, err := Load(path, resolve, lookup(map[string]string{"ACCOUNT_KEY": `"quoted": value`}))
+	test.Ok(t, err)
+	test.Equals(t, []Setting{
+		{Name: "azure.account-key", Values: []string{`"quoted": value`}},
+		{Name: "azure.account-name", Values: []string{"account"}},
+		{Name: "backend", Values: []string{"mirror"}},
+		{Name: "backend.operation-timeout", Values: []string{"1m"}},
+		{Name: "backend.settings", Values: []string{"region=eu-west-1"}},
+		{Name: "compression-level", Values: []string{"4"}},
+		{Name: "mirror.backends", Values: []string{"filesystem?filesystem.cache-root=%2Ftmp%2Fcache"}},
+		{Name: "mount", Values: []string{"node_modules", ".cache"}},
+		{Name: "rebuild", Values: []string{"true"}},
+	}, settings)
+}
+
+func TestLoadJSON(t *testing.T) {
+	t.Parallel()
+
+	path := write(t, "config.json", `{"backend": "filesystem", "filesystem": {"cache-root": "/tmp/cache"}}`)
+
+	settings, err := Load(path, resolve, lookup(nil))
+	test.Ok(t, err)
+	test.Equals(t, []Setting{
+		{Name: "backend", Values: []string{"filesystem"}},
+		{Name: "filesystem.cache-root", Values: []string{"/tmp/cache"}},
+	}, settings)
+}
+
+func TestLoadErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := Load(write(t, "unknown.yml", "bucket: cache"), resolve, lookup(nil))
+	test.Expected(t, err, ErrUnknownSetting)
+
+	_, err = Load(write(t, "unset.yml", "azure.account-key: ${ACCOUNT_KEY}"), resolve, lookup(nil))
+	test.Expected(t, err, ErrUnsetVariable)
+
+	_, err = Load(write(t, "invalid.yml", "backend: [filesystem"), resolve, lookup(nil))
+	test.NotOk(t, err)
+}
+
+func TestFind(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	test.Equals(t, "", Find(dir))
+
+	test.Ok(t, os.WriteFile(filepath.Join(dir, ".drone-cache.json"), []byte("{}"), 0o600))
+	test.Equals(t, filepath.Join(dir, ".drone-cache.json"), Find(dir))
+}
+
+// Helpers
+
+func resolve(key string) (string, bool) {
+	return key, flags[key]
+}
+
+func lookup(env map[string]string) Lookup {
+	return func(name string) (string, bool) {
+		v, ok := env[name]
+
+		return v, ok
+	}
+}
+
+func write(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	test.Ok(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
@@ -2,6 +2,7 @@ package internal
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"sync"
 )
@@ -48,6 +49,42 @@ func (me *MultiError) Add(err error) {
 	me.errs = append(me.errs, err)
 }
 
+// Is reports whether any of the contained errors matches the target, to let errors.Is look into all of them.
+func (me *MultiError) Is(target error) bool {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	for _, err := range me.errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// As finds the first contained error that matches the target, to let errors.As look into all of them.
+func (me *MultiError) As(target interface{}) bool {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	for _, err := range me.errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Errors returns the contained errors.
+func (me *MultiError) Errors() []error {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+
+	return append([]error{}, me.errs...)
+}
+
 // Err returns the error list as an error or nil if it is empty.
 func (me *MultiError) Err() error {
 	me.mu.Lock()
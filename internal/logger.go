@@ -17,7 +17,8 @@ const (
 	LogLevelDebug = "debug"
 )
 
-func NewLogger(logLevel, logFormat, name string) log.Logger {
+// NewLogger creates a leveled logger, which redacts the given secrets and the values of keys that look like secrets.
+func NewLogger(logLevel, logFormat, name string, secrets ...string) log.Logger {
 	var (
 		logger log.Logger
 		lvl    level.Option
@@ -41,6 +42,8 @@ func NewLogger(logLevel, logFormat, name string) log.Logger {
 		logger = log.NewJSONLogger(log.NewSyncWriter(os.Stderr))
 	}
 
+	// NOTICE: Redacted before the caller is added, so that it reports the callers of the returned logger.
+	logger = NewRedactingLogger(logger, secrets...)
 	logger = level.NewFilter(logger, lvl)
 	logger = log.With(logger, "name", name)
 	logger = log.With(logger, "ts", log.DefaultTimestampUTC, "caller", log.DefaultCaller)
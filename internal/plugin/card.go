@@ -0,0 +1,158 @@
+package plugin
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/meltwater/drone-cache/cache"
+)
+
+// CardSchema is the adaptive card template that renders the cards of drone-cache.
+const CardSchema = "https://raw.githubusercontent.com/meltwater/drone-cache/master/card.json"
+
+// Statuses of a rebuild shown on cards, restores show the statuses of cache.Report.
+const (
+	statusRebuilt = "rebuilt"
+	statusSkipped = "skipped"
+	statusFailed  = "failed"
+)
+
+// Card is the summary of a run of the plugin, rendered by Drone in the build UI.
+type Card struct {
+	Mode     string      `json:"mode"`
+	Backend  string      `json:"backend"`
+	Key      string      `json:"key"`
+	Status   string      `json:"status"`
+	Hit      bool        `json:"hit"`
+	Size     string      `json:"size"`
+	Duration string      `json:"duration"`
+	Error    string      `json:"error"`
+	Mounts   []CardMount `json:"mounts"`
+}
+
+// CardMount is a row of the table of mounts of a card.
+type CardMount struct {
+	Path     string `json:"path"`
+	Status   string `json:"status"`
+	Size     string `json:"size"`
+	RawSize  string `json:"raw_size"`
+	Ratio    string `json:"ratio"`
+	Duration string `json:"duration"`
+}
+
+// NewCard creates the card of a restore or a rebuild from its report.
+func NewCard(mode, backendType string, r *cache.Report, took time.Duration, err error) Card {
+	c := Card{
+		Mode:     mode,
+		Backend:  backendType,
+		Key:      r.Key,
+		Size:     humanize.Bytes(uint64(r.Size())),
+		Duration: took.Round(time.Millisecond).String(),
+		Mounts:   []CardMount{},
+	}
+
+	if err != nil {
+		c.Error = err.Error()
+	}
+
+	switch mode {
+	case OpRestore:
+		c.Status = r.Status()
+		c.Hit = c.Status == cache.StatusExactHit
+	case OpRebuild:
+		switch {
+		case err != nil:
+			c.Status = statusFailed
+		case r.Skipped():
+			c.Status = statusSkipped
+		default:
+			c.Status = statusRebuilt
+		}
+	}
+
+	for _, m := range r.Mounts {
+		row := CardMount{Path: m.Path, Status: statusOK(mode), Size: "-", RawSize: "-", Ratio: "-", Duration: "-"}
+
+		if m.Skipped {
+			row.Status = statusSkipped
+		} else {
+			row.Size = humanize.Bytes(uint64(m.Size))
+			row.RawSize = humanize.Bytes(uint64(m.RawSize))
+			row.Duration = m.Duration.Round(time.Millisecond).String()
+
+			if m.RawSize > 0 {
+				row.Ratio = fmt.Sprintf("%.2f%%", float64(m.Size)/float64(m.RawSize)*100.0) // nolint: gomnd
+			}
+		}
+
+		c.Mounts = append(c.Mounts, row)
+	}
+
+	for _, f := range r.Failed {
+		c.Mounts = append(c.Mounts, CardMount{Path: f, Status: statusFailed, Size: "-", RawSize: "-", Ratio: "-", Duration: "-"})
+	}
+
+	sort.Slice(c.Mounts, func(i, j int) bool { return c.Mounts[i].Path < c.Mounts[j].Path })
+
+	return c
+}
+
+// SkippedCard creates the card of a rebuild that is skipped, as the outputs of the restore report an exact hit.
+func SkippedCard(backendType string, o Outputs) Card {
+	c := Card{Mode: OpRebuild, Backend: backendType, Key: o.Key, Status: statusSkipped, Hit: true, Mounts: []CardMount{}}
+
+	for _, m := range o.Mounts {
+		c.Mounts = append(c.Mounts, CardMount{Path: m, Status: statusSkipped, Size: "-", RawSize: "-", Ratio: "-", Duration: "-"})
+	}
+
+	return c
+}
+
+// WriteCard writes the card to the given path the way Drone reads it.
+// Cards written to standard output or error are encoded within an escape sequence, so that they are told apart from logs.
+func WriteCard(path string, c Card) error {
+	data, err := json.Marshal(struct {
+		Schema string `json:"schema"`
+		Data   Card   `json:"data"`
+	}{CardSchema, c})
+	if err != nil {
+		return fmt.Errorf("marshal card, %w", err)
+	}
+
+	switch path {
+	case "/dev/stdout":
+		return writeCardTo(os.Stdout, data)
+	case "/dev/stderr":
+		return writeCardTo(os.Stderr, data)
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil { // nolint: gosec, gomnd
+		return fmt.Errorf("write card, %w", err)
+	}
+
+	return nil
+}
+
+// Helpers
+
+func statusOK(mode string) string {
+	if mode == OpRebuild {
+		return statusRebuilt
+	}
+
+	return "restored"
+}
+
+func writeCardTo(w io.Writer, data []byte) error {
+	if _, err := fmt.Fprintf(w, "\u001B]1338;%s\u001B]0m\n", base64.StdEncoding.EncodeToString(data)); err != nil {
+		return fmt.Errorf("write card, %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/meltwater/drone-cache/cache"
+	"github.com/meltwater/drone-cache/test"
+)
+
+func TestNewCard(t *testing.T) {
+	t.Parallel()
+
+	report := &cache.Report{
+		Key:    "key",
+		Mounts: []cache.MountReport{{Path: "vendor", Size: 512, RawSize: 2048, Duration: 1500 * time.Millisecond}},
+		Failed: []string{"node_modules"},
+	}
+
+	card := NewCard(OpRestore, "s3", report, 2*time.Second, errors.New("restore failed"))
+	test.Equals(t, cache.StatusPartialHit, card.Status)
+	test.Assert(t, !card.Hit, "partial hit is not a hit")
+	test.Equals(t, "restore failed", card.Error)
+	test.Equals(t, []CardMount{
+		{Path: "node_modules", Status: statusFailed, Size: "-", RawSize: "-", Ratio: "-", Duration: "-"},
+		{Path: "vendor", Status: "restored", Size: "512 B", RawSize: "2.0 kB", Ratio: "25.00%", Duration: "1.5s"},
+	}, card.Mounts)
+
+	card = NewCard(OpRebuild, "s3", &cache.Report{Key: "key"}, time.Second, nil)
+	test.Equals(t, statusRebuilt, card.Status)
+
+	report = &cache.Report{Key: "key", Mounts: []cache.MountReport{{Path: "vendor", Skipped: true}, {Path: "node_modules"}}}
+	card = NewCard(OpRebuild, "s3", report, time.Second, nil)
+	test.Equals(t, statusRebuilt, card.Status)
+
+	// Rebuilds that skipped every mount, as their archives already exist, are skipped.
+	report.Mounts[1].Skipped = true
+	card = NewCard(OpRebuild, "s3", report, time.Second, nil)
+	test.Equals(t, statusSkipped, card.Status)
+	test.Equals(t, statusSkipped, card.Mounts[0].Status)
+}
+
+func TestWriteCard(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "card.json")
+	test.Ok(t, WriteCard(path, SkippedCard("s3", Outputs{Key: "key", Hit: true, Mounts: []string{"vendor"}})))
+
+	data, err := os.ReadFile(path)
+	test.Ok(t, err)
+
+	var got struct {
+		Schema string `json:"schema"`
+		Data   Card   `json:"data"`
+	}
+	test.Ok(t, json.Unmarshal(data, &got))
+	test.Equals(t, CardSchema, got.Schema)
+	test.Equals(t, statusSkipped, got.Data.Status)
+	test.Equals(t, "key", got.Data.Key)
+	test.Equals(t, "vendor", got.Data.Mounts[0].Path)
+
+	var buf bytes.Buffer
+	test.Ok(t, writeCardTo(&buf, data))
+
+	encoded := strings.TrimSuffix(strings.TrimPrefix(buf.String(), "\u001B]1338;"), "\u001B]0m\n")
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	test.Ok(t, err)
+	test.Equals(t, data, decoded)
+}
@@ -7,12 +7,23 @@ import (
 	"time"
 
 	"github.com/bmatcuk/doublestar/v4"
+	"github.com/meltwater/drone-cache/storage/backend"
 	"github.com/meltwater/drone-cache/storage/backend/alioss"
 	"github.com/meltwater/drone-cache/storage/backend/azure"
+	"github.com/meltwater/drone-cache/storage/backend/fault"
 	"github.com/meltwater/drone-cache/storage/backend/filesystem"
+	"github.com/meltwater/drone-cache/storage/backend/ftp"
 	"github.com/meltwater/drone-cache/storage/backend/gcs"
+	"github.com/meltwater/drone-cache/storage/backend/gha"
+	"github.com/meltwater/drone-cache/storage/backend/http"
+	"github.com/meltwater/drone-cache/storage/backend/mirror"
+	"github.com/meltwater/drone-cache/storage/backend/oci"
+	"github.com/meltwater/drone-cache/storage/backend/redis"
 	"github.com/meltwater/drone-cache/storage/backend/s3"
 	"github.com/meltwater/drone-cache/storage/backend/sftp"
+	"github.com/meltwater/drone-cache/storage/backend/shard"
+	"github.com/meltwater/drone-cache/storage/backend/tiered"
+	"github.com/meltwater/drone-cache/storage/common"
 )
 
 // Config plugin-specific parameters and secrets.
@@ -32,17 +43,85 @@ type Config struct {
 	SkipSymlinks            bool
 	Override                bool
 	CompressionLevel        int
+	CompressionThreads      int
+	ZstdWindowSize          int
 	StorageOperationTimeout time.Duration
 
+	// Outputs is the path of the file that restore writes its results to, and rebuild reads them back from.
+	Outputs string
+	// SkipRebuildOnHit skips rebuild if the outputs of the restore report an exact hit of the same key and mounts.
+	SkipRebuildOnHit bool
+	// RedactEnv are additional patterns of the names of environment variables to redact in debug output.
+	RedactEnv []string
+	// Preflight probes the storage with a canary object before rebuild, to fail early on misconfigured backends.
+	Preflight bool
+	// CardPath is the path that the summary card of the run is written to, for Drone to render it.
+	CardPath string
+
+	// Settings of the archive formats and backends registered with RegisterTyped.
+	ArchiveSettings map[string]string
+	BackendSettings map[string]string
+
 	Mount []string
 
 	// Backend
 	S3         s3.Config
 	FileSystem filesystem.Config
 	SFTP       sftp.Config
+	FTP        ftp.Config
 	Azure      azure.Config
 	GCS        gcs.Config
 	Alioss     alioss.Config
+	HTTP       http.Config
+	OCI        oci.Config
+	Redis      redis.Config
+	GHA        gha.Config
+	Tiered     tiered.Config
+	Mirror     mirror.Config
+	Shard      shard.Config
+	Fault      fault.Config
+
+	// Backends of the composite backends (e.g. mirror, shard).
+	Backends []backend.Spec
+
+	// Transport of the backends that speak HTTP.
+	Transport common.TransportConfig
+}
+
+// BackendConfig returns the configuration of the storage backends.
+func (c *Config) BackendConfig() backend.Config {
+	return backend.Config{
+		Debug:      c.Debug,
+		Azure:      c.Azure,
+		FileSystem: c.FileSystem,
+		GCS:        c.GCS,
+		S3:         c.S3,
+		SFTP:       c.SFTP,
+		FTP:        c.FTP,
+		Alioss:     c.Alioss,
+		HTTP:       c.HTTP,
+		OCI:        c.OCI,
+		Redis:      c.Redis,
+		GHA:        c.GHA,
+		Tiered:     c.Tiered,
+		Mirror:     c.Mirror,
+		Shard:      c.Shard,
+		Fault:      c.Fault,
+		Backends:   c.Backends,
+		Settings:   c.BackendSettings,
+		Transport:  c.Transport,
+	}
+}
+
+// Validate reports every problem of the configuration of the plugin, the backends validate their own.
+func (c *Config) Validate() error {
+	var v common.Validation
+
+	if c.SkipRebuildOnHit && c.Outputs == "" {
+		v.Addf("<skip_rebuild_on_hit> requires <outputs>, to read the result of restore from")
+	}
+
+	return v.Err()
 }
 
 // HandleMount runs prior to Rebuild and Restoring of caches to handle unique
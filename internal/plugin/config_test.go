@@ -1,11 +1,13 @@
 package plugin
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"reflect"
 	"testing"
 
+	"github.com/meltwater/drone-cache/storage/common"
 	"github.com/meltwater/drone-cache/test"
 )
 
@@ -71,3 +73,11 @@ func TestHandleMount(t *testing.T) {
 			"expected mount differs from handled mount result:\nexpected: %v\ngot:%v", tc.expectedMounts, c.Mount)
 	}
 }
+
+func TestValidate(t *testing.T) {
+	test.Ok(t, (&Config{}).Validate())
+	test.Ok(t, (&Config{SkipRebuildOnHit: true, Outputs: "cache.env"}).Validate())
+
+	err := (&Config{SkipRebuildOnHit: true}).Validate()
+	test.Assert(t, errors.Is(err, common.ErrInvalidConfig), "invalid configuration error is expected, got %v", err)
+}
@@ -0,0 +1,264 @@
+package plugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/meltwater/drone-cache/cache"
+)
+
+// Keys of the outputs in dotenv format.
+const (
+	outputStatus     = "CACHE_STATUS"
+	outputHit        = "CACHE_HIT"
+	outputKey        = "CACHE_KEY"
+	outputFallback   = "CACHE_FALLBACK"
+	outputMounts     = "CACHE_MOUNTS"
+	outputFailed     = "CACHE_FAILED"
+	outputSize       = "CACHE_SIZE"
+	outputSizes      = "CACHE_SIZES"
+	outputDigest     = "CACHE_DIGEST"
+	outputMatched    = "CACHE_MATCHED"
+	outputMatchedKey = "CACHE_MATCHED_KEY"
+)
+
+// Outputs are the results of a restore, written for the later steps of the pipeline.
+type Outputs struct {
+	// Status is one of cache.StatusExactHit, cache.StatusPartialHit or cache.StatusMiss.
+	Status string `json:"status"`
+	// Hit reports whether the status is cache.StatusExactHit.
+	Hit bool `json:"hit"`
+	// Key is the requested cache key, generated by the template or by the fallback generator.
+	Key string `json:"key"`
+	// Fallback reports whether the key is generated by the fallback generator.
+	Fallback bool `json:"fallback"`
+	// Mounts are the restored mounts.
+	Mounts []string `json:"mounts"`
+	// Failed are the mounts that could not be restored.
+	Failed []string `json:"failed"`
+	// Size is the total size of the restored archives in bytes.
+	Size int64 `json:"size"`
+	// Sizes are the sizes of the restored archives in bytes, by mount.
+	Sizes map[string]int64 `json:"sizes"`
+	// Digest is the digest of the manifest of the restored mounts and their archives.
+	Digest string `json:"digest"`
+	// Matched are the cache keys of the restored archives by mount, they differ from Key
+	// if the storage fell back to the archive of another key, e.g. of a restore key.
+	Matched map[string]string `json:"matched"`
+	// MatchedKey is the cache key that all the mounts are restored from, empty if they are restored from different keys.
+	MatchedKey string `json:"matched_key"`
+}
+
+// NewOutputs creates the outputs of a restore from its report.
+func NewOutputs(r *cache.Report) Outputs {
+	o := Outputs{
+		Status:   r.Status(),
+		Key:      r.Key,
+		Fallback: r.Fallback,
+		Mounts:   []string{},
+		Failed:   append([]string{}, r.Failed...),
+		Size:     r.Size(),
+		Sizes:    map[string]int64{},
+		Digest:   r.Digest(),
+		Matched:  map[string]string{},
+	}
+	o.Hit = o.Status == cache.StatusExactHit
+
+	for _, m := range r.Mounts {
+		o.Mounts = append(o.Mounts, m.Path)
+		o.Sizes[m.Path] = m.Size
+		o.Matched[m.Path] = m.Key
+	}
+
+	sort.Strings(o.Mounts)
+	sort.Strings(o.Failed)
+
+	for i, m := range o.Mounts {
+		if i > 0 && o.Matched[m] != o.MatchedKey {
+			o.MatchedKey = ""
+
+			break
+		}
+
+		o.MatchedKey = o.Matched[m]
+	}
+
+	return o
+}
+
+// WriteOutputs writes the outputs to the given path, as JSON if it has .json extension, otherwise in dotenv format.
+// The file is replaced atomically, so that it is never read half written.
+func WriteOutputs(path string, o Outputs) error {
+	var (
+		data []byte
+		err  error
+	)
+
+	if isJSON(path) {
+		data, err = json.MarshalIndent(o, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshal outputs, %w", err)
+		}
+	} else {
+		data = o.dotenv()
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return fmt.Errorf("create directory of outputs, %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil { // nolint: gosec, gomnd
+		return fmt.Errorf("write outputs, %w", err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("rename outputs, %w", err)
+	}
+
+	return nil
+}
+
+// ReadOutputs reads the outputs written by WriteOutputs from the given path.
+func ReadOutputs(path string) (Outputs, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Outputs{}, fmt.Errorf("read outputs, %w", err)
+	}
+
+	var o Outputs
+
+	if isJSON(path) {
+		if err := json.Unmarshal(data, &o); err != nil {
+			return Outputs{}, fmt.Errorf("unmarshal outputs, %w", err)
+		}
+
+		return o, nil
+	}
+
+	if err := o.parseDotenv(data); err != nil {
+		return Outputs{}, fmt.Errorf("parse outputs, %w", err)
+	}
+
+	return o, nil
+}
+
+// Helpers
+
+func isJSON(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".json")
+}
+
+func (o Outputs) dotenv() []byte {
+	sizes := make([]string, 0, len(o.Mounts))
+	matched := make([]string, 0, len(o.Mounts))
+
+	for _, m := range o.Mounts {
+		sizes = append(sizes, m+"="+strconv.FormatInt(o.Sizes[m], 10))
+		matched = append(matched, m+"="+o.Matched[m])
+	}
+
+	var b strings.Builder
+	for _, kv := range [][2]string{
+		{outputStatus, o.Status},
+		{outputHit, strconv.FormatBool(o.Hit)},
+		{outputKey, o.Key},
+		{outputFallback, strconv.FormatBool(o.Fallback)},
+		{outputMounts, strings.Join(o.Mounts, ",")},
+		{outputFailed, strings.Join(o.Failed, ",")},
+		{outputSize, strconv.FormatInt(o.Size, 10)},
+		{outputSizes, strings.Join(sizes, ",")},
+		{outputDigest, o.Digest},
+		{outputMatched, strings.Join(matched, ",")},
+		{outputMatchedKey, o.MatchedKey},
+	} {
+		fmt.Fprintf(&b, "%s=%s\n", kv[0], strconv.Quote(kv[1]))
+	}
+
+	return []byte(b.String())
+}
+
+func (o *Outputs) parseDotenv(data []byte) error { // nolint: cyclop
+	values := map[string]string{}
+
+	s := bufio.NewScanner(strings.NewReader(string(data)))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		k, v, ok := strings.Cut(line, "=")
+		if !ok {
+			return fmt.Errorf("line <%s> is not in <key>=<value> form", line)
+		}
+
+		if uv, err := strconv.Unquote(v); err == nil {
+			v = uv
+		}
+
+		values[k] = v
+	}
+
+	o.Status = values[outputStatus]
+	o.Hit = values[outputHit] == "true"
+	o.Key = values[outputKey]
+	o.Fallback = values[outputFallback] == "true"
+	o.Mounts = split(values[outputMounts])
+	o.Failed = split(values[outputFailed])
+	o.Digest = values[outputDigest]
+	o.MatchedKey = values[outputMatchedKey]
+	o.Sizes = map[string]int64{}
+	o.Matched = map[string]string{}
+
+	if v := values[outputSize]; v != "" {
+		size, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("size <%s>, %w", v, err)
+		}
+
+		o.Size = size
+	}
+
+	for _, pair := range split(values[outputSizes]) {
+		// NOTICE: Sizes are the last part, as mounts might have "=" in them.
+		i := strings.LastIndex(pair, "=")
+		if i < 0 {
+			return fmt.Errorf("size <%s> is not in <mount>=<size> form", pair)
+		}
+
+		m, v := pair[:i], pair[i+1:]
+
+		size, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("size of mount <%s>, %w", m, err)
+		}
+
+		o.Sizes[m] = size
+	}
+
+	// NOTICE: Matched keys are written in the order of the mounts, as both might have "=" in them.
+	for i, pair := range split(values[outputMatched]) {
+		if i >= len(o.Mounts) || !strings.HasPrefix(pair, o.Mounts[i]+"=") {
+			return fmt.Errorf("matched key <%s> is not in <mount>=<key> form", pair)
+		}
+
+		o.Matched[o.Mounts[i]] = strings.TrimPrefix(pair, o.Mounts[i]+"=")
+	}
+
+	return nil
+}
+
+func split(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+
+	return strings.Split(s, ",")
+}
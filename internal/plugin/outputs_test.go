@@ -0,0 +1,85 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/meltwater/drone-cache/cache"
+	"github.com/meltwater/drone-cache/test"
+)
+
+func TestOutputs(t *testing.T) {
+	t.Parallel()
+
+	want := Outputs{
+		Status:   cache.StatusPartialHit,
+		Key:      "key",
+		Fallback: true,
+		Mounts:   []string{"node_modules", "with=equals"},
+		Failed:   []string{"vendor"},
+		Size:     42,
+		Sizes:    map[string]int64{"node_modules": 40, "with=equals": 2},
+		Digest:   "sha256:abc",
+		Matched:  map[string]string{"node_modules": "key", "with=equals": "restore=key"},
+	}
+
+	for _, name := range []string{"cache.env", "cache.json"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "outputs", name)
+			test.Ok(t, WriteOutputs(path, want))
+
+			got, err := ReadOutputs(path)
+			test.Ok(t, err)
+			test.Equals(t, want, got)
+		})
+	}
+}
+
+func TestOutputsRestoreKeyFallback(t *testing.T) {
+	t.Parallel()
+
+	r := &cache.Report{
+		Key: "feature",
+		Mounts: []cache.MountReport{
+			{Path: "node_modules", Remote: "repo/feature/node_modules", Key: "main", Fallback: "repo/main/node_modules"},
+			{Path: "vendor", Remote: "repo/feature/vendor", Key: "main", Fallback: "repo/main/vendor"},
+		},
+	}
+
+	o := NewOutputs(r)
+	test.Equals(t, cache.StatusPartialHit, o.Status)
+	test.Equals(t, "feature", o.Key)
+	test.Equals(t, "main", o.MatchedKey)
+	test.Equals(t, map[string]string{"node_modules": "main", "vendor": "main"}, o.Matched)
+
+	r.Mounts[1].Key = "feature"
+	r.Mounts[1].Fallback = ""
+
+	o = NewOutputs(r)
+	test.Equals(t, "", o.MatchedKey)
+	test.Equals(t, map[string]string{"node_modules": "main", "vendor": "feature"}, o.Matched)
+
+	path := filepath.Join(t.TempDir(), "cache.env")
+	test.Ok(t, WriteOutputs(path, o))
+
+	got, err := ReadOutputs(path)
+	test.Ok(t, err)
+	test.Equals(t, o, got)
+}
+
+func TestOutputsDotenv(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "cache.env")
+	test.Ok(t, WriteOutputs(path, Outputs{Status: cache.StatusExactHit, Hit: true, Key: "key"}))
+
+	data, err := os.ReadFile(path)
+	test.Ok(t, err)
+	test.Assert(t, strings.Contains(string(data), "CACHE_STATUS=\"exact-hit\"\n"), "status is expected, got %s", data)
+	test.Assert(t, strings.Contains(string(data), "CACHE_HIT=\"true\"\n"), "hit is expected, got %s", data)
+}
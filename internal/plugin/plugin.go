@@ -7,12 +7,15 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/meltwater/drone-cache/archive"
 	"github.com/meltwater/drone-cache/cache"
+	"github.com/meltwater/drone-cache/internal"
 	"github.com/meltwater/drone-cache/internal/metadata"
 	"github.com/meltwater/drone-cache/key"
 	keygen "github.com/meltwater/drone-cache/key/generator"
@@ -20,14 +23,34 @@ import (
 	"github.com/meltwater/drone-cache/storage/backend"
 )
 
+// Operations of the plugin that errors are recognized for.
+const (
+	OpRebuild = "rebuild"
+	OpRestore = "restore"
+	OpFlush   = "flush"
+)
+
+var opDescriptions = map[string]string{
+	OpRebuild: "build cache",
+	OpRestore: "restore cache",
+	OpFlush:   "flush backend",
+}
+
 // Error recognized error from plugin.
-type Error string
+type Error struct {
+	// Op is the operation of the plugin that failed, e.g. OpRestore.
+	Op string
+	// Err is the underlying error.
+	Err error
+}
 
 // Error is a sentinel plugin error.
-func (e Error) Error() string { return string(e) }
+func (e Error) Error() string {
+	return fmt.Sprintf("[IMPORTANT] %s, %+v\n", opDescriptions[e.Op], e.Err)
+}
 
 // Unwrap unwraps underlying error.
-func (e Error) Unwrap() error { return e }
+func (e Error) Unwrap() error { return e.Err }
 
 // Plugin stores metadata about current plugin.
 type Plugin struct {
@@ -43,14 +66,14 @@ func New(logger log.Logger) *Plugin {
 }
 
 // Exec entry point of Plugin, where the magic happens.
-func (p *Plugin) Exec() error { // nolint: funlen,cyclop
+func (p *Plugin) Exec() (err error) { // nolint: funlen,cyclop
 	cfg := p.Config
 
 	// 1. Check parameters
 	if cfg.Debug {
 		level.Debug(p.logger).Log("msg", "DEBUG MODE enabled!")
 
-		for _, pair := range os.Environ() {
+		for _, pair := range internal.RedactEnv(os.Environ(), cfg.RedactEnv...) {
 			level.Debug(p.logger).Log("var", pair)
 		}
 
@@ -64,6 +87,10 @@ func (p *Plugin) Exec() error { // nolint: funlen,cyclop
 		return errors.New("rebuild and restore are mutually exclusive, please set only one of them")
 	}
 
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("validate configuration, %w", err)
+	}
+
 	var localRoot string
 	if p.Config.LocalRoot != "" {
 		localRoot = filepath.Clean(p.Config.LocalRoot)
@@ -76,13 +103,13 @@ func (p *Plugin) Exec() error { // nolint: funlen,cyclop
 		localRoot = workspace
 	}
 
-	var options []cache.Option
-	if p.Config.RemoteRoot != "" {
-		options = append(options, cache.WithNamespace(p.Config.RemoteRoot))
-	} else {
-		options = append(options, cache.WithNamespace(p.Metadata.Repo.Name))
+	namespace := p.Config.RemoteRoot
+	if namespace == "" {
+		namespace = p.Metadata.Repo.Name
 	}
 
+	options := []cache.Option{cache.WithNamespace(namespace)}
+
 	var generator key.Generator
 	if cfg.CacheKeyTemplate != "" {
 		generator = keygen.NewMetadata(p.logger, cfg.CacheKeyTemplate, p.Metadata, time.Now)
@@ -96,34 +123,59 @@ func (p *Plugin) Exec() error { // nolint: funlen,cyclop
 		options = append(options, cache.WithFallbackGenerator(keygen.NewStatic(p.Metadata.Commit.Branch)))
 	}
 
-	options = append(options, cache.WithOverride(p.Config.Override))
+	report := &cache.Report{}
+	options = append(options, cache.WithOverride(p.Config.Override), cache.WithReport(report))
 
 	// 2. Initialize storage backend.
-	b, err := backend.FromConfig(p.logger, cfg.Backend, backend.Config{
-		Debug:      cfg.Debug,
-		Azure:      cfg.Azure,
-		FileSystem: cfg.FileSystem,
-		GCS:        cfg.GCS,
-		S3:         cfg.S3,
-		SFTP:       cfg.SFTP,
-		Alioss:     cfg.Alioss,
-	})
+	b, err := backend.FromConfig(p.logger, cfg.Backend, cfg.BackendConfig())
 	if err != nil {
 		return fmt.Errorf("initialize backend <%s>, %w", cfg.Backend, err)
 	}
 
-	// 3. Initialize cache.
+	// Some backends (e.g. tiered with async upload) keep uploading in the background,
+	// they are flushed and closed on every return, also when the operation failed.
+	defer func() {
+		cErr := backend.Close(b)
+		if cErr == nil {
+			return
+		}
+
+		level.Debug(p.logger).Log("err", fmt.Sprintf("%+v\n", cErr))
+
+		if err == nil {
+			err = Error{Op: OpFlush, Err: cErr}
+
+			return
+		}
+
+		mErr := internal.MultiError{}
+		mErr.Add(err)
+		mErr.Add(Error{Op: OpFlush, Err: cErr})
+		err = mErr.Err()
+	}()
+
+	// 3. Initialize archive.
+	a, err := archive.FromFormat(p.logger, localRoot, cfg.ArchiveFormat,
+		archive.WithSkipSymlinks(cfg.SkipSymlinks),
+		archive.WithCompressionLevel(cfg.CompressionLevel),
+		archive.WithCompressionThreads(cfg.CompressionThreads),
+		archive.WithZstdWindowSize(cfg.ZstdWindowSize),
+		archive.WithSettings(cfg.ArchiveSettings),
+	)
+	if err != nil {
+		return fmt.Errorf("initialize archive <%s>, %w", cfg.ArchiveFormat, err)
+	}
+
+	// 4. Initialize cache.
+	s := storage.New(p.logger, b, cfg.StorageOperationTimeout)
 	c := cache.New(p.logger,
-		storage.New(p.logger, b, cfg.StorageOperationTimeout),
-		archive.FromFormat(p.logger, localRoot, cfg.ArchiveFormat,
-			archive.WithSkipSymlinks(cfg.SkipSymlinks),
-			archive.WithCompressionLevel(cfg.CompressionLevel),
-		),
+		s,
+		a,
 		generator,
 		options...,
 	)
 
-	// 4. Glob match mounts if doublestar paths exist
+	// 5. Glob match mounts if doublestar paths exist
 	cwd, err := os.Getwd()
 	if err != nil {
 		return fmt.Errorf("get working directory, %w", err)
@@ -135,24 +187,95 @@ func (p *Plugin) Exec() error { // nolint: funlen,cyclop
 		return fmt.Errorf("exec handle mount call, %w", err)
 	}
 
-	// 5. Select mode
-	if cfg.Rebuild {
-		if err := c.Rebuild(p.Config.Mount); err != nil {
+	// 6. Select mode
+	rebuild := cfg.Rebuild
+	if rebuild && cfg.SkipRebuildOnHit {
+		if o, hit := p.exactHit(generator); hit {
+			level.Info(p.logger).Log("msg", "skipping rebuild, restore was an exact hit", "outputs", cfg.Outputs)
+			p.writeCard(SkippedCard(cfg.Backend, o))
+
+			rebuild = false
+		}
+	}
+
+	if rebuild && cfg.Preflight {
+		if err := storage.Probe(s, namespace); err != nil {
 			level.Debug(p.logger).Log("err", fmt.Sprintf("%+v\n", err))
 
-			return Error(fmt.Sprintf("[IMPORTANT] build cache, %+v\n", err))
+			return Error{Op: OpRebuild, Err: fmt.Errorf("preflight probe of storage, %w", err)}
 		}
+
+		level.Info(p.logger).Log("msg", "preflight probe of storage succeeded", "backend", cfg.Backend)
 	}
 
-	if cfg.Restore {
-		if err := c.Restore(p.Config.Mount); err != nil {
+	if rebuild {
+		start := time.Now()
+		err := c.Rebuild(p.Config.Mount)
+		p.writeCard(NewCard(OpRebuild, cfg.Backend, report, time.Since(start), err))
+
+		if err != nil {
 			level.Debug(p.logger).Log("err", fmt.Sprintf("%+v\n", err))
 
-			return Error(fmt.Sprintf("[IMPORTANT] restore cache, %+v\n", err))
+			return Error{Op: OpRebuild, Err: err}
 		}
 	}
 
-	// FLUSH
+	if cfg.Restore {
+		start := time.Now()
+		err := c.Restore(p.Config.Mount)
+		p.writeCard(NewCard(OpRestore, cfg.Backend, report, time.Since(start), err))
+
+		// Outputs are written for misses too, so that later steps can tell them apart.
+		if cfg.Outputs != "" {
+			if err := WriteOutputs(cfg.Outputs, NewOutputs(report)); err != nil {
+				return fmt.Errorf("write outputs of restore, %w", err)
+			}
+		}
+
+		if err != nil {
+			level.Debug(p.logger).Log("err", fmt.Sprintf("%+v\n", err))
+
+			return Error{Op: OpRestore, Err: err}
+		}
+	}
 
 	return nil
 }
+
+// Helpers
+
+// writeCard writes the card of the run, if Drone asks for one.
+func (p *Plugin) writeCard(c Card) {
+	if p.Config.CardPath == "" {
+		return
+	}
+
+	if err := WriteCard(p.Config.CardPath, c); err != nil {
+		level.Warn(p.logger).Log("msg", "write card", "err", err)
+	}
+}
+
+// exactHit returns the outputs of the restore, and reports whether they report an exact hit
+// of the same key and mounts as the rebuild.
+func (p *Plugin) exactHit(g key.Generator) (Outputs, bool) {
+	o, err := ReadOutputs(p.Config.Outputs)
+	if err != nil {
+		level.Warn(p.logger).Log("msg", "read outputs of restore, rebuilding", "err", err)
+
+		return Outputs{}, false
+	}
+
+	if !o.Hit {
+		return o, false
+	}
+
+	k, err := g.Generate()
+	if err != nil {
+		return o, false
+	}
+
+	mounts := append([]string{}, p.Config.Mount...)
+	sort.Strings(mounts)
+
+	return o, o.Key == k && strings.Join(o.Mounts, "\n") == strings.Join(mounts, "\n")
+}
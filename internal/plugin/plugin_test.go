@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"testing"
@@ -26,13 +27,16 @@ import (
 	"google.golang.org/api/option"
 
 	"github.com/meltwater/drone-cache/archive"
+	"github.com/meltwater/drone-cache/cache"
 	"github.com/meltwater/drone-cache/internal/metadata"
 	"github.com/meltwater/drone-cache/storage/backend"
 	"github.com/meltwater/drone-cache/storage/backend/azure"
+	"github.com/meltwater/drone-cache/storage/backend/fault"
 	"github.com/meltwater/drone-cache/storage/backend/filesystem"
 	"github.com/meltwater/drone-cache/storage/backend/gcs"
 	"github.com/meltwater/drone-cache/storage/backend/s3"
 	"github.com/meltwater/drone-cache/storage/backend/sftp"
+	"github.com/meltwater/drone-cache/storage/common"
 	"github.com/meltwater/drone-cache/test"
 )
 
@@ -60,7 +64,10 @@ var (
 
 	formats = []string{
 		archive.Gzip,
+		archive.Lz4,
 		archive.Tar,
+		archive.Xz,
+		archive.Zip,
 		archive.Zstd,
 	}
 )
@@ -201,6 +208,162 @@ func TestPlugin(t *testing.T) {
 	}
 }
 
+func TestPluginFaults(t *testing.T) {
+	test.Ok(t, os.MkdirAll(testRootMounted, 0755))
+	test.Ok(t, os.MkdirAll(testRootMoved, 0755))
+	t.Cleanup(func() { os.RemoveAll(testRoot) })
+
+	cases := []struct {
+		name           string
+		rebuildFaults  fault.Config
+		restoreFaults  fault.Config
+		rebuildSuccess bool
+		restoreSuccess bool
+	}{
+		{
+			name:           "slow-storage",
+			rebuildFaults:  fault.Config{Latency: 10 * time.Millisecond, DripSize: 512, DripInterval: time.Millisecond},
+			restoreFaults:  fault.Config{Jitter: 10 * time.Millisecond, DripSize: 512, DripInterval: time.Millisecond},
+			rebuildSuccess: true,
+			restoreSuccess: true,
+		},
+		{
+			name:           "interrupted-upload",
+			rebuildFaults:  fault.Config{FailAfter: 1024},
+			rebuildSuccess: false,
+		},
+		{
+			name:           "interrupted-download",
+			restoreFaults:  fault.Config{FailAfter: 1024},
+			rebuildSuccess: true,
+			restoreSuccess: false,
+		},
+		{
+			// NOTICE: Truncated within file contents, tar archives truncated within headers look complete without checksums.
+			name:           "truncated-download",
+			restoreFaults:  fault.Config{TruncateAfter: 3000},
+			rebuildSuccess: true,
+			restoreSuccess: false,
+		},
+		{
+			name:           "failing-storage",
+			rebuildFaults:  fault.Config{ErrorRate: 1, Operations: []string{fault.OpPut}},
+			rebuildSuccess: false,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			c := defaultConfig()
+			setupFileSystem(t, c, tc.name)
+			c = mount(c, exampleFileTree(t, tc.name, make([]byte, 4*1024))...)
+			c = format(c, archive.Tar) // NOTICE: Uncompressed, so that faults after a number of bytes are reached.
+
+			c.Backend = backend.Fault
+			c.Fault = tc.rebuildFaults
+			c.Fault.Backend = backend.FileSystem
+
+			// Rebuild run
+			{
+				plugin := newPlugin(rebuild(c))
+				if !tc.rebuildSuccess {
+					test.NotOk(t, plugin.Exec())
+					return
+				}
+
+				test.Ok(t, plugin.Exec())
+			}
+
+			restoreRoot, cleanup := test.CreateTempDir(t, sanitize(tc.name), testRootMoved)
+			t.Cleanup(cleanup)
+
+			for _, p := range c.Mount {
+				rel, err := filepath.Rel(testRootMounted, p)
+				test.Ok(t, err)
+				test.Ok(t, os.Rename(p, filepath.Join(restoreRoot, rel)))
+			}
+
+			c.Fault = tc.restoreFaults
+			c.Fault.Backend = backend.FileSystem
+
+			// Restore run
+			{
+				plugin := newPlugin(restore(c))
+				if !tc.restoreSuccess {
+					test.NotOk(t, plugin.Exec())
+					return
+				}
+
+				test.Ok(t, plugin.Exec())
+			}
+
+			test.EqualDirs(t, restoreRoot, testRootMounted, c.Mount)
+		})
+	}
+}
+
+func TestPluginOutputs(t *testing.T) {
+	test.Ok(t, os.MkdirAll(testRootMounted, 0755))
+	test.Ok(t, os.MkdirAll(testRootMoved, 0755))
+	t.Cleanup(func() { os.RemoveAll(testRoot) })
+
+	for _, name := range []string{"outputs.env", "outputs.json"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			c := defaultConfig()
+			setupFileSystem(t, c, name)
+			c = mount(c, exampleFileTree(t, name, make([]byte, 1024))...)
+			c = format(c, archive.Tar)
+			c.Outputs = filepath.Join(testRoot, "outputs", name)
+
+			// Rebuild run
+			{
+				plugin := newPlugin(rebuild(c))
+				test.Ok(t, plugin.Exec())
+			}
+
+			// Restore run
+			{
+				plugin := newPlugin(restore(c))
+				test.Ok(t, plugin.Exec())
+			}
+
+			outputs, err := ReadOutputs(c.Outputs)
+			test.Ok(t, err)
+			test.Equals(t, cache.StatusExactHit, outputs.Status)
+			test.Assert(t, outputs.Hit, "exact hit is expected")
+			mounts := append([]string{}, c.Mount...)
+			sort.Strings(mounts)
+			test.Equals(t, mounts, outputs.Mounts)
+			test.Assert(t, outputs.Size > 0, "size of restored archives is expected")
+			test.Assert(t, strings.HasPrefix(outputs.Digest, "sha256:"), "digest of manifest is expected, got %s", outputs.Digest)
+
+			// NOTICE: Stored caches are removed, to see whether rebuild uploads them again.
+			test.Ok(t, os.RemoveAll(c.FileSystem.CacheRoot))
+			test.Ok(t, os.MkdirAll(c.FileSystem.CacheRoot, 0755))
+
+			// Rebuild run, skipped on exact hit
+			{
+				c.SkipRebuildOnHit = true
+				plugin := newPlugin(rebuild(c))
+				test.Ok(t, plugin.Exec())
+			}
+
+			// Restore run, missed
+			{
+				plugin := newPlugin(restore(c))
+				test.NotOk(t, plugin.Exec())
+			}
+
+			outputs, err = ReadOutputs(c.Outputs)
+			test.Ok(t, err)
+			test.Equals(t, cache.StatusMiss, outputs.Status)
+			test.Assert(t, !outputs.Hit, "miss is expected")
+		})
+	}
+}
+
 // Plugin configuration
 
 func defaultConfig() *Config {
@@ -370,7 +533,7 @@ func setupAzure(t *testing.T, c *Config, name string) {
 	c.Backend = backend.Azure
 	c.Azure = azure.Config{
 		AccountName:    accountName,
-		AccountKey:     accountKey,
+		AccountKey:     common.Secret(accountKey),
 		ContainerName:  name,
 		BlobStorageURL: blobURL,
 		Azurite:        true,
@@ -420,7 +583,7 @@ func setupGCS(t *testing.T, c *Config, name string) {
 	c.GCS = gcs.Config{
 		Bucket:   bucketName,
 		Endpoint: endpoint,
-		APIKey:   apiKey,
+		APIKey:   common.Secret(apiKey),
 		Timeout:  defaultStorageOperationTimeout,
 	}
 }
@@ -461,7 +624,7 @@ func setupS3(t *testing.T, c *Config, name string) {
 		PathStyle:  true, // Should be true for minio and false for AWS.
 		DisableSSL: true,
 		Region:     defaultRegion,
-		Secret:     secretAccessKey,
+		Secret:     common.Secret(secretAccessKey),
 	}
 }
 
@@ -500,7 +663,7 @@ func setupSFTP(t *testing.T, c *Config, name string) {
 		CacheRoot: cacheRoot,
 		Username:  username,
 		Auth: sftp.SSHAuth{
-			Password: password,
+			Password: common.Secret(password),
 			Method:   sftp.SSHAuthMethodPassword,
 		},
 		Host: host,
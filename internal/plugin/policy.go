@@ -0,0 +1,225 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/meltwater/drone-cache/storage"
+)
+
+// Actions to take on recognized errors of the plugin.
+const (
+	// ActionIgnore succeeds, the error is only logged as information.
+	ActionIgnore = "ignore"
+	// ActionWarn succeeds, the error is logged as an error.
+	ActionWarn = "warn"
+	// ActionFail exits with the exit code of the class of the error.
+	ActionFail = "fail"
+)
+
+// Classes of errors that failure policies match, they correspond to the classes of errors of the storage.
+const (
+	ClassNotFound      = "not-found"
+	ClassUnauthorized  = "unauthorized"
+	ClassTimeout       = "timeout"
+	ClassIntegrity     = "integrity"
+	ClassQuotaExceeded = "quota-exceeded"
+	// ClassOther matches errors that are not of any other class.
+	ClassOther = "other"
+
+	// classAny matches errors of all classes.
+	classAny = "*"
+)
+
+// ExitCodes are the exit codes of the classes of errors, used when the plugin fails without an explicit exit code.
+// nolint: gochecknoglobals, gomnd
+var ExitCodes = map[string]int{
+	ClassOther:         1,
+	ClassNotFound:      3,
+	ClassUnauthorized:  4,
+	ClassTimeout:       5,
+	ClassIntegrity:     6,
+	ClassQuotaExceeded: 7,
+}
+
+// classErrors are the classes of the errors of the storage, from the most to the least severe.
+// NOTICE: Not found is the least severe, so that a miss never hides another failure of the same operation.
+// nolint: gochecknoglobals
+var classErrors = []struct {
+	class string
+	err   error
+}{
+	{ClassIntegrity, storage.ErrIntegrity},
+	{ClassUnauthorized, storage.ErrUnauthorized},
+	{ClassQuotaExceeded, storage.ErrQuotaExceeded},
+	{ClassTimeout, storage.ErrTimeout},
+	{ClassNotFound, storage.ErrNotFound},
+}
+
+// Rule decides the action for the errors of an operation and a class.
+type Rule struct {
+	// Op is the operation that the rule applies to, all of them if empty.
+	Op string
+	// Class is the class of errors that the rule applies to, all of them if "*".
+	Class string
+	// Action is one of ActionIgnore, ActionWarn or ActionFail.
+	Action string
+	// ExitCode overrides the exit code of the class for ActionFail, if not zero.
+	ExitCode int
+}
+
+// Policy decides how recognized errors of the plugin are handled.
+// The most specific matching rule wins, the last one of equally specific rules.
+type Policy struct {
+	// Default is the action for errors that no rule matches.
+	Default string
+	Rules   []Rule
+}
+
+// ParsePolicy parses rules given as [<operation>:]<class>=<action>[:<exit code>],
+// e.g. "restore:not-found=ignore", "unauthorized=fail" or "rebuild:timeout=warn".
+func ParsePolicy(defaultAction string, specs []string) (Policy, error) {
+	p := Policy{Default: defaultAction}
+
+	for _, spec := range specs {
+		r, err := parseRule(spec)
+		if err != nil {
+			return Policy{}, fmt.Errorf("rule <%s>, %w", spec, err)
+		}
+
+		p.Rules = append(p.Rules, r)
+	}
+
+	return p, nil
+}
+
+// Decide returns the action and the exit code for the given error.
+func (p Policy) Decide(err error) (string, int) {
+	var op string
+
+	var e Error
+	if errors.As(err, &e) {
+		op = e.Op
+	}
+
+	class := Class(err)
+	action, code, score := p.Default, 0, -1
+
+	for _, r := range p.Rules {
+		if (r.Op != "" && r.Op != op) || (r.Class != classAny && r.Class != class) {
+			continue
+		}
+
+		s := 0
+		if r.Op != "" {
+			s += 2
+		}
+
+		if r.Class != classAny {
+			s++
+		}
+
+		if s >= score {
+			action, code, score = r.Action, r.ExitCode, s
+		}
+	}
+
+	if code == 0 {
+		code = ExitCodes[class]
+	}
+
+	return action, code
+}
+
+// Class returns the class of the given error, ClassOther if it is not of any of the classes of the storage.
+// Errors that consist of multiple errors, e.g. of multiple mounts, are of the most severe class of them,
+// and of ClassOther if any of them is not of any of the classes of the storage.
+func Class(err error) string {
+	found := map[string]bool{}
+	collectClasses(err, found)
+
+	if found[ClassOther] {
+		return ClassOther
+	}
+
+	for _, c := range classErrors {
+		if found[c.class] {
+			return c.class
+		}
+	}
+
+	return ClassOther
+}
+
+// Helpers
+
+// multiError is implemented by errors that consist of multiple errors, e.g. internal.MultiError.
+type multiError interface {
+	Errors() []error
+}
+
+func collectClasses(err error, found map[string]bool) {
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if m, ok := e.(multiError); ok { // nolint: errorlint
+			for _, inner := range m.Errors() {
+				collectClasses(inner, found)
+			}
+
+			return
+		}
+	}
+
+	for _, c := range classErrors {
+		if errors.Is(err, c.err) {
+			found[c.class] = true
+
+			return
+		}
+	}
+
+	found[ClassOther] = true
+}
+
+func parseRule(spec string) (Rule, error) {
+	match, action, ok := strings.Cut(strings.TrimSpace(spec), "=")
+	if !ok {
+		return Rule{}, errors.New("not in [<operation>:]<class>=<action>[:<exit code>] form")
+	}
+
+	r := Rule{Class: match}
+	if op, class, ok := strings.Cut(match, ":"); ok {
+		r.Op, r.Class = op, class
+	}
+
+	if _, ok := opDescriptions[r.Op]; r.Op != "" && !ok {
+		return Rule{}, fmt.Errorf("unknown operation <%s>, expected one of <%s>, <%s>, <%s>", r.Op, OpRebuild, OpRestore, OpFlush)
+	}
+
+	if _, ok := ExitCodes[r.Class]; r.Class != classAny && !ok {
+		return Rule{}, fmt.Errorf("unknown class <%s>", r.Class)
+	}
+
+	r.Action = action
+	if a, code, ok := strings.Cut(action, ":"); ok {
+		c, err := strconv.Atoi(code)
+		if err != nil || c < 1 || c > 255 {
+			return Rule{}, fmt.Errorf("exit code <%s> is not between 1 and 255", code)
+		}
+
+		r.Action, r.ExitCode = a, c
+	}
+
+	switch r.Action {
+	case ActionIgnore, ActionWarn, ActionFail:
+	default:
+		return Rule{}, fmt.Errorf("unknown action <%s>, expected one of <%s>, <%s>, <%s>", r.Action, ActionIgnore, ActionWarn, ActionFail)
+	}
+
+	if r.ExitCode != 0 && r.Action != ActionFail {
+		return Rule{}, fmt.Errorf("exit code can only be given for <%s>", ActionFail)
+	}
+
+	return r, nil
+}
@@ -0,0 +1,123 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/meltwater/drone-cache/internal"
+	"github.com/meltwater/drone-cache/storage"
+	"github.com/meltwater/drone-cache/test"
+)
+
+func TestPolicyDecide(t *testing.T) {
+	t.Parallel()
+
+	policy, err := ParsePolicy(ActionWarn, []string{
+		"restore:not-found=ignore",
+		"unauthorized=fail",
+		"timeout=fail:42",
+		"rebuild:timeout=warn",
+	})
+	test.Ok(t, err)
+
+	cases := []struct {
+		name   string
+		err    error
+		action string
+		code   int
+	}{
+		{
+			name:   "miss-on-restore",
+			err:    Error{Op: OpRestore, Err: fmt.Errorf("restore failed, %w", storage.ErrNotFound)},
+			action: ActionIgnore,
+			code:   ExitCodes[ClassNotFound],
+		},
+		{
+			name:   "miss-on-rebuild",
+			err:    Error{Op: OpRebuild, Err: storage.ErrNotFound},
+			action: ActionWarn,
+			code:   ExitCodes[ClassNotFound],
+		},
+		{
+			name:   "unauthorized",
+			err:    Error{Op: OpRebuild, Err: storage.ErrUnauthorized},
+			action: ActionFail,
+			code:   ExitCodes[ClassUnauthorized],
+		},
+		{
+			name:   "timeout-on-restore",
+			err:    Error{Op: OpRestore, Err: storage.ErrTimeout},
+			action: ActionFail,
+			code:   42,
+		},
+		{
+			name:   "timeout-on-rebuild",
+			err:    Error{Op: OpRebuild, Err: storage.ErrTimeout},
+			action: ActionWarn,
+			code:   ExitCodes[ClassTimeout],
+		},
+		{
+			name:   "miss-and-unauthorized-on-restore",
+			err:    Error{Op: OpRestore, Err: fmt.Errorf("restore failed, %w", multi(storage.ErrNotFound, storage.ErrUnauthorized))},
+			action: ActionFail,
+			code:   ExitCodes[ClassUnauthorized],
+		},
+		{
+			name:   "miss-and-unclassified-on-restore",
+			err:    Error{Op: OpRestore, Err: multi(storage.ErrNotFound, errors.New("extract archive, unexpected EOF"))},
+			action: ActionWarn,
+			code:   ExitCodes[ClassOther],
+		},
+		{
+			name:   "misses-on-restore",
+			err:    Error{Op: OpRestore, Err: multi(storage.ErrNotFound, fmt.Errorf("mount, %w", storage.ErrNotFound))},
+			action: ActionIgnore,
+			code:   ExitCodes[ClassNotFound],
+		},
+		{
+			name:   "other",
+			err:    Error{Op: OpFlush, Err: errors.New("unexpected")},
+			action: ActionWarn,
+			code:   ExitCodes[ClassOther],
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			action, code := policy.Decide(tc.err)
+			test.Equals(t, tc.action, action)
+			test.Equals(t, tc.code, code)
+		})
+	}
+}
+
+func TestParsePolicyInvalid(t *testing.T) {
+	t.Parallel()
+
+	for _, spec := range []string{
+		"not-found",
+		"upload:not-found=ignore",
+		"missing=ignore",
+		"not-found=retry",
+		"not-found=fail:0",
+		"not-found=warn:3",
+	} {
+		_, err := ParsePolicy(ActionWarn, []string{spec})
+		test.NotOk(t, err)
+	}
+}
+
+// Helpers
+
+func multi(errs ...error) error {
+	me := &internal.MultiError{}
+	for _, err := range errs {
+		me.Add(err)
+	}
+
+	return me.Err()
+}
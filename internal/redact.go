@@ -0,0 +1,197 @@
+package internal
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/meltwater/drone-cache/storage/common"
+)
+
+// DefaultSecretPatterns are the patterns of the names of environment variables and log keys that hold secrets.
+// They are matched case-insensitively with path.Match.
+// nolint: gochecknoglobals
+var DefaultSecretPatterns = []string{
+	"*SECRET*",
+	"*PASSWORD*",
+	"*PASSWD*",
+	"*TOKEN*",
+	"*CREDENTIAL*",
+	"*API_KEY*",
+	"*ACCESS_KEY*",
+	"*ACCOUNT_KEY*",
+	"*JSON_KEY*",
+	"*PRIVATE_KEY*",
+	"*ENCRYPTION_KEY*",
+}
+
+// IsSecretName reports whether the given name matches any of the default or given patterns.
+func IsSecretName(name string, patterns ...string) bool {
+	name = strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+
+	for _, ps := range [][]string{DefaultSecretPatterns, patterns} {
+		for _, p := range ps {
+			if ok, _ := path.Match(strings.ToUpper(p), name); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// DefaultSecretHeaders are the HTTP headers that hold credentials, or keys of server side encryption.
+// nolint: gochecknoglobals
+var DefaultSecretHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"X-Amz-Security-Token",
+	"X-Amz-Server-Side-Encryption-Customer-Key",
+	"X-Amz-Copy-Source-Server-Side-Encryption-Customer-Key",
+	"X-Goog-Encryption-Key",
+	"X-Oss-Security-Token",
+}
+
+// RedactHTTPDump redacts the values of the headers that hold secrets from a dump of an HTTP request or response,
+// e.g. as logged by the clients of the backends in debug mode.
+func RedactHTTPDump(dump string) string {
+	lines := strings.Split(dump, "\n")
+
+	for i, line := range lines {
+		name, _, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+
+		for _, h := range DefaultSecretHeaders {
+			if strings.EqualFold(strings.TrimSpace(name), h) {
+				// NOTICE: Dumps end their lines with CRLF, the CR is kept.
+				lines[i] = name + ": " + common.Redacted + line[len(strings.TrimRight(line, "\r")):]
+
+				break
+			}
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// RedactEnv redacts the values of the environment variables, given as <name>=<value> pairs, that hold secrets.
+func RedactEnv(environ []string, patterns ...string) []string {
+	redacted := make([]string, 0, len(environ))
+
+	for _, pair := range environ {
+		name, value, ok := strings.Cut(pair, "=")
+		if ok && value != "" && IsSecretName(name, patterns...) {
+			pair = name + "=" + common.Redacted
+		}
+
+		redacted = append(redacted, pair)
+	}
+
+	return redacted
+}
+
+// Secrets returns the values of the common.Secret fields of the given struct and the structs in it,
+// and the values of string maps in it whose keys look like secrets, e.g. of the archive and backend settings.
+func Secrets(v interface{}) []string {
+	var secrets []string
+
+	collectSecrets(reflect.ValueOf(v), &secrets)
+
+	return secrets
+}
+
+type redactingLogger struct {
+	next     log.Logger
+	replacer *strings.Replacer
+}
+
+// NewRedactingLogger returns a logger that redacts the values of keys that look like secrets,
+// and the given secrets wherever they appear in the values.
+func NewRedactingLogger(l log.Logger, secrets ...string) log.Logger {
+	var pairs []string
+
+	for _, s := range secrets {
+		if s != "" {
+			pairs = append(pairs, s, common.Redacted)
+		}
+	}
+
+	r := &redactingLogger{next: l}
+	if len(pairs) > 0 {
+		r.replacer = strings.NewReplacer(pairs...)
+	}
+
+	return r
+}
+
+// Log redacts the key value pairs and passes them to the wrapped logger.
+func (l *redactingLogger) Log(keyvals ...interface{}) error {
+	redacted := make([]interface{}, len(keyvals))
+	copy(redacted, keyvals)
+
+	for i := 1; i < len(redacted); i += 2 {
+		if key, ok := redacted[i-1].(string); ok && IsSecretName(key) {
+			redacted[i] = common.Redacted
+
+			continue
+		}
+
+		if l.replacer == nil {
+			continue
+		}
+
+		// NOTICE: Values are only replaced if they contain secrets, so that others keep their types for the next loggers.
+		switch v := redacted[i].(type) {
+		case string, error, fmt.Stringer:
+			if s := fmt.Sprint(v); l.replacer.Replace(s) != s {
+				redacted[i] = l.replacer.Replace(s)
+			}
+		}
+	}
+
+	return l.next.Log(redacted...) // nolint: wrapcheck
+}
+
+// Helpers
+
+func collectSecrets(v reflect.Value, secrets *[]string) {
+	switch v.Kind() { // nolint: exhaustive
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			collectSecrets(v.Elem(), secrets)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				collectSecrets(v.Field(i), secrets)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			collectSecrets(v.Index(i), secrets)
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String || v.Type().Elem().Kind() != reflect.String {
+			return
+		}
+
+		// NOTICE: Sorted, so that the secrets are in the same order on every call.
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+
+		for _, k := range keys {
+			if s := v.MapIndex(k).String(); s != "" && IsSecretName(k.String()) {
+				*secrets = append(*secrets, s)
+			}
+		}
+	case reflect.String:
+		if s, ok := v.Interface().(common.Secret); ok && s != "" {
+			*secrets = append(*secrets, string(s))
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/meltwater/drone-cache/storage/common"
+	"github.com/meltwater/drone-cache/test"
+)
+
+func TestRedactEnv(t *testing.T) {
+	t.Parallel()
+
+	got := RedactEnv([]string{
+		"PLUGIN_SECRET_KEY=hunter2",
+		"AWS_SECRET_ACCESS_KEY=hunter2",
+		"PLUGIN_GCS_JSON_KEY={}",
+		"DRONE_NETRC_PASSWORD=hunter2",
+		"PLUGIN_DSN=postgres://user:hunter2@db",
+		"PLUGIN_PASSWORD=",
+		"PLUGIN_CACHE_KEY={{ .Commit.Branch }}",
+		"PLUGIN_BUCKET=bucket",
+	}, "*_DSN")
+
+	test.Equals(t, []string{
+		"PLUGIN_SECRET_KEY=" + common.Redacted,
+		"AWS_SECRET_ACCESS_KEY=" + common.Redacted,
+		"PLUGIN_GCS_JSON_KEY=" + common.Redacted,
+		"DRONE_NETRC_PASSWORD=" + common.Redacted,
+		"PLUGIN_DSN=" + common.Redacted,
+		"PLUGIN_PASSWORD=",
+		"PLUGIN_CACHE_KEY={{ .Commit.Branch }}",
+		"PLUGIN_BUCKET=bucket",
+	}, got)
+}
+
+func TestRedactHTTPDump(t *testing.T) {
+	t.Parallel()
+
+	got := RedactHTTPDump("GET /bucket/key HTTP/1.1\r\n" +
+		"Host: s3.local\r\n" +
+		"Authorization: AWS4-HMAC-SHA256 Credential=AKID/20260101/eu-west-1/s3/aws4_request, Signature=abcdef\r\n" +
+		"x-amz-security-token: token\r\n\r\n")
+
+	test.Equals(t, "GET /bucket/key HTTP/1.1\r\n"+
+		"Host: s3.local\r\n"+
+		"Authorization: "+common.Redacted+"\r\n"+
+		"x-amz-security-token: "+common.Redacted+"\r\n\r\n", got)
+}
+
+func TestSecrets(t *testing.T) {
+	t.Parallel()
+
+	type auth struct{ Token common.Secret }
+
+	got := Secrets(struct {
+		Name     string
+		Password common.Secret
+		Empty    common.Secret
+		Auth     auth
+		Backends []struct{ Auth *auth }
+		Settings map[string]string
+	}{
+		Name:     "name",
+		Password: "hunter2",
+		Auth:     auth{Token: "token"},
+		Backends: []struct{ Auth *auth }{{Auth: &auth{Token: "nested"}}, {}},
+		Settings: map[string]string{"region": "eu", "sse-encryption-key": "key", "secret.token": "setting"},
+	})
+
+	test.Equals(t, []string{"hunter2", "token", "nested", "setting", "key"}, got)
+}
+
+func TestRedactingLogger(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	logger := NewRedactingLogger(log.NewLogfmtLogger(&buf), "hunter2")
+	test.Ok(t, logger.Log(
+		"msg", "connecting with hunter2",
+		"err", errors.New("auth failed for hunter2"),
+		"password", "plain",
+		"secret_key", common.Secret("other"),
+		"count", 3,
+	))
+
+	got := buf.String()
+	for _, revealed := range []string{"hunter2", "plain", "other"} {
+		test.Assert(t, !strings.Contains(got, revealed), "secret <%s> is revealed: %s", revealed, got)
+	}
+
+	test.Assert(t, strings.Contains(got, "count=3"), "values without secrets are kept: %s", got)
+}
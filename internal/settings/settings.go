@@ -0,0 +1,180 @@
+// Package settings decodes free-form key value settings into typed configurations,
+// for the backends and archive formats which are registered from outside of drone-cache.
+package settings
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tag is the struct tag to name the setting of a field, fields without it are named by their lower-cased name.
+// Fields tagged with "-" are skipped.
+const Tag = "setting"
+
+var (
+	// ErrUnknownSetting means that a setting does not correspond to any field of the configuration.
+	ErrUnknownSetting = errors.New("unknown setting")
+	// ErrUnsupportedType means that a field of the configuration has a type which can not be decoded.
+	ErrUnsupportedType = errors.New("unsupported type")
+)
+
+// nolint: gochecknoglobals
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Parse parses settings given as <key>=<value> pairs.
+func Parse(pairs []string) (map[string]string, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+
+	m := make(map[string]string, len(pairs))
+
+	for _, pair := range pairs {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("setting <%s> is not in <key>=<value> form", pair)
+		}
+
+		m[strings.TrimSpace(k)] = v
+	}
+
+	return m, nil
+}
+
+// Decode sets the fields of the struct pointed by v from the given settings.
+// Strings, booleans, numbers, durations, comma separated string slices and encoding.TextUnmarshaler are supported.
+// Settings which do not correspond to any field are reported, to catch typos early.
+func Decode(settings map[string]string, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("decode into <%T>, a pointer to a struct is required, %w", v, ErrUnsupportedType)
+	}
+
+	rv = rv.Elem()
+	rt := rv.Type()
+
+	used := make(map[string]bool, len(settings))
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := field.Tag.Get(Tag)
+		if name == "-" {
+			continue
+		}
+
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		s, ok := settings[name]
+		if !ok {
+			continue
+		}
+
+		used[name] = true
+
+		if err := set(rv.Field(i), s); err != nil {
+			return fmt.Errorf("setting <%s>, %w", name, err)
+		}
+	}
+
+	var unknown []string
+
+	for k := range settings {
+		if !used[k] {
+			unknown = append(unknown, k)
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+
+		return fmt.Errorf("<%s>, %w", strings.Join(unknown, ", "), ErrUnknownSetting)
+	}
+
+	return nil
+}
+
+// nolint: cyclop
+func set(v reflect.Value, s string) error {
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)) // nolint: forcetypeassert, wrapcheck
+	}
+
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("parse duration, %w", err)
+		}
+
+		v.SetInt(int64(d))
+
+		return nil
+	}
+
+	switch v.Kind() { // nolint: exhaustive
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("parse bool, %w", err)
+		}
+
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 0, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("parse int, %w", err)
+		}
+
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 0, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("parse uint, %w", err)
+		}
+
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("parse float, %w", err)
+		}
+
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("<%s>, %w", v.Type(), ErrUnsupportedType)
+		}
+
+		var items []string
+		if s != "" {
+			items = strings.Split(s, ",")
+		}
+
+		sv := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			sv.Index(i).SetString(strings.TrimSpace(item))
+		}
+
+		v.Set(sv)
+	default:
+		return fmt.Errorf("<%s>, %w", v.Type(), ErrUnsupportedType)
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package settings
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/meltwater/drone-cache/test"
+)
+
+type config struct {
+	Endpoint string
+	Secure   bool
+	Retries  int
+	Size     uint64 `setting:"max-size"`
+	Ratio    float64
+	Timeout  time.Duration
+	Zones    []string
+	Addr     net.IP
+	Ignored  string `setting:"-"`
+}
+
+func TestDecode(t *testing.T) {
+	t.Parallel()
+
+	m, err := Parse([]string{
+		"endpoint=https://store.local/?a=b",
+		"secure=true",
+		"retries=3",
+		"max-size=0x100",
+		"ratio=0.5",
+		"timeout=1m30s",
+		"zones=eu-1, eu-2",
+		"addr=10.0.0.1",
+	})
+	test.Ok(t, err)
+
+	var c config
+	test.Ok(t, Decode(m, &c))
+
+	test.Equals(t, config{
+		Endpoint: "https://store.local/?a=b",
+		Secure:   true,
+		Retries:  3,
+		Size:     256,
+		Ratio:    0.5,
+		Timeout:  90 * time.Second,
+		Zones:    []string{"eu-1", "eu-2"},
+		Addr:     net.ParseIP("10.0.0.1"),
+	}, c)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	t.Parallel()
+
+	var c config
+
+	test.Expected(t, Decode(map[string]string{"endpont": "typo"}, &c), ErrUnknownSetting)
+	test.Expected(t, Decode(map[string]string{"ignored": "x"}, &c), ErrUnknownSetting)
+	test.NotOk(t, Decode(map[string]string{"retries": "many"}, &c))
+	test.Expected(t, Decode(nil, c), ErrUnsupportedType)
+
+	_, err := Parse([]string{"novalue"})
+	test.NotOk(t, err)
+}
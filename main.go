@@ -5,21 +5,34 @@ import (
 	"fmt"
 	stdlog "log"
 	"os"
+	"strings"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/meltwater/drone-cache/archive"
 	"github.com/meltwater/drone-cache/internal"
+	"github.com/meltwater/drone-cache/internal/configfile"
 	"github.com/meltwater/drone-cache/internal/metadata"
 	"github.com/meltwater/drone-cache/internal/plugin"
+	"github.com/meltwater/drone-cache/internal/settings"
 	"github.com/meltwater/drone-cache/storage"
 	"github.com/meltwater/drone-cache/storage/backend"
 	"github.com/meltwater/drone-cache/storage/backend/alioss"
 	"github.com/meltwater/drone-cache/storage/backend/azure"
+	"github.com/meltwater/drone-cache/storage/backend/fault"
 	"github.com/meltwater/drone-cache/storage/backend/filesystem"
+	"github.com/meltwater/drone-cache/storage/backend/ftp"
 	"github.com/meltwater/drone-cache/storage/backend/gcs"
+	"github.com/meltwater/drone-cache/storage/backend/gha"
+	"github.com/meltwater/drone-cache/storage/backend/http"
+	"github.com/meltwater/drone-cache/storage/backend/mirror"
+	"github.com/meltwater/drone-cache/storage/backend/oci"
+	"github.com/meltwater/drone-cache/storage/backend/redis"
 	"github.com/meltwater/drone-cache/storage/backend/s3"
 	"github.com/meltwater/drone-cache/storage/backend/sftp"
+	"github.com/meltwater/drone-cache/storage/backend/shard"
+	"github.com/meltwater/drone-cache/storage/backend/tiered"
+	"github.com/meltwater/drone-cache/storage/common"
 	"github.com/urfave/cli/v2"
 )
 
@@ -37,7 +50,17 @@ func main() {
 	app.Usage = "Drone cache plugin"
 	app.Action = run
 	app.Version = version
+	app.Commands = commands()
+	app.Before = loadConfigFile
 	app.Flags = []cli.Flag{
+		// Config file flags
+
+		&cli.StringFlag{
+			Name:    "config",
+			Usage:   "path of the YAML or JSON configuration file, defaults to .drone-cache.yml, .drone-cache.yaml or .drone-cache.json in the workspace",
+			EnvVars: []string{"PLUGIN_CONFIG"},
+		},
+
 		// Logger flags
 
 		&cli.StringFlag{
@@ -228,7 +251,7 @@ func main() {
 
 		&cli.StringFlag{
 			Name:    "backend, b",
-			Usage:   "cache backend to use in plugin (s3, filesystem, sftp, azure, gcs, alibaba)",
+			Usage:   "cache backend to use in plugin (s3, filesystem, sftp, ftp, azure, gcs, alibaba, http, oci, redis, gha, tiered, mirror, shard, fault)",
 			Value:   backend.S3,
 			EnvVars: []string{"PLUGIN_BACKEND"},
 		},
@@ -273,18 +296,37 @@ func main() {
 		// RESTORE-KEYS
 		&cli.StringFlag{
 			Name:    "archive-format, arcfmt",
-			Usage:   "archive format to use to store the cache directories (tar, gzip, zstd)",
+			Usage:   "archive format to use to store the cache directories (tar, gzip, zstd, lz4, xz, zip)",
 			Value:   archive.DefaultArchiveFormat,
 			EnvVars: []string{"PLUGIN_ARCHIVE_FORMAT"},
 		},
 		&cli.IntFlag{
 			Name: "compression-level, cpl",
-			Usage: `compression level to use for gzip/zstd compression when archive-format specified as gzip/zstd
-			(check https://godoc.org/compress/flate#pkg-constants for available options for gzip
-			and https://pkg.go.dev/github.com/klauspost/compress/zstd#EncoderLevelFromZstd for zstd)`,
+			Usage: `compression level to use for gzip/zstd/lz4/zip compression when archive-format specified as gzip/zstd/lz4/zip
+			(check https://godoc.org/compress/flate#pkg-constants for available options for gzip and zip,
+			https://pkg.go.dev/github.com/klauspost/compress/zstd#EncoderLevelFromZstd for zstd
+			and 1-9 for lz4, anything lower uses the fast mode)`,
 			Value:   archive.DefaultCompressionLevel,
 			EnvVars: []string{"PLUGIN_COMPRESSION_LEVEL"},
 		},
+		&cli.IntFlag{
+			Name: "compression-threads, cpt",
+			Usage: `number of threads to use for compression and decompression when archive-format specified as gzip/zstd/lz4
+			(0 uses all available CPUs, output stays compatible with standard decompressors)`,
+			Value:   archive.DefaultCompressionThreads,
+			EnvVars: []string{"PLUGIN_COMPRESSION_THREADS"},
+		},
+		&cli.IntFlag{
+			Name: "zstd-window-size, zws",
+			Usage: `window size in bytes to use for zstd compression, must be a power of two
+			(0 uses the encoder default, standard decompressors may need a higher memory limit above 128MB)`,
+			EnvVars: []string{"PLUGIN_ZSTD_WINDOW_SIZE"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "archive-settings",
+			Usage:   "settings of a custom archive format, given as <key>=<value>",
+			EnvVars: []string{"PLUGIN_ARCHIVE_SETTINGS"},
+		},
 		&cli.BoolFlag{
 			Name:    "skip-symlinks, ss",
 			Usage:   "skip symbolic links in archive",
@@ -301,6 +343,36 @@ func main() {
 			Hidden:  true,
 			EnvVars: []string{"PLUGIN_EXIT_CODE", "EXIT_CODE"},
 		},
+		&cli.StringFlag{
+			Name:    "outputs",
+			Usage:   "path of the file to write the results of restore to, as JSON if it ends with .json, otherwise in dotenv format",
+			EnvVars: []string{"PLUGIN_OUTPUTS"},
+		},
+		&cli.BoolFlag{
+			Name:    "skip-rebuild-on-hit",
+			Usage:   "skip rebuild if the outputs of restore report an exact hit of the same key and mounts, requires outputs",
+			EnvVars: []string{"PLUGIN_SKIP_REBUILD_ON_HIT"},
+		},
+		&cli.BoolFlag{
+			Name:    "preflight",
+			Usage:   "probe the storage by writing, reading and deleting a canary object before rebuild",
+			EnvVars: []string{"PLUGIN_PREFLIGHT"},
+		},
+		&cli.StringFlag{
+			Name:    "card-path",
+			Usage:   "path to write the summary card of the run to, for Drone to render it in the build UI",
+			EnvVars: []string{"DRONE_CARD_PATH"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "redact-env",
+			Usage:   "additional patterns of the names of environment variables to redact in debug output, e.g. *_DSN",
+			EnvVars: []string{"PLUGIN_REDACT_ENV"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "failure-policy",
+			Usage:   "actions (ignore, warn, fail) for errors given as [<operation>:]<class>=<action>[:<exit code>], e.g. restore:not-found=ignore",
+			EnvVars: []string{"PLUGIN_FAILURE_POLICY"},
+		},
 
 		// Backends Configs
 
@@ -312,6 +384,46 @@ func main() {
 			Value:   storage.DefaultOperationTimeout,
 			EnvVars: []string{"PLUGIN_BACKEND_OPERATION_TIMEOUT", "BACKEND_OPERATION_TIMEOUT"},
 		},
+		&cli.StringSliceFlag{
+			Name:    "backend.settings",
+			Usage:   "settings of a custom backend, given as <key>=<value>",
+			EnvVars: []string{"PLUGIN_BACKEND_SETTINGS"},
+		},
+		&cli.StringFlag{
+			Name:    "transport.ca-file",
+			Usage:   "PEM bundle of certificate authorities to trust in addition to the system ones, for backends that speak http",
+			EnvVars: []string{"PLUGIN_TRANSPORT_CA_FILE", "TRANSPORT_CA_FILE"},
+		},
+		&cli.StringFlag{
+			Name:    "transport.cert-file",
+			Usage:   "PEM client certificate to present to backends that speak http, for mutual tls",
+			EnvVars: []string{"PLUGIN_TRANSPORT_CERT_FILE", "TRANSPORT_CERT_FILE"},
+		},
+		&cli.StringFlag{
+			Name:    "transport.key-file",
+			Usage:   "PEM key of the client certificate",
+			EnvVars: []string{"PLUGIN_TRANSPORT_KEY_FILE", "TRANSPORT_KEY_FILE"},
+		},
+		&cli.StringFlag{
+			Name:    "transport.proxy-url",
+			Usage:   "proxy to send the requests of backends that speak http through, HTTP_PROXY and HTTPS_PROXY are used when empty",
+			EnvVars: []string{"PLUGIN_TRANSPORT_PROXY_URL", "TRANSPORT_PROXY_URL"},
+		},
+		&cli.DurationFlag{
+			Name:    "transport.connect-timeout",
+			Usage:   "timeout of establishing connections to backends that speak http",
+			EnvVars: []string{"PLUGIN_TRANSPORT_CONNECT_TIMEOUT", "TRANSPORT_CONNECT_TIMEOUT"},
+		},
+		&cli.DurationFlag{
+			Name:    "transport.idle-timeout",
+			Usage:   "time that idle connections to backends that speak http are kept open",
+			EnvVars: []string{"PLUGIN_TRANSPORT_IDLE_TIMEOUT", "TRANSPORT_IDLE_TIMEOUT"},
+		},
+		&cli.BoolFlag{
+			Name:    "transport.disable-http2",
+			Usage:   "use only http/1.1 with backends that speak http",
+			EnvVars: []string{"PLUGIN_TRANSPORT_DISABLE_HTTP2", "TRANSPORT_DISABLE_HTTP2"},
+		},
 		&cli.StringFlag{
 			Name:    "endpoint, e",
 			Usage:   "endpoint for the s3/cloud storage connection",
@@ -339,11 +451,21 @@ func main() {
 			Usage:   "AWS access key",
 			EnvVars: []string{"PLUGIN_ACCESS_KEY", "AWS_ACCESS_KEY_ID", "CACHE_AWS_ACCESS_KEY_ID"},
 		},
+		&cli.StringFlag{
+			Name:    "access-key-file",
+			Usage:   "file to read the AWS access key from",
+			EnvVars: []string{"PLUGIN_ACCESS_KEY_FILE", "AWS_ACCESS_KEY_ID_FILE", "CACHE_AWS_ACCESS_KEY_ID_FILE"},
+		},
 		&cli.StringFlag{
 			Name:    "secret-key, skey",
 			Usage:   "AWS secret key",
 			EnvVars: []string{"PLUGIN_SECRET_KEY", "AWS_SECRET_ACCESS_KEY", "CACHE_AWS_SECRET_ACCESS_KEY"},
 		},
+		&cli.StringFlag{
+			Name:    "secret-key-file",
+			Usage:   "file to read the AWS secret key from",
+			EnvVars: []string{"PLUGIN_SECRET_KEY_FILE", "AWS_SECRET_ACCESS_KEY_FILE", "CACHE_AWS_SECRET_ACCESS_KEY_FILE"},
+		},
 		&cli.StringFlag{
 			Name:    "region, reg",
 			Usage:   "AWS bucket region. (us-east-1, eu-west-1, ...)",
@@ -395,11 +517,21 @@ func main() {
 			Usage:   "Google service account API key",
 			EnvVars: []string{"PLUGIN_API_KEY", "GCP_API_KEY"},
 		},
+		&cli.StringFlag{
+			Name:    "gcs.api-key-file",
+			Usage:   "file to read the Google service account API key from",
+			EnvVars: []string{"PLUGIN_API_KEY_FILE", "GCP_API_KEY_FILE"},
+		},
 		&cli.StringFlag{
 			Name:    "gcs.json-key",
 			Usage:   "Google service account JSON key",
 			EnvVars: []string{"PLUGIN_JSON_KEY", "GCS_CACHE_JSON_KEY"},
 		},
+		&cli.StringFlag{
+			Name:    "gcs.json-key-file",
+			Usage:   "file to read the Google service account JSON key from",
+			EnvVars: []string{"PLUGIN_JSON_KEY_FILE", "GCS_CACHE_JSON_KEY_FILE"},
+		},
 		&cli.StringFlag{
 			Name:    "gcs.acl, gacl",
 			Usage:   "upload files with acl (private, public-read, ...)",
@@ -425,6 +557,11 @@ func main() {
 			Usage:   "Azure Blob Storage Account Key",
 			EnvVars: []string{"PLUGIN_ACCOUNT_KEY", "AZURE_ACCOUNT_KEY"},
 		},
+		&cli.StringFlag{
+			Name:    "azure.account-key-file",
+			Usage:   "file to read the Azure Blob Storage Account Key from",
+			EnvVars: []string{"PLUGIN_ACCOUNT_KEY_FILE", "AZURE_ACCOUNT_KEY_FILE"},
+		},
 		&cli.StringFlag{
 			Name:    "azure.blob-container-name",
 			Usage:   "Azure Blob Storage container name",
@@ -460,6 +597,11 @@ func main() {
 			Usage:   "sftp password",
 			EnvVars: []string{"PLUGIN_PASSWORD", "SFTP_PASSWORD"},
 		},
+		&cli.StringFlag{
+			Name:    "sftp.password-file",
+			Usage:   "file to read the sftp password from",
+			EnvVars: []string{"PLUGIN_PASSWORD_FILE", "SFTP_PASSWORD_FILE"},
+		},
 		&cli.StringFlag{
 			Name:    "sftp.public-key-file",
 			Usage:   "sftp public key file path",
@@ -481,6 +623,60 @@ func main() {
 			EnvVars: []string{"SFTP_PORT"},
 		},
 
+		// FTP (storage) specific Config flags
+
+		&cli.StringFlag{
+			Name:    "ftp.cache-root",
+			Usage:   "ftp root directory",
+			EnvVars: []string{"PLUGIN_FTP_CACHE_ROOT", "FTP_CACHE_ROOT"},
+		},
+		&cli.StringFlag{
+			Name:    "ftp.host",
+			Usage:   "ftp host",
+			EnvVars: []string{"PLUGIN_FTP_HOST", "FTP_HOST"},
+		},
+		&cli.StringFlag{
+			Name:    "ftp.port",
+			Usage:   "ftp port, defaults to 21 or 990 with implicit tls",
+			EnvVars: []string{"PLUGIN_FTP_PORT", "FTP_PORT"},
+		},
+		&cli.StringFlag{
+			Name:    "ftp.username",
+			Usage:   "ftp username",
+			EnvVars: []string{"PLUGIN_FTP_USERNAME", "FTP_USERNAME"},
+		},
+		&cli.StringFlag{
+			Name:    "ftp.password",
+			Usage:   "ftp password",
+			EnvVars: []string{"PLUGIN_FTP_PASSWORD", "FTP_PASSWORD"},
+		},
+		&cli.StringFlag{
+			Name:    "ftp.password-file",
+			Usage:   "file to read the ftp password from",
+			EnvVars: []string{"PLUGIN_FTP_PASSWORD_FILE", "FTP_PASSWORD_FILE"},
+		},
+		&cli.StringFlag{
+			Name:    "ftp.tls",
+			Usage:   "ftps mode, defaults to plain ftp. (explicit, implicit)",
+			EnvVars: []string{"PLUGIN_FTP_TLS", "FTP_TLS"},
+		},
+		&cli.BoolFlag{
+			Name:    "ftp.skip-verify",
+			Usage:   "skip verification of the tls certificate of the ftp server",
+			EnvVars: []string{"PLUGIN_FTP_SKIP_VERIFY"},
+		},
+		&cli.BoolFlag{
+			Name:    "ftp.disable-epsv",
+			Usage:   "use PASV instead of EPSV for passive mode, for servers behind nat",
+			EnvVars: []string{"PLUGIN_FTP_DISABLE_EPSV"},
+		},
+		&cli.IntFlag{
+			Name:    "ftp.max-connections",
+			Usage:   "maximum number of connections to the ftp server, reused across mounts",
+			Value:   ftp.DefaultMaxConnections,
+			EnvVars: []string{"PLUGIN_FTP_MAX_CONNECTIONS"},
+		},
+
 		// Alibaba OSS (storage) specific Config flags
 
 		&cli.StringFlag{
@@ -488,11 +684,309 @@ func main() {
 			Usage:   "AlibabaOSS access key",
 			EnvVars: []string{"PLUGIN_ALIBABA_ACCESS_KEY", "ALIBABA_ACCESS_KEY_ID", "CACHE_ALIBABA_ACCESS_KEY_ID"},
 		},
+		&cli.StringFlag{
+			Name:    "alibaba.access-key-file",
+			Usage:   "file to read the AlibabaOSS access key from",
+			EnvVars: []string{"PLUGIN_ALIBABA_ACCESS_KEY_FILE", "ALIBABA_ACCESS_KEY_ID_FILE", "CACHE_ALIBABA_ACCESS_KEY_ID_FILE"},
+		},
 		&cli.StringFlag{
 			Name:    "alibaba.secret-key",
 			Usage:   "AlibabaOSS access secret",
 			EnvVars: []string{"PLUGIN_ALIBABA_ACCESS_SECRET", "ALIBABA_ACCESS_SECRET", "CACHE_ALIBABA_ACCESS_SECRET"},
 		},
+		&cli.StringFlag{
+			Name:    "alibaba.secret-key-file",
+			Usage:   "file to read the AlibabaOSS access secret from",
+			EnvVars: []string{"PLUGIN_ALIBABA_ACCESS_SECRET_FILE", "ALIBABA_ACCESS_SECRET_FILE", "CACHE_ALIBABA_ACCESS_SECRET_FILE"},
+		},
+
+		// HTTP (storage) specific Config flags
+
+		&cli.StringFlag{
+			Name:    "http.url",
+			Usage:   "base url of the http cache server, caches are stored under it",
+			EnvVars: []string{"PLUGIN_HTTP_URL"},
+		},
+		&cli.StringFlag{
+			Name:    "http.username",
+			Usage:   "username for basic authentication to the http cache server",
+			EnvVars: []string{"PLUGIN_HTTP_USERNAME", "HTTP_CACHE_USERNAME"},
+		},
+		&cli.StringFlag{
+			Name:    "http.password",
+			Usage:   "password for basic authentication to the http cache server",
+			EnvVars: []string{"PLUGIN_HTTP_PASSWORD", "HTTP_CACHE_PASSWORD"},
+		},
+		&cli.StringFlag{
+			Name:    "http.password-file",
+			Usage:   "file to read the password for basic authentication to the http cache server from",
+			EnvVars: []string{"PLUGIN_HTTP_PASSWORD_FILE", "HTTP_CACHE_PASSWORD_FILE"},
+		},
+		&cli.StringFlag{
+			Name:    "http.token",
+			Usage:   "bearer token for authentication to the http cache server",
+			EnvVars: []string{"PLUGIN_HTTP_TOKEN", "HTTP_CACHE_TOKEN"},
+		},
+		&cli.StringFlag{
+			Name:    "http.token-file",
+			Usage:   "file to read the bearer token for authentication to the http cache server from",
+			EnvVars: []string{"PLUGIN_HTTP_TOKEN_FILE", "HTTP_CACHE_TOKEN_FILE"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "http.header",
+			Usage:   "additional request header in <name: value> form",
+			EnvVars: []string{"PLUGIN_HTTP_HEADERS"},
+		},
+		&cli.StringFlag{
+			Name:    "http.list-method",
+			Usage:   "how to list caches on the http cache server (propfind, json)",
+			Value:   "propfind",
+			EnvVars: []string{"PLUGIN_HTTP_LIST_METHOD"},
+		},
+		&cli.BoolFlag{
+			Name:    "http.chunked-upload",
+			Usage:   "stream uploads with chunked transfer encoding, otherwise they are spooled to disk to send their size",
+			Value:   true,
+			EnvVars: []string{"PLUGIN_HTTP_CHUNKED_UPLOAD"},
+		},
+		&cli.BoolFlag{
+			Name:    "http.make-collections",
+			Usage:   "create missing parent collections with MKCOL before uploads, as plain WebDAV servers require",
+			EnvVars: []string{"PLUGIN_HTTP_MAKE_COLLECTIONS"},
+		},
+
+		// OCI registry (storage) specific Config flags
+
+		&cli.StringFlag{
+			Name:    "oci.repository",
+			Usage:   "repository to store caches in as artifacts (e.g. registry.example.com/ci/caches)",
+			EnvVars: []string{"PLUGIN_OCI_REPOSITORY"},
+		},
+		&cli.StringFlag{
+			Name:    "oci.username",
+			Usage:   "registry username, credentials are read from the docker config and its credential helpers when empty",
+			EnvVars: []string{"PLUGIN_OCI_USERNAME", "OCI_USERNAME"},
+		},
+		&cli.StringFlag{
+			Name:    "oci.password",
+			Usage:   "registry password or token",
+			EnvVars: []string{"PLUGIN_OCI_PASSWORD", "OCI_PASSWORD"},
+		},
+		&cli.StringFlag{
+			Name:    "oci.password-file",
+			Usage:   "file to read the registry password or token from",
+			EnvVars: []string{"PLUGIN_OCI_PASSWORD_FILE", "OCI_PASSWORD_FILE"},
+		},
+		&cli.BoolFlag{
+			Name:    "oci.insecure",
+			Usage:   "allow plain http connections to the registry",
+			EnvVars: []string{"PLUGIN_OCI_INSECURE"},
+		},
+
+		// Redis (storage) specific Config flags
+
+		&cli.StringFlag{
+			Name:    "redis.addr",
+			Usage:   "redis server address in <host>:<port> form",
+			Value:   "localhost:6379",
+			EnvVars: []string{"PLUGIN_REDIS_ADDR", "REDIS_ADDR"},
+		},
+		&cli.StringFlag{
+			Name:    "redis.username",
+			Usage:   "redis ACL username",
+			EnvVars: []string{"PLUGIN_REDIS_USERNAME", "REDIS_USERNAME"},
+		},
+		&cli.StringFlag{
+			Name:    "redis.password",
+			Usage:   "redis password",
+			EnvVars: []string{"PLUGIN_REDIS_PASSWORD", "REDIS_PASSWORD"},
+		},
+		&cli.StringFlag{
+			Name:    "redis.password-file",
+			Usage:   "file to read the redis password from",
+			EnvVars: []string{"PLUGIN_REDIS_PASSWORD_FILE", "REDIS_PASSWORD_FILE"},
+		},
+		&cli.IntFlag{
+			Name:    "redis.db",
+			Usage:   "redis database number",
+			EnvVars: []string{"PLUGIN_REDIS_DB"},
+		},
+		&cli.BoolFlag{
+			Name:    "redis.tls",
+			Usage:   "use tls to connect to redis",
+			EnvVars: []string{"PLUGIN_REDIS_TLS"},
+		},
+		&cli.StringFlag{
+			Name:    "redis.key-prefix",
+			Usage:   "prefix of all keys stored in redis",
+			Value:   redis.DefaultKeyPrefix,
+			EnvVars: []string{"PLUGIN_REDIS_KEY_PREFIX"},
+		},
+		&cli.DurationFlag{
+			Name:    "redis.ttl",
+			Usage:   "expire caches after the given duration since they are stored (0 means never)",
+			EnvVars: []string{"PLUGIN_REDIS_TTL"},
+		},
+		&cli.Int64Flag{
+			Name:    "redis.max-size",
+			Usage:   "maximum size of a cache in bytes, larger caches are rejected (0 means unlimited)",
+			Value:   redis.DefaultMaxSize,
+			EnvVars: []string{"PLUGIN_REDIS_MAX_SIZE"},
+		},
+		&cli.IntFlag{
+			Name:    "redis.chunk-size",
+			Usage:   "size of the values in bytes that caches are split into",
+			Value:   redis.DefaultChunkSize,
+			EnvVars: []string{"PLUGIN_REDIS_CHUNK_SIZE"},
+		},
+
+		// GitHub Actions cache service (storage) specific Config flags
+
+		&cli.StringFlag{
+			Name:    "gha.url",
+			Usage:   "base url of the github actions cache service",
+			EnvVars: []string{"PLUGIN_GHA_URL", "ACTIONS_CACHE_URL"},
+		},
+		&cli.StringFlag{
+			Name:    "gha.token",
+			Usage:   "bearer token of the github actions cache service",
+			EnvVars: []string{"PLUGIN_GHA_TOKEN", "ACTIONS_RUNTIME_TOKEN"},
+		},
+		&cli.StringFlag{
+			Name:    "gha.token-file",
+			Usage:   "file to read the bearer token of the github actions cache service from",
+			EnvVars: []string{"PLUGIN_GHA_TOKEN_FILE", "ACTIONS_RUNTIME_TOKEN_FILE"},
+		},
+		&cli.StringFlag{
+			Name:    "gha.version",
+			Usage:   "version of the cache entries, must match the version of actions/cache to share caches with it",
+			EnvVars: []string{"PLUGIN_GHA_VERSION"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "gha.restore-keys",
+			Usage:   "key prefixes to restore from when there is no cache entry with the exact key",
+			EnvVars: []string{"PLUGIN_GHA_RESTORE_KEYS"},
+		},
+		&cli.IntFlag{
+			Name:    "gha.chunk-size",
+			Usage:   "size of the chunks in bytes that uploads are split into",
+			Value:   gha.DefaultChunkSize,
+			EnvVars: []string{"PLUGIN_GHA_CHUNK_SIZE"},
+		},
+		&cli.IntFlag{
+			Name:    "gha.concurrency",
+			Usage:   "number of chunks uploaded concurrently",
+			Value:   gha.DefaultConcurrency,
+			EnvVars: []string{"PLUGIN_GHA_CONCURRENCY"},
+		},
+
+		// Tiered (storage) specific Config flags
+
+		&cli.StringFlag{
+			Name:    "tiered.remote",
+			Usage:   "remote backend to use behind the local cache (s3, filesystem, sftp, ftp, azure, gcs, alibaba, http, oci, redis, gha)",
+			Value:   backend.S3,
+			EnvVars: []string{"PLUGIN_TIERED_REMOTE"},
+		},
+		&cli.StringFlag{
+			Name:    "tiered.cache-root",
+			Usage:   "local directory to keep caches in front of the remote backend",
+			Value:   "/tmp/drone-cache",
+			EnvVars: []string{"PLUGIN_TIERED_CACHE_ROOT"},
+		},
+		&cli.Int64Flag{
+			Name:    "tiered.max-size",
+			Usage:   "maximum size of the local cache in bytes, least recently used caches are evicted above it (0 means unlimited)",
+			EnvVars: []string{"PLUGIN_TIERED_MAX_SIZE"},
+		},
+		&cli.BoolFlag{
+			Name:    "tiered.async-upload",
+			Usage:   "upload caches to the remote backend in the background, while the rest of the caches are being rebuilt",
+			EnvVars: []string{"PLUGIN_TIERED_ASYNC_UPLOAD"},
+		},
+
+		// Fault injection (storage) specific Config flags
+
+		&cli.StringFlag{
+			Name:    "fault.backend",
+			Usage:   "backend to inject faults into, for resilience testing only (s3, filesystem, sftp, ftp, azure, gcs, alibaba, http, oci, redis, gha, tiered)",
+			Value:   backend.FileSystem,
+			EnvVars: []string{"PLUGIN_FAULT_BACKEND"},
+		},
+		&cli.Int64Flag{
+			Name:    "fault.seed",
+			Usage:   "seed of the random faults, the same seed injects the same faults",
+			EnvVars: []string{"PLUGIN_FAULT_SEED"},
+		},
+		&cli.DurationFlag{
+			Name:    "fault.latency",
+			Usage:   "delay of every operation",
+			EnvVars: []string{"PLUGIN_FAULT_LATENCY"},
+		},
+		&cli.DurationFlag{
+			Name:    "fault.jitter",
+			Usage:   "maximum random delay of every operation on top of the latency",
+			EnvVars: []string{"PLUGIN_FAULT_JITTER"},
+		},
+		&cli.Float64Flag{
+			Name:    "fault.error-rate",
+			Usage:   "probability of an operation failing, between 0 and 1",
+			EnvVars: []string{"PLUGIN_FAULT_ERROR_RATE"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "fault.operations",
+			Usage:   "operations that fail with the error rate (get, put, exists, list, delete), all of them if empty",
+			EnvVars: []string{"PLUGIN_FAULT_OPERATIONS"},
+		},
+		&cli.Int64Flag{
+			Name:    "fault.fail-after",
+			Usage:   "fail downloads and uploads after the given number of bytes (0 means never)",
+			EnvVars: []string{"PLUGIN_FAULT_FAIL_AFTER"},
+		},
+		&cli.Int64Flag{
+			Name:    "fault.truncate-after",
+			Usage:   "silently drop downloaded content after the given number of bytes (0 means never)",
+			EnvVars: []string{"PLUGIN_FAULT_TRUNCATE_AFTER"},
+		},
+		&cli.IntFlag{
+			Name:    "fault.drip-size",
+			Usage:   "transfer downloads and uploads in chunks of the given number of bytes (0 means disabled)",
+			EnvVars: []string{"PLUGIN_FAULT_DRIP_SIZE"},
+		},
+		&cli.DurationFlag{
+			Name:    "fault.drip-interval",
+			Usage:   "pause between the chunks of the drip size",
+			EnvVars: []string{"PLUGIN_FAULT_DRIP_INTERVAL"},
+		},
+
+		// Mirror (storage) specific Config flags
+
+		&cli.StringSliceFlag{
+			Name: "mirror.backends",
+			Usage: "backends to mirror caches to, in priority order for reads, given as <backend>?<flag>=<value>&...\n" +
+				"\t(e.g. s3?bucket=caches-eu&region=eu-west-1), unspecified flags fall back to the global flags",
+			EnvVars: []string{"PLUGIN_MIRROR_BACKENDS"},
+		},
+		&cli.IntFlag{
+			Name:    "mirror.write-quorum",
+			Usage:   "number of mirrored backends that must succeed to store a cache (0 means all of them)",
+			EnvVars: []string{"PLUGIN_MIRROR_WRITE_QUORUM"},
+		},
+
+		// Shard (storage) specific Config flags
+
+		&cli.StringSliceFlag{
+			Name: "shard.backends",
+			Usage: "backends to spread caches across, given as <backend>?<flag>=<value>&... (e.g. s3?bucket=caches-0),\n" +
+				"\tnew shards must be appended to the end, unspecified flags fall back to the global flags",
+			EnvVars: []string{"PLUGIN_SHARD_BACKENDS"},
+		},
+		&cli.IntFlag{
+			Name:    "shard.replicas",
+			Usage:   "number of points of every shard on the consistent hash ring",
+			Value:   shard.DefaultReplicas,
+			EnvVars: []string{"PLUGIN_SHARD_REPLICAS"},
+		},
 	}
 
 	if err := app.Run(os.Args); err != nil {
@@ -502,12 +996,12 @@ func main() {
 
 // nolint:funlen
 func run(c *cli.Context) error {
-	logLevel := c.String("log.level")
-	if c.Bool("debug") {
-		logLevel = internal.LogLevelDebug
+	cfg, err := config(c)
+	if err != nil {
+		return fmt.Errorf("parse config, %w", err)
 	}
 
-	logger := internal.NewLogger(logLevel, c.String("log.format"), "drone-cache")
+	logger := newLogger(c, internal.Secrets(cfg)...)
 	level.Info(logger).Log("version", version, "commit", commit, "date", date)
 
 	plg := plugin.New(log.With(logger, "component", "plugin"))
@@ -547,20 +1041,154 @@ func run(c *cli.Context) error {
 		},
 	}
 
-	plg.Config = plugin.Config{
-		ArchiveFormat:    c.String("archive-format"),
-		Backend:          c.String("backend"),
-		CacheKeyTemplate: c.String("cache-key"),
-		CompressionLevel: c.Int("compression-level"),
-		Debug:            c.Bool("debug"),
-		Mount:            c.StringSlice("mount"),
-		Rebuild:          c.Bool("rebuild"),
-		Restore:          c.Bool("restore"),
-		RemoteRoot:       c.String("remote-root"),
-		LocalRoot:        c.String("local-root"),
-		Override:         c.Bool("override"),
+	plg.Config = cfg
+
+	// Recognized errors are logged and handled gracefully, unless exit-code is enabled or the policy says otherwise.
+	defaultAction := plugin.ActionWarn
+	if c.Bool("exit-code") {
+		defaultAction = plugin.ActionFail
+	}
+
+	policy, err := plugin.ParsePolicy(defaultAction, c.StringSlice("failure-policy"))
+	if err != nil {
+		return fmt.Errorf("parse failure policy, %w", err)
+	}
+
+	err = plg.Exec()
+	if err == nil {
+		return nil
+	}
+
+	var e plugin.Error
+	if !errors.As(err, &e) {
+		return fmt.Errorf("uncaught error, %w", err)
+	}
+
+	action, code := policy.Decide(err)
+	switch action {
+	case plugin.ActionIgnore:
+		level.Info(logger).Log("msg", "ignoring error by failure policy", "op", e.Op, "class", plugin.Class(err), "err", err)
+
+		return nil
+	case plugin.ActionWarn:
+		level.Error(logger).Log("op", e.Op, "class", plugin.Class(err), "err", err)
+
+		return nil
+	default:
+		level.Warn(logger).Log("msg", "silent fails disabled, exiting with status code on error", "code", code)
+
+		return cli.Exit(fmt.Sprintf("status code exit, %v", err), code)
+	}
+}
+
+// loadConfigFile sets the flags that are set neither on the command line nor by environment variables
+// from the configuration file, if there is one.
+func loadConfigFile(c *cli.Context) error {
+	path := c.String("config")
+	if path == "" {
+		if path = configfile.Find(c.String("local-root")); path == "" {
+			return nil
+		}
+	}
+
+	settings, err := configfile.Load(path, flagResolver(c.App.Flags), os.LookupEnv)
+	if err != nil {
+		return fmt.Errorf("load configuration file, %w", err)
+	}
+
+	for _, s := range settings {
+		if c.IsSet(s.Name) {
+			continue
+		}
+
+		for _, v := range s.Values {
+			if err := c.Set(s.Name, v); err != nil {
+				return fmt.Errorf("configuration file <%s>, setting <%s>, %w", path, s.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// flagResolver resolves the keys of the configuration file to flag names,
+// keys are either flag names or the names of the settings of the plugin, e.g. "azure.account-name" or "account_name".
+func flagResolver(flags []cli.Flag) configfile.Resolver {
+	return func(key string) (string, bool) {
+		env := "PLUGIN_" + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+
+		for _, f := range flags {
+			name := f.Names()[0]
+
+			// NOTICE: Some flags have their short names within their names, e.g. "log.level, ll".
+			for _, n := range strings.Split(name, ",") {
+				if strings.TrimSpace(n) == key {
+					return name, true
+				}
+			}
+
+			if df, ok := f.(cli.DocGenerationFlag); ok {
+				for _, e := range df.GetEnvVars() {
+					if e == env {
+						return name, true
+					}
+				}
+			}
+		}
+
+		return "", false
+	}
+}
+
+func newLogger(c *cli.Context, secrets ...string) log.Logger {
+	logLevel := c.String("log.level")
+	if c.Bool("debug") {
+		logLevel = internal.LogLevelDebug
+	}
+
+	return internal.NewLogger(logLevel, c.String("log.format"), "drone-cache", secrets...)
+}
+
+// nolint:funlen
+func config(c *cli.Context) (plugin.Config, error) {
+	backends, err := backendSpecs(c)
+	if err != nil {
+		return plugin.Config{}, err
+	}
+
+	archiveSettings, err := settings.Parse(c.StringSlice("archive-settings"))
+	if err != nil {
+		return plugin.Config{}, fmt.Errorf("parse archive settings, %w", err)
+	}
+
+	backendSettings, err := settings.Parse(c.StringSlice("backend.settings"))
+	if err != nil {
+		return plugin.Config{}, fmt.Errorf("parse backend settings, %w", err)
+	}
+
+	return plugin.Config{
+		ArchiveFormat:      c.String("archive-format"),
+		Backend:            c.String("backend"),
+		CacheKeyTemplate:   c.String("cache-key"),
+		CompressionLevel:   c.Int("compression-level"),
+		CompressionThreads: c.Int("compression-threads"),
+		ZstdWindowSize:     c.Int("zstd-window-size"),
+		Debug:              c.Bool("debug"),
+		Mount:              c.StringSlice("mount"),
+		Rebuild:            c.Bool("rebuild"),
+		Restore:            c.Bool("restore"),
+		RemoteRoot:         c.String("remote-root"),
+		LocalRoot:          c.String("local-root"),
+		Override:           c.Bool("override"),
+		Outputs:            c.String("outputs"),
+		SkipRebuildOnHit:   c.Bool("skip-rebuild-on-hit"),
+		Preflight:          c.Bool("preflight"),
+		CardPath:           c.String("card-path"),
+		RedactEnv:          c.StringSlice("redact-env"),
 
 		StorageOperationTimeout: c.Duration("backend.operation-timeout"),
+		ArchiveSettings:         archiveSettings,
+		BackendSettings:         backendSettings,
 		FileSystem: filesystem.Config{
 			CacheRoot: c.String("filesystem.cache-root"),
 		},
@@ -570,21 +1198,25 @@ func run(c *cli.Context) error {
 			Encryption:  c.String("encryption"),
 			Endpoint:    c.String("endpoint"),
 			Key:         c.String("access-key"),
+			KeyFile:     c.String("access-key-file"),
 			PathStyle:   c.Bool("path-style"),
 			Public:      c.Bool("s3-bucket-public"),
 			Region:      c.String("region"),
-			Secret:      c.String("secret-key"),
+			Secret:      common.Secret(c.String("secret-key")),
+			SecretFile:  c.String("secret-key-file"),
 			StsEndpoint: c.String("sts-endpoint"),
 			RoleArn:     c.String("role-arn"),
 			DisableSSL:  c.Bool("disable-ssl"),
 		},
 		Azure: azure.Config{
-			AccountName:    c.String("azure.account-name"),
-			AccountKey:     c.String("azure.account-key"),
-			ContainerName:  c.String("azure.container-name"),
-			BlobStorageURL: c.String("azure.blob-storage-url"),
-			Azurite:        false,
-			Timeout:        c.Duration("backend.operation-timeout"),
+			AccountName:      c.String("azure.account-name"),
+			AccountKey:       common.Secret(c.String("azure.account-key")),
+			AccountKeyFile:   c.String("azure.account-key-file"),
+			ContainerName:    c.String("azure.blob-container-name"),
+			BlobStorageURL:   c.String("azure.blob-storage-url"),
+			Azurite:          false,
+			MaxRetryRequests: c.Int("azure.blob-max-retry-requets"),
+			Timeout:          c.Duration("backend.operation-timeout"),
 		},
 		SFTP: sftp.Config{
 			CacheRoot: c.String("sftp.cache-root"),
@@ -592,49 +1224,149 @@ func run(c *cli.Context) error {
 			Host:      c.String("sftp.host"),
 			Port:      c.String("sftp.port"),
 			Auth: sftp.SSHAuth{
-				Password:      c.String("sftp.password"),
+				Password:      common.Secret(c.String("sftp.password")),
+				PasswordFile:  c.String("sftp.password-file"),
 				PublicKeyFile: c.String("sftp.public-key-file"),
 				Method:        sftp.SSHAuthMethod(c.String("sftp.auth-method")),
 			},
 			Timeout: c.Duration("backend.operation-timeout"),
 		},
+		FTP: ftp.Config{
+			CacheRoot:      c.String("ftp.cache-root"),
+			Host:           c.String("ftp.host"),
+			Port:           c.String("ftp.port"),
+			Username:       c.String("ftp.username"),
+			Password:       common.Secret(c.String("ftp.password")),
+			PasswordFile:   c.String("ftp.password-file"),
+			TLS:            ftp.TLSMode(c.String("ftp.tls")),
+			SkipVerify:     c.Bool("ftp.skip-verify"),
+			DisableEPSV:    c.Bool("ftp.disable-epsv"),
+			MaxConnections: c.Int("ftp.max-connections"),
+			Timeout:        c.Duration("backend.operation-timeout"),
+		},
 		GCS: gcs.Config{
-			Bucket:     c.String("bucket"),
-			Endpoint:   c.String("endpoint"),
-			APIKey:     c.String("gcs.api-key"),
-			JSONKey:    c.String("gcs.json-key"),
-			Encryption: c.String("gcs.encryption-key"),
-			Timeout:    c.Duration("backend.operation-timeout"),
+			Bucket:      c.String("bucket"),
+			Endpoint:    c.String("endpoint"),
+			APIKey:      common.Secret(c.String("gcs.api-key")),
+			JSONKey:     common.Secret(c.String("gcs.json-key")),
+			APIKeyFile:  c.String("gcs.api-key-file"),
+			JSONKeyFile: c.String("gcs.json-key-file"),
+			Encryption:  common.Secret(c.String("gcs.encryption-key")),
+			Timeout:     c.Duration("backend.operation-timeout"),
 		},
 		Alioss: alioss.Config{
-			Bucket:         c.String("bucket"),
-			Endpoint:       c.String("endpoint"),
-			AccesKeyID:     c.String("alibaba.access-key"),
-			AccesKeySecret: c.String("alibaba.secret-key"),
+			Bucket:             c.String("bucket"),
+			Endpoint:           c.String("endpoint"),
+			AccesKeyID:         c.String("alibaba.access-key"),
+			AccesKeySecret:     common.Secret(c.String("alibaba.secret-key")),
+			AccesKeyIDFile:     c.String("alibaba.access-key-file"),
+			AccesKeySecretFile: c.String("alibaba.secret-key-file"),
+		},
+		HTTP: http.Config{
+			URL:             c.String("http.url"),
+			Username:        c.String("http.username"),
+			Password:        common.Secret(c.String("http.password")),
+			BearerToken:     common.Secret(c.String("http.token")),
+			PasswordFile:    c.String("http.password-file"),
+			BearerTokenFile: c.String("http.token-file"),
+			Headers:         c.StringSlice("http.header"),
+			ListMethod:      c.String("http.list-method"),
+			ChunkedUpload:   c.Bool("http.chunked-upload"),
+			MakeCollections: c.Bool("http.make-collections"),
+		},
+		OCI: oci.Config{
+			Repository:   c.String("oci.repository"),
+			Username:     c.String("oci.username"),
+			Password:     common.Secret(c.String("oci.password")),
+			PasswordFile: c.String("oci.password-file"),
+			Insecure:     c.Bool("oci.insecure"),
+		},
+		Redis: redis.Config{
+			Addr:         c.String("redis.addr"),
+			Username:     c.String("redis.username"),
+			Password:     common.Secret(c.String("redis.password")),
+			PasswordFile: c.String("redis.password-file"),
+			DB:           c.Int("redis.db"),
+			TLS:          c.Bool("redis.tls"),
+			KeyPrefix:    c.String("redis.key-prefix"),
+			TTL:          c.Duration("redis.ttl"),
+			MaxSize:      c.Int64("redis.max-size"),
+			ChunkSize:    c.Int("redis.chunk-size"),
+		},
+		GHA: gha.Config{
+			URL:         c.String("gha.url"),
+			Token:       common.Secret(c.String("gha.token")),
+			TokenFile:   c.String("gha.token-file"),
+			Version:     c.String("gha.version"),
+			RestoreKeys: c.StringSlice("gha.restore-keys"),
+			ChunkSize:   c.Int("gha.chunk-size"),
+			Concurrency: c.Int("gha.concurrency"),
+		},
+		Tiered: tiered.Config{
+			Remote:      c.String("tiered.remote"),
+			CacheRoot:   c.String("tiered.cache-root"),
+			MaxSize:     c.Int64("tiered.max-size"),
+			AsyncUpload: c.Bool("tiered.async-upload"),
+		},
+		Fault: fault.Config{
+			Backend:       c.String("fault.backend"),
+			Seed:          c.Int64("fault.seed"),
+			Latency:       c.Duration("fault.latency"),
+			Jitter:        c.Duration("fault.jitter"),
+			ErrorRate:     c.Float64("fault.error-rate"),
+			Operations:    c.StringSlice("fault.operations"),
+			FailAfter:     c.Int64("fault.fail-after"),
+			TruncateAfter: c.Int64("fault.truncate-after"),
+			DripSize:      c.Int("fault.drip-size"),
+			DripInterval:  c.Duration("fault.drip-interval"),
+		},
+		Mirror: mirror.Config{
+			WriteQuorum: c.Int("mirror.write-quorum"),
+		},
+		Shard: shard.Config{
+			Replicas: c.Int("shard.replicas"),
+		},
+		Backends: backends,
+		Transport: common.TransportConfig{
+			CAFile:         c.String("transport.ca-file"),
+			CertFile:       c.String("transport.cert-file"),
+			KeyFile:        c.String("transport.key-file"),
+			ProxyURL:       c.String("transport.proxy-url"),
+			ConnectTimeout: c.Duration("transport.connect-timeout"),
+			IdleTimeout:    c.Duration("transport.idle-timeout"),
+			DisableHTTP2:   c.Bool("transport.disable-http2"),
 		},
 
 		SkipSymlinks: c.Bool("skip-symlinks"),
-	}
+	}, nil
+}
 
-	err := plg.Exec()
-	if err == nil {
-		return nil
+// backendSpecs parses backends of the composite backends, each of them is configured the same way as the plugin itself.
+func backendSpecs(c *cli.Context) ([]backend.Spec, error) {
+	if !backend.IsComposite(c.String("backend")) {
+		return nil, nil
 	}
 
-	if c.Bool("exit-code") {
-		// If it is exit-code enabled, always exit with error.
-		level.Warn(logger).Log("msg", "silent fails disabled, exiting with status code on error")
+	var specs []backend.Spec
 
-		return fmt.Errorf("status code exit, %w", err)
-	}
+	for _, spec := range c.StringSlice(c.String("backend") + ".backends") {
+		sc, err := specContext(c, spec)
+		if err != nil {
+			return nil, err
+		}
 
-	var e plugin.Error
-	if errors.As(err, &e) {
-		// If it is an expected error log it, handle it gracefully,
-		level.Error(logger).Log("err", err)
+		typ := sc.String("backend")
+		if backend.IsComposite(typ) {
+			return nil, fmt.Errorf("backend spec <%s>, composite backend <%s> can not be nested", spec, typ)
+		}
 
-		return nil
+		cfg, err := config(sc)
+		if err != nil {
+			return nil, err
+		}
+
+		specs = append(specs, backend.Spec{Type: typ, Config: cfg.BackendConfig()})
 	}
 
-	return fmt.Errorf("uncaught error, %w", err)
+	return specs, nil
 }
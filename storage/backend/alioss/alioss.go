@@ -8,6 +8,7 @@ import (
 	"github.com/aliyun/aliyun-oss-go-sdk/oss"
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
+	"github.com/meltwater/drone-cache/storage/common"
 	"github.com/pkg/errors"
 )
 
@@ -44,25 +45,52 @@ func New(l log.Logger, c Config, debug bool) (*Backend, error) {
 	}
 
 	if c.AccesKeySecret != "" {
-		ossConf.AccessKeySecret = c.AccesKeySecret
+		ossConf.AccessKeySecret = string(c.AccesKeySecret)
 	}
 
 	if debug {
 		level.Debug(l).Log("msg", "oss storage backend", "config", fmt.Sprintf("%+v", c))
 	}
 
-	return newAlibabaOss(c.Bucket, ossConf)
+	var opts []oss.ClientOption
+
+	if !c.Transport.IsZero() {
+		client, err := c.Transport.NewClient()
+		if err != nil {
+			return nil, errors.Wrap(err, "could not create transport")
+		}
+
+		opts = append(opts, oss.HTTPClient(client))
+	}
+
+	if c.AccesKeyIDFile == "" && c.AccesKeySecretFile == "" {
+		return newAlibabaOss(c.Bucket, ossConf, opts...)
+	}
+
+	id, err := common.NewSecretFile(common.Secret(c.AccesKeyID), c.AccesKeyIDFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read access key")
+	}
+
+	secret, err := common.NewSecretFile(c.AccesKeySecret, c.AccesKeySecretFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read secret key")
+	}
+
+	opts = append(opts, oss.SetCredentialsProvider(&fileCredentials{id: id, secret: secret}))
+
+	return newAlibabaOss(c.Bucket, ossConf, opts...)
 }
 
 func (c Backend) Get(ctx context.Context, p string, w io.Writer) error {
 	bucket, err := c.client.Bucket(c.bucket)
 	if err != nil {
-		return errors.Wrap(err, "couldn't get the object")
+		return errors.Wrap(classify(err), "couldn't get the object")
 	}
 
 	reader, err := bucket.GetObject(p)
 	if err != nil {
-		return errors.Wrap(err, "couldn't get the object")
+		return errors.Wrap(classify(err), "couldn't get the object")
 	}
 
 	if reader != nil {
@@ -75,7 +103,7 @@ func (c Backend) Get(ctx context.Context, p string, w io.Writer) error {
 func (c Backend) Put(ctx context.Context, p string, src io.Reader) error {
 	bucket, err := c.client.Bucket(c.bucket)
 	if err != nil {
-		return errors.Wrap(err, "couldn't put the object")
+		return errors.Wrap(classify(err), "couldn't put the object")
 	}
 
 	options := []oss.Option{}
@@ -91,7 +119,7 @@ func (c Backend) Put(ctx context.Context, p string, src io.Reader) error {
 	}
 
 	if err := bucket.PutObject(p, src, options...); err != nil {
-		return errors.Wrap(err, "couldn't put the object")
+		return errors.Wrap(classify(err), "couldn't put the object")
 	}
 
 	return nil
@@ -100,15 +128,115 @@ func (c Backend) Put(ctx context.Context, p string, src io.Reader) error {
 func (c Backend) Exists(ctx context.Context, p string) (bool, error) {
 	bucket, err := c.client.Bucket(c.bucket)
 	if err != nil {
-		return false, errors.Wrap(err, "couldn't get the bucket object")
+		return false, errors.Wrap(classify(err), "couldn't get the bucket object")
 	}
 
 	options := []oss.Option{}
 
 	result, err := bucket.IsObjectExist(p, options...)
 	if err != nil {
-		return false, errors.Wrap(err, "couldn't get the object")
+		return false, errors.Wrap(classify(err), "couldn't get the object")
 	}
 
 	return result, nil
 }
+
+func (c Backend) List(ctx context.Context, p string) ([]common.FileEntry, error) {
+	bucket, err := c.client.Bucket(c.bucket)
+	if err != nil {
+		return nil, errors.Wrap(classify(err), "couldn't get the bucket object")
+	}
+
+	var (
+		entries []common.FileEntry
+		token   string
+	)
+
+	for {
+		result, err := bucket.ListObjectsV2(oss.Prefix(p), oss.ContinuationToken(token))
+		if err != nil {
+			return nil, errors.Wrap(classify(err), "couldn't list the objects")
+		}
+
+		for _, obj := range result.Objects {
+			if !common.InPrefix(obj.Key, p) {
+				continue
+			}
+
+			entries = append(entries, common.FileEntry{
+				Path:         obj.Key,
+				Size:         obj.Size,
+				LastModified: obj.LastModified,
+			})
+		}
+
+		if !result.IsTruncated {
+			return entries, nil
+		}
+
+		token = result.NextContinuationToken
+	}
+}
+
+func (c Backend) Delete(ctx context.Context, p string) error {
+	bucket, err := c.client.Bucket(c.bucket)
+	if err != nil {
+		return errors.Wrap(classify(err), "couldn't get the bucket object")
+	}
+
+	if err := bucket.DeleteObject(p); err != nil {
+		return errors.Wrap(classify(err), "couldn't delete the object")
+	}
+
+	return nil
+}
+
+// Helpers
+
+// classify classifies the errors of OSS by their error codes, or by their status codes.
+func classify(err error) error {
+	var serr oss.ServiceError
+	if !errors.As(err, &serr) {
+		return common.Classify(err)
+	}
+
+	switch serr.Code {
+	case "NoSuchKey":
+		return common.NewError(common.ErrNotFound, err)
+	case "NoSuchBucket":
+		return common.NewError(common.ErrInvalidConfig, err)
+	case "AccessDenied", "InvalidAccessKeyId", "SignatureDoesNotMatch", "SecurityTokenExpired":
+		return common.NewError(common.ErrUnauthorized, err)
+	case "RequestTimeout":
+		return common.NewError(common.ErrTimeout, err)
+	case "InvalidDigest", "BadDigest":
+		return common.NewError(common.ErrIntegrity, err)
+	case "EntityTooLarge":
+		return common.NewError(common.ErrQuotaExceeded, err)
+	default:
+		return common.ClassifyStatus(serr.StatusCode, err)
+	}
+}
+
+// fileCredentials implements oss.CredentialsProvider and oss.Credentials for keys read from files,
+// which are read again when the files change.
+// NOTICE: If a file can not be read anymore, the last key read from it is used.
+type fileCredentials struct {
+	id, secret *common.SecretFile
+}
+
+func (f *fileCredentials) GetCredentials() oss.Credentials { return f }
+
+func (f *fileCredentials) GetAccessKeyID() string {
+	id, _ := f.id.Read()
+
+	return string(id)
+}
+
+func (f *fileCredentials) GetAccessKeySecret() string {
+	secret, _ := f.secret.Read()
+
+	return string(secret)
+}
+
+func (f *fileCredentials) GetSecurityToken() string { return "" }
@@ -1,7 +1,76 @@
+//go:build integration
+// +build integration
+
 package alioss
 
-import "testing"
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/go-kit/log"
+
+	"github.com/meltwater/drone-cache/storage/backend/backendtest"
+	"github.com/meltwater/drone-cache/storage/common"
+	"github.com/meltwater/drone-cache/test"
+)
+
+var (
+	endpoint        = os.Getenv("TEST_ALIOSS_ENDPOINT")
+	accessKey       = os.Getenv("TEST_ALIOSS_ACCESS_KEY")
+	secretAccessKey = os.Getenv("TEST_ALIOSS_SECRET_KEY")
+	bucket          = getEnv("TEST_ALIOSS_BUCKET", "alioss-conformance")
+)
 
 func TestAlibabaOss(t *testing.T) {
 	t.Skip("skipping backend package tests")
 }
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	backend := setup(t, Config{
+		Bucket:         bucket,
+		Endpoint:       endpoint,
+		AccesKeyID:     accessKey,
+		AccesKeySecret: common.Secret(secretAccessKey),
+	})
+
+	backendtest.Run(t, backend, backendtest.WithPrefix(fmt.Sprintf("conformance-%d", time.Now().UnixNano())))
+}
+
+// Helpers
+
+func setup(t *testing.T, config Config) *Backend {
+	// NOTICE: There is no emulator of OSS, the tests run against the account given by the environment.
+	if config.Endpoint == "" {
+		t.Skip("TEST_ALIOSS_ENDPOINT is not set")
+	}
+
+	client, err := oss.New(config.Endpoint, config.AccesKeyID, string(config.AccesKeySecret))
+	test.Ok(t, err)
+
+	exists, err := client.IsBucketExist(config.Bucket)
+	test.Ok(t, err)
+
+	if !exists {
+		test.Ok(t, client.CreateBucket(config.Bucket))
+		t.Cleanup(func() { client.DeleteBucket(config.Bucket) })
+	}
+
+	b, err := New(log.NewNopLogger(), config, false)
+	test.Ok(t, err)
+
+	return b
+}
+
+func getEnv(key, defaultVal string) string {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return defaultVal
+	}
+
+	return value
+}
@@ -1,9 +1,36 @@
 package alioss
 
+import "github.com/meltwater/drone-cache/storage/common"
+
 // Config is a structure to store AlibabaOss backend configuration.
 type Config struct {
 	Bucket         string
 	Endpoint       string
 	AccesKeyID     string
-	AccesKeySecret string
+	AccesKeySecret common.Secret
+
+	// AccesKeyIDFile and AccesKeySecretFile are files to read the keys from, instead of AccesKeyID and AccesKeySecret.
+	// They are read again when they change.
+	AccesKeyIDFile     string
+	AccesKeySecretFile string
+
+	// Transport configures the HTTP transport, e.g. the CA bundle, the client certificate and the proxy.
+	Transport common.TransportConfig
+}
+
+// Validate reports every problem of the configuration.
+func (c Config) Validate() error {
+	var v common.Validation
+
+	v.Required("bucket", c.Bucket)
+	v.Required("endpoint", c.Endpoint)
+	v.Endpoint("endpoint", c.Endpoint)
+	v.Required("access key", c.AccesKeyID+c.AccesKeyIDFile)
+	v.Required("secret key", string(c.AccesKeySecret)+c.AccesKeySecretFile)
+	v.SecretFile("access key", common.Secret(c.AccesKeyID), c.AccesKeyIDFile)
+	v.SecretFile("secret key", c.AccesKeySecret, c.AccesKeySecretFile)
+
+	v.Merge("transport", c.Transport.Validate())
+
+	return v.Err()
 }
@@ -7,11 +7,14 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"sync"
 
+	"github.com/Azure/azure-pipeline-go/pipeline"
 	"github.com/Azure/azure-storage-blob-go/azblob"
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/meltwater/drone-cache/internal"
+	"github.com/meltwater/drone-cache/storage/common"
 )
 
 const (
@@ -33,12 +36,12 @@ type Backend struct {
 // New creates an AzureBlob backend.
 func New(l log.Logger, c Config) (*Backend, error) {
 	// 1. From the Azure portal, get your storage account name and key and set environment variables.
-	if c.AccountName == "" || c.AccountKey == "" {
+	if c.AccountName == "" || (c.AccountKey == "" && c.AccountKeyFile == "") {
 		return nil, errors.New("either the AZURE_ACCOUNT_NAME or AZURE_ACCOUNT_KEY environment variable is not set")
 	}
 
 	// 2. Create a default request pipeline using your storage account name and account key.
-	credential, err := azblob.NewSharedKeyCredential(c.AccountName, c.AccountKey)
+	credential, err := newCredential(c)
 	if err != nil {
 		return nil, fmt.Errorf("azure, invalid credentials, %w", err)
 	}
@@ -55,7 +58,18 @@ func New(l log.Logger, c Config) (*Backend, error) {
 		level.Error(l).Log("msg", "can't create url with : "+err.Error())
 	}
 
-	pipeline := azblob.NewPipeline(credential, azblob.PipelineOptions{})
+	options := azblob.PipelineOptions{}
+
+	if !c.Transport.IsZero() {
+		client, err := c.Transport.NewClient()
+		if err != nil {
+			return nil, fmt.Errorf("azure, transport, %w", err)
+		}
+
+		options.HTTPSender = httpSender(client)
+	}
+
+	pipeline := azblob.NewPipeline(credential, options)
 	containerURL := azblob.NewContainerURL(*blobURL, pipeline)
 
 	// 4. Always creating new container, it will throw error if it already exists.
@@ -90,7 +104,7 @@ func (b *Backend) Get(ctx context.Context, p string, w io.Writer) error {
 		// nolint: lll
 		resp, err := blobURL.Download(ctx, 0, azblob.CountToEnd, azblob.BlobAccessConditions{}, false, azblob.ClientProvidedKeyOptions{})
 		if err != nil {
-			errCh <- fmt.Errorf("get the object, %w", err)
+			errCh <- fmt.Errorf("get the object, %w", classify(err))
 
 			return
 		}
@@ -100,7 +114,7 @@ func (b *Backend) Get(ctx context.Context, p string, w io.Writer) error {
 
 		_, err = io.Copy(w, rc)
 		if err != nil {
-			errCh <- fmt.Errorf("copy the object, %w", err)
+			errCh <- fmt.Errorf("copy the object, %w", classify(err))
 		}
 	}()
 
@@ -124,7 +138,7 @@ func (b *Backend) Put(ctx context.Context, p string, r io.Reader) error {
 			MaxBuffers: defaultMaxBuffers,
 		},
 	); err != nil {
-		return fmt.Errorf("put the object, %w", err)
+		return fmt.Errorf("put the object, %w", classify(err))
 	}
 
 	return nil
@@ -137,9 +151,165 @@ func (b *Backend) Exists(ctx context.Context, p string) (bool, error) {
 	blobURL := b.containerURL.NewBlockBlobURL(p)
 
 	get, err := blobURL.GetProperties(ctx, azblob.BlobAccessConditions{}, azblob.ClientProvidedKeyOptions{})
+	if err = classify(err); errors.Is(err, common.ErrNotFound) {
+		return false, nil
+	}
+
 	if err != nil {
 		return false, fmt.Errorf("check if object exists, %w", err)
 	}
 
 	return get.StatusCode() == http.StatusOK, nil
 }
+
+// List lists all the objects that are the given path or under it, recursively.
+func (b *Backend) List(ctx context.Context, p string) ([]common.FileEntry, error) {
+	var entries []common.FileEntry
+
+	for marker := (azblob.Marker{}); marker.NotDone(); {
+		resp, err := b.containerURL.ListBlobsFlatSegment(ctx, marker, azblob.ListBlobsSegmentOptions{Prefix: p})
+		if err != nil {
+			return nil, fmt.Errorf("list the objects, %w", classify(err))
+		}
+
+		for _, blob := range resp.Segment.BlobItems {
+			if !common.InPrefix(blob.Name, p) {
+				continue
+			}
+
+			var size int64
+			if blob.Properties.ContentLength != nil {
+				size = *blob.Properties.ContentLength
+			}
+
+			entries = append(entries, common.FileEntry{
+				Path:         blob.Name,
+				Size:         size,
+				LastModified: blob.Properties.LastModified,
+			})
+		}
+
+		marker = resp.NextMarker
+	}
+
+	return entries, nil
+}
+
+// Delete deletes the object with the given path.
+func (b *Backend) Delete(ctx context.Context, p string) error {
+	blobURL := b.containerURL.NewBlockBlobURL(p)
+	if _, err := blobURL.Delete(ctx, azblob.DeleteSnapshotsOptionInclude, azblob.BlobAccessConditions{}); err != nil {
+		return fmt.Errorf("delete the object, %w", classify(err))
+	}
+
+	return nil
+}
+
+// Helpers
+
+// classify classifies the errors of Azure Blob Storage by their service codes, or by their status codes.
+func classify(err error) error {
+	var serr azblob.StorageError
+	if !errors.As(err, &serr) {
+		return common.Classify(err)
+	}
+
+	switch serr.ServiceCode() {
+	case azblob.ServiceCodeBlobNotFound:
+		return common.NewError(common.ErrNotFound, err)
+	case azblob.ServiceCodeContainerNotFound:
+		return common.NewError(common.ErrInvalidConfig, err)
+	case azblob.ServiceCodeAuthenticationFailed, azblob.ServiceCodeInsufficientAccountPermissions, "AuthorizationFailure":
+		return common.NewError(common.ErrUnauthorized, err)
+	case azblob.ServiceCodeOperationTimedOut:
+		return common.NewError(common.ErrTimeout, err)
+	case azblob.ServiceCodeMd5Mismatch, azblob.ServiceCodeInvalidMd5:
+		return common.NewError(common.ErrIntegrity, err)
+	}
+
+	if resp := serr.Response(); resp != nil {
+		return common.ClassifyStatus(resp.StatusCode, err)
+	}
+
+	return common.Classify(err)
+}
+
+// httpSender returns a pipeline factory that sends requests with the given client, as the default one does.
+func httpSender(client *http.Client) pipeline.Factory {
+	return pipeline.FactoryFunc(func(next pipeline.Policy, po *pipeline.PolicyOptions) pipeline.PolicyFunc {
+		return func(ctx context.Context, request pipeline.Request) (pipeline.Response, error) {
+			r, err := client.Do(request.WithContext(ctx))
+			if err != nil {
+				err = pipeline.NewError(err, "HTTP request failed")
+			}
+
+			return pipeline.NewHTTPResponse(r), err
+		}
+	})
+}
+
+// newCredential returns the shared key credential of the account key, or of the file to read it from.
+func newCredential(c Config) (azblob.Credential, error) {
+	key, err := common.NewSecretFile(c.AccountKey, c.AccountKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, _ := key.Read()
+
+	credential, err := azblob.NewSharedKeyCredential(c.AccountName, string(secret))
+	if err != nil {
+		return nil, err // nolint: wrapcheck
+	}
+
+	if c.AccountKeyFile == "" {
+		return credential, nil
+	}
+
+	return &fileCredential{SharedKeyCredential: credential, accountName: c.AccountName, key: key}, nil
+}
+
+// fileCredential signs requests with the account key read from a file, and reads it again when the file changes.
+type fileCredential struct {
+	*azblob.SharedKeyCredential
+
+	accountName string
+	key         *common.SecretFile
+
+	mu sync.Mutex
+}
+
+// New creates a credential policy object, that signs every request with the current account key.
+func (f *fileCredential) New(next pipeline.Policy, po *pipeline.PolicyOptions) pipeline.Policy {
+	return pipeline.PolicyFunc(func(ctx context.Context, request pipeline.Request) (pipeline.Response, error) {
+		credential, err := f.current()
+		if err != nil {
+			return nil, err
+		}
+
+		return credential.New(next, po).Do(ctx, request)
+	})
+}
+
+func (f *fileCredential) current() (*azblob.SharedKeyCredential, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.key.Changed() {
+		return f.SharedKeyCredential, nil
+	}
+
+	secret, err := f.key.Read()
+	if err != nil {
+		return nil, fmt.Errorf("azure, read account key, %w", err)
+	}
+
+	credential, err := azblob.NewSharedKeyCredential(f.accountName, string(secret))
+	if err != nil {
+		return nil, fmt.Errorf("azure, invalid credentials, %w", err)
+	}
+
+	f.SharedKeyCredential = credential
+
+	return credential, nil
+}
@@ -5,6 +5,7 @@ package azure
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"strings"
@@ -12,6 +13,8 @@ import (
 	"time"
 
 	"github.com/go-kit/log"
+	"github.com/meltwater/drone-cache/storage/backend/backendtest"
+	"github.com/meltwater/drone-cache/storage/common"
 	"github.com/meltwater/drone-cache/test"
 )
 
@@ -55,6 +58,15 @@ func TestRoundTrip(t *testing.T) {
 	test.Equals(t, true, exists)
 }
 
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	backend, cleanUp := setup(t)
+	t.Cleanup(cleanUp)
+
+	backendtest.Run(t, backend, backendtest.WithPrefix(fmt.Sprintf("conformance-%d", time.Now().UnixNano())))
+}
+
 // Helpers
 
 func setup(t *testing.T) (*Backend, func()) {
@@ -62,7 +74,7 @@ func setup(t *testing.T) (*Backend, func()) {
 		log.NewNopLogger(),
 		Config{
 			AccountName:    accountName,
-			AccountKey:     accountKey,
+			AccountKey:     common.Secret(accountKey),
 			ContainerName:  containerName,
 			BlobStorageURL: blobURL,
 			Azurite:        true,
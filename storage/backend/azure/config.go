@@ -1,14 +1,56 @@
 package azure
 
-import "time"
+import (
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/meltwater/drone-cache/storage/common"
+)
 
 // Config is a structure to store Azure backend configuration.
 type Config struct {
-	AccountName      string
-	AccountKey       string
+	AccountName string
+	AccountKey  common.Secret
+	// AccountKeyFile is a file to read the account key from, instead of AccountKey. It is read again when it changes.
+	AccountKeyFile   string
 	ContainerName    string
 	BlobStorageURL   string
 	Azurite          bool
 	MaxRetryRequests int
 	Timeout          time.Duration
+
+	// Transport configures the HTTP transport, e.g. the CA bundle, the client certificate and the proxy.
+	Transport common.TransportConfig
+}
+
+// Validate reports every problem of the configuration.
+func (c Config) Validate() error {
+	var v common.Validation
+
+	v.Required("account name", c.AccountName)
+	v.Required("account key", string(c.AccountKey)+c.AccountKeyFile)
+	v.SecretFile("account key", c.AccountKey, c.AccountKeyFile)
+	v.Required("container name", c.ContainerName)
+	v.Required("blob storage url", c.BlobStorageURL)
+
+	// NOTICE: Blob storage URL is the host that the account name is prepended to, e.g. blob.core.windows.net.
+	if strings.Contains(c.BlobStorageURL, "://") {
+		v.Addf("blob storage url <%s> must be a host without scheme", c.BlobStorageURL)
+	} else {
+		v.Endpoint("blob storage url", c.BlobStorageURL)
+	}
+
+	if c.AccountKey != "" {
+		if _, err := base64.StdEncoding.DecodeString(string(c.AccountKey)); err != nil {
+			v.Addf("account key is not base64 encoded")
+		}
+	}
+
+	v.NonNegative("max retry requests", int64(c.MaxRetryRequests))
+	v.NonNegative("timeout", int64(c.Timeout))
+
+	v.Merge("transport", c.Transport.Validate())
+
+	return v.Err()
 }
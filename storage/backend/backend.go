@@ -1,20 +1,28 @@
 package backend
 
 import (
-	"context"
 	"errors"
 	"fmt"
 	"io"
-	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/meltwater/drone-cache/storage/backend/alioss"
 	"github.com/meltwater/drone-cache/storage/backend/azure"
+	"github.com/meltwater/drone-cache/storage/backend/fault"
 	"github.com/meltwater/drone-cache/storage/backend/filesystem"
+	"github.com/meltwater/drone-cache/storage/backend/ftp"
 	"github.com/meltwater/drone-cache/storage/backend/gcs"
+	"github.com/meltwater/drone-cache/storage/backend/gha"
+	"github.com/meltwater/drone-cache/storage/backend/http"
+	"github.com/meltwater/drone-cache/storage/backend/mirror"
+	"github.com/meltwater/drone-cache/storage/backend/oci"
+	"github.com/meltwater/drone-cache/storage/backend/redis"
 	"github.com/meltwater/drone-cache/storage/backend/s3"
 	"github.com/meltwater/drone-cache/storage/backend/sftp"
+	"github.com/meltwater/drone-cache/storage/backend/shard"
+	"github.com/meltwater/drone-cache/storage/backend/tiered"
+	"github.com/meltwater/drone-cache/storage/common"
 )
 
 const (
@@ -30,68 +38,230 @@ const (
 	SFTP = "sftp"
 	// OSS type of the corresponding backend represented as string constant.
 	AliOSS = "alioss"
+	// Tiered type of the corresponding backend represented as string constant.
+	Tiered = "tiered"
+	// Mirror type of the corresponding backend represented as string constant.
+	Mirror = "mirror"
+	// Shard type of the corresponding backend represented as string constant.
+	Shard = "shard"
+	// HTTP type of the corresponding backend represented as string constant.
+	HTTP = "http"
+	// OCI type of the corresponding backend represented as string constant.
+	OCI = "oci"
+	// Redis type of the corresponding backend represented as string constant.
+	Redis = "redis"
+	// GHA type of the corresponding backend represented as string constant.
+	GHA = "gha"
+	// FTP type of the corresponding backend represented as string constant.
+	FTP = "ftp"
+	// Fault type of the corresponding backend represented as string constant.
+	Fault = "fault"
 )
 
-// MOTICE: FileEntry needs a better place.
-
 // FileEntry defines a single cache item.
-type FileEntry struct {
-	Path         string
-	Size         int64
-	LastModified time.Time
-}
+type FileEntry = common.FileEntry
 
 // Backend implements operations for caching files.
-type Backend interface {
-	// Get writes downloaded content to the given writer.
-	Get(ctx context.Context, p string, w io.Writer) error
+type Backend = common.Backend
+
+// ErrUnknownBackend means that no backend is registered with the given type.
+var ErrUnknownBackend = errors.New("unknown backend")
+
+// nolint: gochecknoinits, funlen // Built-in backends are registered the same way as custom ones.
+func init() {
+	Register(Azure, func(l log.Logger, cfg Config) (Backend, error) {
+		level.Warn(l).Log("msg", "using azure blob as backend")
+
+		return azure.New(log.With(l, "backend", Azure), cfg.Azure) // nolint: wrapcheck
+	})
+	Register(S3, func(l log.Logger, cfg Config) (Backend, error) {
+		level.Warn(l).Log("msg", "using aws s3 as backend")
+
+		return s3.New(log.With(l, "backend", S3), cfg.S3, cfg.Debug) // nolint: wrapcheck
+	})
+	Register(GCS, func(l log.Logger, cfg Config) (Backend, error) {
+		level.Warn(l).Log("msg", "using gc storage as backend")
+
+		return gcs.New(log.With(l, "backend", GCS), cfg.GCS) // nolint: wrapcheck
+	})
+	Register(FileSystem, func(l log.Logger, cfg Config) (Backend, error) {
+		level.Warn(l).Log("msg", "using filesystem as backend")
+
+		return filesystem.New(log.With(l, "backend", FileSystem), cfg.FileSystem) // nolint: wrapcheck
+	})
+	Register(SFTP, func(l log.Logger, cfg Config) (Backend, error) {
+		level.Warn(l).Log("msg", "using sftp as backend")
+
+		return sftp.New(log.With(l, "backend", SFTP), cfg.SFTP) // nolint: wrapcheck
+	})
+	Register(FTP, func(l log.Logger, cfg Config) (Backend, error) {
+		level.Warn(l).Log("msg", "using ftp as backend")
+
+		return ftp.New(log.With(l, "backend", FTP), cfg.FTP) // nolint: wrapcheck
+	})
+	Register(AliOSS, func(l log.Logger, cfg Config) (Backend, error) {
+		level.Warn(l).Log("msg", "using Alibaba OSS storage as backend")
+
+		return alioss.New(log.With(l, "backend", AliOSS), cfg.Alioss, cfg.Debug) // nolint: wrapcheck
+	})
+	Register(HTTP, func(l log.Logger, cfg Config) (Backend, error) {
+		level.Warn(l).Log("msg", "using http server as backend")
 
-	// Put uploads contents of the given reader.
-	Put(ctx context.Context, p string, r io.Reader) error
+		return http.New(log.With(l, "backend", HTTP), cfg.HTTP) // nolint: wrapcheck
+	})
+	Register(OCI, func(l log.Logger, cfg Config) (Backend, error) {
+		level.Warn(l).Log("msg", "using oci registry as backend")
 
-	// Exists checks if path already exists.
-	Exists(ctx context.Context, p string) (bool, error)
+		return oci.New(log.With(l, "backend", OCI), cfg.OCI) // nolint: wrapcheck
+	})
+	Register(Redis, func(l log.Logger, cfg Config) (Backend, error) {
+		level.Warn(l).Log("msg", "using redis as backend")
 
-	// Implement me!
-	// List(ctx context.Context, p string) ([]FileEntry, error)
+		return redis.New(log.With(l, "backend", Redis), cfg.Redis) // nolint: wrapcheck
+	})
+	Register(GHA, func(l log.Logger, cfg Config) (Backend, error) {
+		level.Warn(l).Log("msg", "using github actions cache service as backend")
 
-	// Implement me!
-	// Delete(ctx context.Context, p string) error
+		return gha.New(log.With(l, "backend", GHA), cfg.GHA) // nolint: wrapcheck
+	})
+	Register(Tiered, newTiered)
+	Register(Fault, newFault)
+	Register(Mirror, newMirror)
+	Register(Shard, newShard)
 }
 
 // FromConfig creates new Backend by initializing  using given configuration.
 func FromConfig(l log.Logger, backedType string, cfg Config) (Backend, error) {
-	var (
-		b   Backend
-		err error
-	)
+	factory, ok := lookup(backedType)
+	if !ok {
+		return nil, fmt.Errorf("<%s>, %w", backedType, ErrUnknownBackend)
+	}
 
-	switch backedType {
-	case Azure:
-		level.Warn(l).Log("msg", "using azure blob as backend")
-		b, err = azure.New(log.With(l, "backend", Azure), cfg.Azure)
-	case S3:
-		level.Warn(l).Log("msg", "using aws s3 as backend")
-		b, err = s3.New(log.With(l, "backend", S3), cfg.S3, cfg.Debug)
-	case GCS:
-		level.Warn(l).Log("msg", "using gc storage as backend")
-		b, err = gcs.New(log.With(l, "backend", GCS), cfg.GCS)
-	case FileSystem:
-		level.Warn(l).Log("msg", "using filesystem as backend")
-		b, err = filesystem.New(log.With(l, "backend", FileSystem), cfg.FileSystem)
-	case SFTP:
-		level.Warn(l).Log("msg", "using sftp as backend")
-		b, err = sftp.New(log.With(l, "backend", SFTP), cfg.SFTP)
-	case AliOSS:
-		level.Warn(l).Log("msg", "using Alibaba OSS storage as backend")
-		b, err = alioss.New(log.With(l, "backend", AliOSS), cfg.Alioss, cfg.Debug)
-	default:
-		return nil, errors.New("unknown backend")
+	cfg = cfg.withTransport()
+
+	if err := validate(backedType, cfg); err != nil {
+		return nil, fmt.Errorf("validate configuration, %w", err)
 	}
 
+	b, err := factory(l, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("initialize backend, %w", err)
 	}
 
 	return b, nil
 }
+
+// IsComposite reports whether the given backend type consists of other backends.
+func IsComposite(backendType string) bool {
+	return backendType == Mirror || backendType == Shard
+}
+
+func fromSpec(l log.Logger, i int, spec Spec) (Backend, error) {
+	if IsComposite(spec.Type) {
+		return nil, fmt.Errorf("backend <%d>, composite backend <%s> can not be nested", i, spec.Type)
+	}
+
+	b, err := FromConfig(log.With(l, "index", i), spec.Type, spec.Config)
+	if err != nil {
+		return nil, fmt.Errorf("backend <%d>, %w", i, err)
+	}
+
+	return b, nil
+}
+
+// Close waits for pending operations and releases resources of the given backend, if it supports it.
+func Close(b Backend) error {
+	c, ok := b.(io.Closer)
+	if !ok {
+		return nil
+	}
+
+	if err := c.Close(); err != nil {
+		return fmt.Errorf("close backend, %w", err)
+	}
+
+	return nil
+}
+
+// Helpers
+
+func newTiered(l log.Logger, cfg Config) (Backend, error) {
+	level.Warn(l).Log("msg", "using local filesystem in front of remote as tiered backend", "remote", cfg.Tiered.Remote)
+
+	if cfg.Tiered.Remote == Tiered {
+		return nil, errors.New("tiered backend can not be used as its own remote")
+	}
+
+	remote, err := FromConfig(l, cfg.Tiered.Remote, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("initialize remote tier, %w", err)
+	}
+
+	return tiered.New(log.With(l, "backend", Tiered), cfg.Tiered, remote) // nolint: wrapcheck
+}
+
+func newMirror(l log.Logger, cfg Config) (Backend, error) {
+	level.Warn(l).Log("msg", "using mirror of multiple backends as backend", "backends", len(cfg.Backends))
+
+	// NOTICE: Misconfigured backends fail right away, whereas unavailable ones (e.g. of a regional outage) are kept
+	// as failed members, so that they count against the write quorum which counts all the configured backends.
+	backends := make([]Backend, 0, len(cfg.Backends))
+	available := 0
+
+	for i, spec := range cfg.Backends {
+		mb, err := fromSpec(l, i, spec)
+		if errors.Is(err, ErrInvalidConfig) || errors.Is(err, ErrUnknownBackend) {
+			return nil, fmt.Errorf("initialize mirrored backend, %w", err)
+		}
+
+		if err != nil {
+			level.Error(l).Log("msg", "mirrored backend is unavailable", "index", i, "err", err)
+
+			backends = append(backends, mirror.Unavailable(err))
+
+			continue
+		}
+
+		backends = append(backends, mb)
+		available++
+	}
+
+	if available == 0 && len(cfg.Backends) > 0 {
+		return nil, errors.New("none of the mirrored backends could be initialized")
+	}
+
+	return mirror.New(log.With(l, "backend", Mirror), cfg.Mirror, backends...) // nolint: wrapcheck
+}
+
+func newShard(l log.Logger, cfg Config) (Backend, error) {
+	level.Warn(l).Log("msg", "using shards of multiple backends as backend", "backends", len(cfg.Backends))
+
+	// NOTICE: Unlike mirror, every shard is required, otherwise objects would be remapped to other shards.
+	backends := make([]Backend, 0, len(cfg.Backends))
+
+	for i, spec := range cfg.Backends {
+		sb, err := fromSpec(l, i, spec)
+		if err != nil {
+			return nil, fmt.Errorf("initialize shard, %w", err)
+		}
+
+		backends = append(backends, sb)
+	}
+
+	return shard.New(log.With(l, "backend", Shard), cfg.Shard, backends...) // nolint: wrapcheck
+}
+
+func newFault(l log.Logger, cfg Config) (Backend, error) {
+	level.Warn(l).Log("msg", "using fault injection in front of backend, do not use it outside of tests", "backend", cfg.Fault.Backend)
+
+	if cfg.Fault.Backend == Fault {
+		return nil, errors.New("fault backend can not wrap itself")
+	}
+
+	b, err := FromConfig(l, cfg.Fault.Backend, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("initialize wrapped backend, %w", err)
+	}
+
+	return fault.New(log.With(l, "backend", Fault), cfg.Fault, b) // nolint: wrapcheck
+}
@@ -0,0 +1,302 @@
+// Package backendtest provides a conformance suite that every storage backend is expected to pass.
+//
+// Backends run it from their own tests:
+//
+//	func TestConformance(t *testing.T) {
+//		backendtest.Run(t, setup(t))
+//	}
+package backendtest
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"math/rand"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/meltwater/drone-cache/storage/common"
+	"github.com/meltwater/drone-cache/test"
+)
+
+const (
+	defaultLargeObjectSize = 16 << 20
+	defaultConcurrency     = 8
+)
+
+// Run checks the semantics of Get, Put, Exists, List and Delete of the given backend,
+// including missing objects, canceled contexts, large streams and concurrent access.
+// Getting missing objects must fail with common.ErrNotFound.
+func Run(t *testing.T, b common.Backend, opts ...Option) {
+	t.Helper()
+
+	o := options{
+		prefix:          "backendtest",
+		largeObjectSize: defaultLargeObjectSize,
+		concurrency:     defaultConcurrency,
+	}
+
+	for _, opt := range opts {
+		opt.apply(&o)
+	}
+
+	s := &suite{b: b, o: o}
+
+	t.Run("RoundTrip", s.roundTrip)
+	t.Run("Overwrite", s.overwrite)
+	t.Run("Missing", s.missing)
+	t.Run("List", s.list)
+	t.Run("Delete", s.delete)
+	t.Run("CanceledContext", s.canceledContext)
+	t.Run("LargeObject", s.largeObject)
+	t.Run("Concurrent", s.concurrent)
+}
+
+type suite struct {
+	b common.Backend
+	o options
+}
+
+func (s *suite) roundTrip(t *testing.T) {
+	p := s.path(t, "repo/key/mount")
+	content := "Hello world4"
+
+	test.Ok(t, s.b.Put(context.Background(), p, strings.NewReader(content)))
+	test.Equals(t, content, s.get(t, p))
+
+	exists, err := s.b.Exists(context.Background(), p)
+	test.Ok(t, err)
+	test.Equals(t, true, exists)
+
+	// Empty objects are valid objects.
+	p = s.path(t, "repo/key/empty")
+
+	test.Ok(t, s.b.Put(context.Background(), p, strings.NewReader("")))
+	test.Equals(t, "", s.get(t, p))
+}
+
+func (s *suite) overwrite(t *testing.T) {
+	if s.o.skipOverwrite {
+		t.Skip("backend does not support overwriting objects")
+	}
+
+	p := s.path(t, "repo/key/mount")
+
+	test.Ok(t, s.b.Put(context.Background(), p, strings.NewReader("first version")))
+	test.Ok(t, s.b.Put(context.Background(), p, strings.NewReader("second")))
+	test.Equals(t, "second", s.get(t, p))
+}
+
+func (s *suite) missing(t *testing.T) {
+	p := s.path(t, "repo/missing/mount")
+
+	var buf bytes.Buffer
+
+	test.Expected(t, s.b.Get(context.Background(), p, &buf), common.ErrNotFound)
+
+	exists, err := s.b.Exists(context.Background(), p)
+	test.Ok(t, err)
+	test.Equals(t, false, exists)
+}
+
+func (s *suite) list(t *testing.T) {
+	if s.o.skipList {
+		t.Skip("backend does not support listing objects")
+	}
+
+	root := s.path(t, "")
+	objects := map[string]string{
+		"repo/branch/a":       "a",
+		"repo/branch/b":       "bb",
+		"repo/other/a":        "ccc",
+		"repository/branch/a": "dddd",
+	}
+
+	for p, content := range objects {
+		test.Ok(t, s.b.Put(context.Background(), path.Join(root, p), strings.NewReader(content)))
+	}
+
+	entries := s.listPaths(t, path.Join(root, "repo/branch"))
+	test.Equals(t, []string{path.Join(root, "repo/branch/a"), path.Join(root, "repo/branch/b")}, paths(entries))
+	test.Equals(t, []int64{1, 2}, sizes(entries))
+
+	// Prefixes are directories, "repo" must not match "repository".
+	entries = s.listPaths(t, path.Join(root, "repo"))
+	test.Equals(t, 3, len(entries))
+
+	entries = s.listPaths(t, root)
+	test.Equals(t, len(objects), len(entries))
+
+	entries = s.listPaths(t, path.Join(root, "missing"))
+	test.Equals(t, 0, len(entries))
+}
+
+func (s *suite) delete(t *testing.T) {
+	if s.o.skipDelete {
+		t.Skip("backend does not support deleting objects")
+	}
+
+	p := s.path(t, "repo/key/mount")
+	other := s.path(t, "repo/key/other")
+
+	test.Ok(t, s.b.Put(context.Background(), p, strings.NewReader("Hello world4")))
+	test.Ok(t, s.b.Put(context.Background(), other, strings.NewReader("Hello world4")))
+	test.Ok(t, s.b.Delete(context.Background(), p))
+
+	exists, err := s.b.Exists(context.Background(), p)
+	test.Ok(t, err)
+	test.Equals(t, false, exists)
+
+	var buf bytes.Buffer
+	test.Expected(t, s.b.Get(context.Background(), p, &buf), common.ErrNotFound)
+
+	// Siblings are kept.
+	exists, err = s.b.Exists(context.Background(), other)
+	test.Ok(t, err)
+	test.Equals(t, true, exists)
+}
+
+func (s *suite) canceledContext(t *testing.T) {
+	p := s.path(t, "repo/key/mount")
+	test.Ok(t, s.b.Put(context.Background(), p, strings.NewReader("Hello world4")))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var buf bytes.Buffer
+
+	test.NotOk(t, s.b.Get(ctx, p, &buf))
+	test.NotOk(t, s.b.Put(ctx, s.path(t, "repo/key/canceled"), strings.NewReader("Hello world4")))
+}
+
+func (s *suite) largeObject(t *testing.T) {
+	p := s.path(t, "repo/key/large")
+
+	// The content is streamed from a generator, so the backend can not rely on the reader being in memory.
+	src := io.LimitReader(rand.New(rand.NewSource(s.o.largeObjectSize)), s.o.largeObjectSize) // #nosec G404 test data
+	want := sha256.New()
+
+	test.Ok(t, s.b.Put(context.Background(), p, io.TeeReader(src, want)))
+
+	got := sha256.New()
+	cw := &countingWriter{w: got}
+
+	test.Ok(t, s.b.Get(context.Background(), p, cw))
+	test.Equals(t, s.o.largeObjectSize, cw.n)
+	test.Equals(t, want.Sum(nil), got.Sum(nil))
+}
+
+func (s *suite) concurrent(t *testing.T) {
+	shared := s.path(t, "repo/shared/mount")
+	test.Ok(t, s.b.Put(context.Background(), shared, strings.NewReader("shared")))
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+
+	fail := func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}
+
+	for i := 0; i < s.o.concurrency; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			p := s.path(t, fmt.Sprintf("repo/key-%d/mount", i))
+			content := strings.Repeat(fmt.Sprintf("content-%d;", i), 1024) // nolint: gomnd
+
+			if err := s.b.Put(context.Background(), p, strings.NewReader(content)); err != nil {
+				fail(fmt.Errorf("put <%s>, %w", p, err))
+
+				return
+			}
+
+			for _, c := range []struct{ path, content string }{{p, content}, {shared, "shared"}} {
+				var buf bytes.Buffer
+				if err := s.b.Get(context.Background(), c.path, &buf); err != nil {
+					fail(fmt.Errorf("get <%s>, %w", c.path, err))
+
+					return
+				}
+
+				if buf.String() != c.content {
+					fail(fmt.Errorf("get <%s>, unexpected content of %d bytes", c.path, buf.Len()))
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	for _, err := range errs {
+		t.Error(err)
+	}
+}
+
+// Helpers
+
+// path returns a path unique to the running test, so that tests do not see objects of each other.
+func (s *suite) path(t *testing.T, p string) string {
+	return path.Join(s.o.prefix, strings.ReplaceAll(t.Name(), "/", "-"), p)
+}
+
+func (s *suite) get(t *testing.T, p string) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	test.Ok(t, s.b.Get(context.Background(), p, &buf))
+
+	return buf.String()
+}
+
+func (s *suite) listPaths(t *testing.T, p string) []common.FileEntry {
+	t.Helper()
+
+	entries, err := s.b.List(context.Background(), p)
+	test.Ok(t, err)
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+
+	return entries
+}
+
+func paths(entries []common.FileEntry) []string {
+	res := make([]string, 0, len(entries))
+	for _, e := range entries {
+		res = append(res, strings.TrimLeft(e.Path, "/"))
+	}
+
+	return res
+}
+
+func sizes(entries []common.FileEntry) []int64 {
+	res := make([]int64, 0, len(entries))
+	for _, e := range entries {
+		res = append(res, e.Size)
+	}
+
+	return res
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+
+	return n, err // nolint: wrapcheck
+}
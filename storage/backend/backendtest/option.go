@@ -0,0 +1,65 @@
+package backendtest
+
+// Option overrides behavior of the conformance suite.
+type Option interface {
+	apply(*options)
+}
+
+type optionFunc func(*options)
+
+func (f optionFunc) apply(o *options) {
+	f(o)
+}
+
+type options struct {
+	prefix          string
+	largeObjectSize int64
+	concurrency     int
+
+	skipList      bool
+	skipDelete    bool
+	skipOverwrite bool
+}
+
+// WithPrefix sets the prefix that all the objects of the suite are written under.
+// Backends that are shared between runs, such as real buckets, should use a unique prefix per run.
+func WithPrefix(prefix string) Option {
+	return optionFunc(func(o *options) {
+		o.prefix = prefix
+	})
+}
+
+// WithLargeObjectSize sets the size of the object that is streamed through the backend, in bytes.
+func WithLargeObjectSize(size int64) Option {
+	return optionFunc(func(o *options) {
+		o.largeObjectSize = size
+	})
+}
+
+// WithConcurrency sets the number of goroutines that use the backend at the same time.
+func WithConcurrency(n int) Option {
+	return optionFunc(func(o *options) {
+		o.concurrency = n
+	})
+}
+
+// WithoutList skips the checks of List, for backends which do not support listing.
+func WithoutList() Option {
+	return optionFunc(func(o *options) {
+		o.skipList = true
+	})
+}
+
+// WithoutDelete skips the checks of Delete, for backends which do not support deleting.
+func WithoutDelete() Option {
+	return optionFunc(func(o *options) {
+		o.skipDelete = true
+	})
+}
+
+// WithoutOverwrite skips the checks of overwriting objects, for backends with immutable objects.
+func WithoutOverwrite() Option {
+	return optionFunc(func(o *options) {
+		o.skipOverwrite = true
+	})
+}
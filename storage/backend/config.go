@@ -3,10 +3,20 @@ package backend
 import (
 	"github.com/meltwater/drone-cache/storage/backend/alioss"
 	"github.com/meltwater/drone-cache/storage/backend/azure"
+	"github.com/meltwater/drone-cache/storage/backend/fault"
 	"github.com/meltwater/drone-cache/storage/backend/filesystem"
+	"github.com/meltwater/drone-cache/storage/backend/ftp"
 	"github.com/meltwater/drone-cache/storage/backend/gcs"
+	"github.com/meltwater/drone-cache/storage/backend/gha"
+	"github.com/meltwater/drone-cache/storage/backend/http"
+	"github.com/meltwater/drone-cache/storage/backend/mirror"
+	"github.com/meltwater/drone-cache/storage/backend/oci"
+	"github.com/meltwater/drone-cache/storage/backend/redis"
 	"github.com/meltwater/drone-cache/storage/backend/s3"
 	"github.com/meltwater/drone-cache/storage/backend/sftp"
+	"github.com/meltwater/drone-cache/storage/backend/shard"
+	"github.com/meltwater/drone-cache/storage/backend/tiered"
+	"github.com/meltwater/drone-cache/storage/common"
 )
 
 // Config configures behavior of Backend.
@@ -16,7 +26,48 @@ type Config struct {
 	S3         s3.Config
 	FileSystem filesystem.Config
 	SFTP       sftp.Config
+	FTP        ftp.Config
 	Azure      azure.Config
 	GCS        gcs.Config
 	Alioss     alioss.Config
+	HTTP       http.Config
+	OCI        oci.Config
+	Redis      redis.Config
+	GHA        gha.Config
+	Tiered     tiered.Config
+	Mirror     mirror.Config
+	Shard      shard.Config
+	Fault      fault.Config
+
+	// Backends configures the backends that a composite backend (e.g. mirror, shard) consists of, in priority order.
+	Backends []Spec
+
+	// Settings configures the backends registered with RegisterTyped.
+	Settings map[string]string
+
+	// Transport configures the HTTP transport of all backends that speak HTTP, unless they have their own.
+	Transport common.TransportConfig
+}
+
+// Spec is a structure to store a backend type along with its configuration.
+type Spec struct {
+	Type   string
+	Config Config
+}
+
+// Helpers
+
+// withTransport returns the configuration with the shared transport configuration set on the backends that speak HTTP,
+// unless they have their own.
+func (c Config) withTransport() Config {
+	for _, t := range []*common.TransportConfig{
+		&c.S3.Transport, &c.GCS.Transport, &c.Azure.Transport, &c.Alioss.Transport,
+		&c.HTTP.Transport, &c.OCI.Transport, &c.GHA.Transport,
+	} {
+		if t.IsZero() {
+			*t = c.Transport
+		}
+	}
+
+	return c
 }
@@ -0,0 +1,68 @@
+package fault
+
+import (
+	"time"
+
+	"github.com/meltwater/drone-cache/storage/common"
+)
+
+// Operations of the backend that faults can be injected into.
+const (
+	OpGet    = "get"
+	OpPut    = "put"
+	OpExists = "exists"
+	OpList   = "list"
+	OpDelete = "delete"
+)
+
+// Config is a structure to store fault injecting backend configuration.
+type Config struct {
+	// Backend is the type of the wrapped backend, e.g. s3.
+	Backend string
+	// Seed seeds the random faults, the same seed injects the same faults for the same sequence of operations.
+	Seed int64
+
+	// Latency delays every operation.
+	Latency time.Duration
+	// Jitter adds a random delay up to the given duration on top of Latency.
+	Jitter time.Duration
+
+	// ErrorRate is the probability of an operation failing before it reaches the wrapped backend, between 0 and 1.
+	ErrorRate float64
+	// Operations are the operations that ErrorRate applies to, all of them when empty.
+	Operations []string
+
+	// FailAfter fails downloads and uploads after the given number of bytes. Zero disables it.
+	FailAfter int64
+	// TruncateAfter silently drops downloaded content after the given number of bytes. Zero disables it.
+	TruncateAfter int64
+
+	// DripSize transfers downloads and uploads in chunks of the given number of bytes. Zero disables it.
+	DripSize int
+	// DripInterval pauses between the chunks of DripSize.
+	DripInterval time.Duration
+}
+
+// Validate reports every problem of the configuration, the wrapped backend is validated by its own configuration.
+func (c Config) Validate() error {
+	var v common.Validation
+
+	v.Required("backend", c.Backend)
+
+	if c.ErrorRate < 0 || c.ErrorRate > 1 {
+		v.Addf("error rate <%g> must be between 0 and 1", c.ErrorRate)
+	}
+
+	for _, op := range c.Operations {
+		v.OneOf("operation", op, OpGet, OpPut, OpExists, OpList, OpDelete)
+	}
+
+	v.NonNegative("latency", int64(c.Latency))
+	v.NonNegative("jitter", int64(c.Jitter))
+	v.NonNegative("fail after", c.FailAfter)
+	v.NonNegative("truncate after", c.TruncateAfter)
+	v.NonNegative("drip size", int64(c.DripSize))
+	v.NonNegative("drip interval", int64(c.DripInterval))
+
+	return v.Err()
+}
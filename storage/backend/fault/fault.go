@@ -0,0 +1,255 @@
+// Package fault provides a Backend that injects faults into another backend, to test how caching behaves
+// when the storage misbehaves.
+package fault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+
+	"github.com/meltwater/drone-cache/storage/common"
+)
+
+// ErrInjected is returned for the injected faults.
+var ErrInjected = errors.New("injected fault")
+
+// Backend wraps a backend and injects latency, errors, truncated and slow transfers into its operations.
+type Backend struct {
+	logger log.Logger
+
+	backend common.Backend
+	c       Config
+	ops     map[string]bool
+
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// New creates a fault injecting backend in front of the given backend.
+func New(l log.Logger, c Config, b common.Backend) (*Backend, error) {
+	if c.ErrorRate < 0 || c.ErrorRate > 1 {
+		return nil, fmt.Errorf("error rate <%v> must be between 0 and 1", c.ErrorRate)
+	}
+
+	ops := map[string]bool{}
+
+	for _, op := range c.Operations {
+		switch op {
+		case OpGet, OpPut, OpExists, OpList, OpDelete:
+			ops[op] = true
+		default:
+			return nil, fmt.Errorf("unknown operation <%s>", op)
+		}
+	}
+
+	level.Warn(l).Log("msg", "injecting faults into backend", "seed", c.Seed, "error_rate", c.ErrorRate, "latency", c.Latency)
+
+	return &Backend{
+		logger:  l,
+		backend: b,
+		c:       c,
+		ops:     ops,
+		rnd:     rand.New(rand.NewSource(c.Seed)), // #nosec G404 faults are meant to be reproducible
+	}, nil
+}
+
+// Get writes downloaded content to the given writer.
+func (b *Backend) Get(ctx context.Context, p string, w io.Writer) error {
+	if err := b.inject(ctx, OpGet, p); err != nil {
+		return err
+	}
+
+	return b.backend.Get(ctx, p, &writer{ctx: ctx, w: w, c: b.c}) // nolint: wrapcheck
+}
+
+// Put uploads contents of the given reader.
+func (b *Backend) Put(ctx context.Context, p string, r io.Reader) error {
+	if err := b.inject(ctx, OpPut, p); err != nil {
+		return err
+	}
+
+	return b.backend.Put(ctx, p, &reader{ctx: ctx, r: r, c: b.c}) // nolint: wrapcheck
+}
+
+// Exists checks if object already exists.
+func (b *Backend) Exists(ctx context.Context, p string) (bool, error) {
+	if err := b.inject(ctx, OpExists, p); err != nil {
+		return false, err
+	}
+
+	return b.backend.Exists(ctx, p) // nolint: wrapcheck
+}
+
+// List lists all the objects that are the given path or under it, recursively.
+func (b *Backend) List(ctx context.Context, p string) ([]common.FileEntry, error) {
+	if err := b.inject(ctx, OpList, p); err != nil {
+		return nil, err
+	}
+
+	return b.backend.List(ctx, p) // nolint: wrapcheck
+}
+
+// Delete deletes the object with the given path.
+func (b *Backend) Delete(ctx context.Context, p string) error {
+	if err := b.inject(ctx, OpDelete, p); err != nil {
+		return err
+	}
+
+	return b.backend.Delete(ctx, p) // nolint: wrapcheck
+}
+
+// Close closes the wrapped backend, if it supports it.
+func (b *Backend) Close() error {
+	if c, ok := b.backend.(io.Closer); ok {
+		return c.Close() // nolint: wrapcheck
+	}
+
+	return nil
+}
+
+// Helpers
+
+// inject delays the operation and decides whether it fails.
+func (b *Backend) inject(ctx context.Context, op, p string) error {
+	b.mu.Lock()
+	delay := b.c.Latency
+	if b.c.Jitter > 0 {
+		delay += time.Duration(b.rnd.Int63n(int64(b.c.Jitter)))
+	}
+
+	fail := b.c.ErrorRate > 0 && (len(b.ops) == 0 || b.ops[op]) && b.rnd.Float64() < b.c.ErrorRate
+	b.mu.Unlock()
+
+	if err := sleep(ctx, delay); err != nil {
+		return err
+	}
+
+	if fail {
+		level.Debug(b.logger).Log("msg", "injecting error", "op", op, "path", p)
+
+		return fmt.Errorf("%s <%s>, %w", op, p, ErrInjected)
+	}
+
+	return nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err() // nolint: wrapcheck
+	}
+}
+
+// reader injects faults into uploads.
+type reader struct {
+	ctx context.Context // nolint: containedctx
+	r   io.Reader
+	c   Config
+	n   int64
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if r.c.FailAfter > 0 && r.n >= r.c.FailAfter {
+		return 0, fmt.Errorf("upload after %d bytes, %w", r.n, ErrInjected)
+	}
+
+	p = r.limit(p)
+
+	if r.n > 0 {
+		if err := sleep(r.ctx, r.c.DripInterval); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+
+	return n, err // nolint: wrapcheck
+}
+
+func (r *reader) limit(p []byte) []byte {
+	if r.c.DripSize > 0 && len(p) > r.c.DripSize {
+		p = p[:r.c.DripSize]
+	}
+
+	if r.c.FailAfter > 0 && int64(len(p)) > r.c.FailAfter-r.n {
+		p = p[:r.c.FailAfter-r.n]
+	}
+
+	return p
+}
+
+// writer injects faults into downloads.
+type writer struct {
+	ctx context.Context // nolint: containedctx
+	w   io.Writer
+	c   Config
+	n   int64
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	written := 0
+
+	for len(p) > 0 {
+		chunk := p
+		if w.c.DripSize > 0 && len(chunk) > w.c.DripSize {
+			chunk = chunk[:w.c.DripSize]
+		}
+
+		if w.c.FailAfter > 0 && w.n+int64(len(chunk)) > w.c.FailAfter {
+			chunk = chunk[:w.c.FailAfter-w.n]
+			if len(chunk) == 0 {
+				return written, fmt.Errorf("download after %d bytes, %w", w.n, ErrInjected)
+			}
+		}
+
+		if w.n > 0 {
+			if err := sleep(w.ctx, w.c.DripInterval); err != nil {
+				return written, err
+			}
+		}
+
+		if err := w.write(chunk); err != nil {
+			return written, err
+		}
+
+		w.n += int64(len(chunk))
+		written += len(chunk)
+		p = p[len(chunk):]
+	}
+
+	return written, nil
+}
+
+// write writes the chunk, dropping the content beyond TruncateAfter as if it was never sent.
+func (w *writer) write(chunk []byte) error {
+	if w.c.TruncateAfter > 0 {
+		if w.n >= w.c.TruncateAfter {
+			return nil
+		}
+
+		if w.n+int64(len(chunk)) > w.c.TruncateAfter {
+			chunk = chunk[:w.c.TruncateAfter-w.n]
+		}
+	}
+
+	_, err := w.w.Write(chunk)
+
+	return err // nolint: wrapcheck
+}
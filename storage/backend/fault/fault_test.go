@@ -0,0 +1,141 @@
+package fault
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/meltwater/drone-cache/storage/backend/backendtest"
+	"github.com/meltwater/drone-cache/storage/backend/memory"
+	"github.com/meltwater/drone-cache/test"
+)
+
+const content = "Hello world4"
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	backend, _ := setup(t, Config{})
+
+	backendtest.Run(t, backend)
+}
+
+func TestFailAfter(t *testing.T) {
+	t.Parallel()
+
+	backend, inner := setup(t, Config{FailAfter: 5})
+
+	test.Expected(t, backend.Put(context.TODO(), "repo/key", strings.NewReader(content)), ErrInjected)
+
+	exists, err := inner.Exists(context.TODO(), "repo/key")
+	test.Ok(t, err)
+	test.Equals(t, false, exists)
+
+	test.Ok(t, inner.Put(context.TODO(), "repo/key", strings.NewReader(content)))
+
+	var buf bytes.Buffer
+	test.Expected(t, backend.Get(context.TODO(), "repo/key", &buf), ErrInjected)
+	test.Equals(t, content[:5], buf.String())
+}
+
+func TestTruncateAfter(t *testing.T) {
+	t.Parallel()
+
+	backend, _ := setup(t, Config{TruncateAfter: 5})
+
+	test.Ok(t, backend.Put(context.TODO(), "repo/key", strings.NewReader(content)))
+
+	var buf bytes.Buffer
+	test.Ok(t, backend.Get(context.TODO(), "repo/key", &buf))
+	test.Equals(t, content[:5], buf.String())
+}
+
+func TestDrip(t *testing.T) {
+	t.Parallel()
+
+	backend, _ := setup(t, Config{DripSize: 4, DripInterval: 10 * time.Millisecond})
+
+	now := time.Now()
+
+	test.Ok(t, backend.Put(context.TODO(), "repo/key", strings.NewReader(content)))
+
+	var buf bytes.Buffer
+	test.Ok(t, backend.Get(context.TODO(), "repo/key", &buf))
+	test.Equals(t, content, buf.String())
+
+	// Both transfers pause twice between their three chunks.
+	test.Assert(t, time.Since(now) >= 40*time.Millisecond, "transfers are not slowed down: %v", time.Since(now))
+}
+
+func TestErrorRate(t *testing.T) {
+	t.Parallel()
+
+	failures := func(seed int64) []bool {
+		backend, _ := setup(t, Config{Seed: seed, ErrorRate: 0.5, Operations: []string{OpExists}})
+
+		var res []bool
+
+		for i := 0; i < 32; i++ {
+			_, err := backend.Exists(context.TODO(), "repo/key")
+			res = append(res, errors.Is(err, ErrInjected))
+
+			// Other operations are not affected.
+			test.Ok(t, backend.Put(context.TODO(), "repo/key", strings.NewReader(content)))
+		}
+
+		return res
+	}
+
+	first := failures(42)
+	test.Equals(t, first, failures(42))
+
+	failed := 0
+
+	for _, f := range first {
+		if f {
+			failed++
+		}
+	}
+
+	test.Assert(t, failed > 0 && failed < len(first), "expected some operations to fail and others to succeed: %v", first)
+}
+
+func TestLatency(t *testing.T) {
+	t.Parallel()
+
+	backend, _ := setup(t, Config{Latency: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := backend.Exists(ctx, "repo/key")
+	test.Expected(t, err, context.DeadlineExceeded)
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range []Config{
+		{ErrorRate: 2},
+		{Operations: []string{"copy"}},
+	} {
+		_, err := New(log.NewNopLogger(), c, memory.New())
+		test.NotOk(t, err)
+	}
+}
+
+// Helpers
+
+func setup(t *testing.T, c Config) (*Backend, *memory.Backend) {
+	inner := memory.New()
+
+	b, err := New(log.NewNopLogger(), c, inner)
+	test.Ok(t, err)
+
+	return b, inner
+}
@@ -1,6 +1,23 @@
 package filesystem
 
+import (
+	"path/filepath"
+
+	"github.com/meltwater/drone-cache/storage/common"
+)
+
 // Config is a structure to store filesystem backend configuration.
 type Config struct {
 	CacheRoot string
 }
+
+// Validate reports every problem of the configuration.
+func (c Config) Validate() error {
+	var v common.Validation
+
+	if c.CacheRoot == "" || filepath.Clean(c.CacheRoot) == "/" {
+		v.Addf("cache root <%s> must not be empty or root", c.CacheRoot)
+	}
+
+	return v.Err()
+}
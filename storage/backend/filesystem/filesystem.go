@@ -8,13 +8,21 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/meltwater/drone-cache/internal"
+	"github.com/meltwater/drone-cache/storage/common"
 )
 
-const defaultFileMode = 0o755
+const (
+	defaultFileMode   = 0o755
+	defaultObjectMode = 0o644
+
+	// tempPattern names the files that objects are written to before they are renamed into place.
+	tempPattern = ".*.tmp"
+)
 
 // Backend is an file system implementation of the Backend.
 type Backend struct {
@@ -45,14 +53,19 @@ func (b *Backend) Get(ctx context.Context, p string, w io.Writer) error {
 		return fmt.Errorf("absolute path, %w", err)
 	}
 
-	errCh := make(chan error)
+	if err := ctx.Err(); err != nil {
+		return err // nolint: wrapcheck
+	}
+
+	// NOTICE: Buffered, so that the goroutine does not leak when the context is done first.
+	errCh := make(chan error, 1)
 
 	go func() {
 		defer close(errCh)
 
 		rc, err := os.Open(path)
 		if err != nil {
-			errCh <- fmt.Errorf("get the object, %w", err)
+			errCh <- fmt.Errorf("get the object, %w", common.Classify(err))
 
 			return
 		}
@@ -61,7 +74,7 @@ func (b *Backend) Get(ctx context.Context, p string, w io.Writer) error {
 
 		_, err = io.Copy(w, rc)
 		if err != nil {
-			errCh <- fmt.Errorf("copy the object, %w", err)
+			errCh <- fmt.Errorf("copy the object, %w", common.Classify(err))
 
 			return
 		}
@@ -77,37 +90,32 @@ func (b *Backend) Get(ctx context.Context, p string, w io.Writer) error {
 }
 
 // Put uploads contents of the given reader.
+// Objects are written to a temporary file in the same directory and renamed into place once complete,
+// so that other readers of the same directory, e.g. other builds, never see partial objects.
 func (b *Backend) Put(ctx context.Context, p string, r io.Reader) error {
 	path, err := filepath.Abs(filepath.Clean(filepath.Join(b.cacheRoot, p)))
 	if err != nil {
 		return fmt.Errorf("build path, %w", err)
 	}
 
-	errCh := make(chan error)
+	if err := ctx.Err(); err != nil {
+		return err // nolint: wrapcheck
+	}
+
+	errCh := make(chan error, 1)
 
 	go func() {
 		defer close(errCh)
 
 		dir := filepath.Dir(path)
 		if err := os.MkdirAll(dir, os.FileMode(defaultFileMode)); err != nil {
-			errCh <- fmt.Errorf("create directory, %w", err)
-		}
-
-		w, err := os.Create(path)
-		if err != nil {
-			errCh <- fmt.Errorf("create cache file, %w", err)
+			errCh <- fmt.Errorf("create directory, %w", common.Classify(err))
 
 			return
 		}
 
-		defer internal.CloseWithErrLogf(b.logger, w, "file writer, close defer")
-
-		if _, err := io.Copy(w, r); err != nil {
-			errCh <- fmt.Errorf("write contents of reader to a file, %w", err)
-		}
-
-		if err := w.Close(); err != nil {
-			errCh <- fmt.Errorf("close the object, %w", err)
+		if err := b.write(ctx, path, r); err != nil {
+			errCh <- err
 		}
 	}()
 
@@ -129,8 +137,146 @@ func (b *Backend) Exists(ctx context.Context, p string) (bool, error) {
 
 	_, err = os.Stat(path)
 	if err != nil && !os.IsNotExist(err) {
-		return false, fmt.Errorf("check the object exists, %w", err)
+		return false, fmt.Errorf("check the object exists, %w", common.Classify(err))
 	}
 
 	return err == nil, nil
 }
+
+// List lists all the objects that are the given path or under it, recursively.
+func (b *Backend) List(ctx context.Context, p string) ([]common.FileEntry, error) {
+	root, err := filepath.Abs(filepath.Clean(b.cacheRoot))
+	if err != nil {
+		return nil, fmt.Errorf("absolute path, %w", err)
+	}
+
+	var entries []common.FileEntry
+
+	err = filepath.Walk(filepath.Join(root, p), func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err() // nolint: wrapcheck
+		}
+
+		if fi.IsDir() || isTemp(fi.Name()) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return fmt.Errorf("relative path, %w", err)
+		}
+
+		entries = append(entries, common.FileEntry{
+			Path:         filepath.ToSlash(rel),
+			Size:         fi.Size(),
+			LastModified: fi.ModTime(),
+		})
+
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("walk the objects, %w", common.Classify(err))
+	}
+
+	return entries, nil
+}
+
+// Delete deletes the object with the given path.
+func (b *Backend) Delete(ctx context.Context, p string) error {
+	path, err := filepath.Abs(filepath.Clean(filepath.Join(b.cacheRoot, p)))
+	if err != nil {
+		return fmt.Errorf("absolute path, %w", err)
+	}
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("delete the object, %w", common.Classify(err))
+	}
+
+	return nil
+}
+
+// RemoveTemp removes the temporary files of writes that have not changed for the given duration,
+// e.g. left behind by a process that was killed while writing.
+func (b *Backend) RemoveTemp(olderThan time.Duration) error {
+	err := filepath.Walk(b.cacheRoot, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if fi.IsDir() || !isTemp(fi.Name()) || time.Since(fi.ModTime()) < olderThan {
+			return nil
+		}
+
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove temporary file <%s>, %w", path, err)
+		}
+
+		level.Debug(b.logger).Log("msg", "removed stale temporary file", "path", path)
+
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("walk the temporary files, %w", common.Classify(err))
+	}
+
+	return nil
+}
+
+// Helpers
+
+// write writes the contents of the reader to a temporary file, and renames it to the given path once it is synced.
+func (b *Backend) write(ctx context.Context, path string, r io.Reader) error {
+	w, err := os.CreateTemp(filepath.Dir(path), strings.Replace(tempPattern, "*", filepath.Base(path)+".*", 1))
+	if err != nil {
+		return fmt.Errorf("create temporary file, %w", common.Classify(err))
+	}
+
+	tmp := w.Name()
+
+	defer func() {
+		if err := os.Remove(tmp); err != nil && !os.IsNotExist(err) {
+			level.Warn(b.logger).Log("msg", "remove temporary file", "path", tmp, "err", err)
+		}
+	}()
+
+	defer internal.CloseWithErrLogf(b.logger, w, "file writer, close defer")
+
+	// NOTICE: Temporary files are only readable by their owner, objects are readable by the other builds as before.
+	if err := w.Chmod(defaultObjectMode); err != nil {
+		return fmt.Errorf("change mode of temporary file, %w", common.Classify(err))
+	}
+
+	if _, err := io.Copy(w, r); err != nil {
+		return fmt.Errorf("write contents of reader to a file, %w", common.Classify(err))
+	}
+
+	if err := w.Sync(); err != nil {
+		return fmt.Errorf("sync the object, %w", common.Classify(err))
+	}
+
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("close the object, %w", common.Classify(err))
+	}
+
+	// NOTICE: Abandoned writes must not replace the object, the caller has already given up on them.
+	if err := ctx.Err(); err != nil {
+		return err // nolint: wrapcheck
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("rename the object, %w", common.Classify(err))
+	}
+
+	return nil
+}
+
+// isTemp reports whether the file of the given name is a temporary file of a write.
+func isTemp(name string) bool {
+	ok, _ := filepath.Match(tempPattern, name)
+
+	return ok
+}
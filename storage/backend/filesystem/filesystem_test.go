@@ -3,11 +3,18 @@ package filesystem
 import (
 	"bytes"
 	"context"
+	"errors"
+	"io"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
 
 	"github.com/go-kit/log"
+	"github.com/meltwater/drone-cache/storage/backend/backendtest"
 	"github.com/meltwater/drone-cache/test"
 )
 
@@ -37,6 +44,90 @@ func TestRoundTrip(t *testing.T) {
 	test.Equals(t, true, exists)
 }
 
+func TestListDelete(t *testing.T) {
+	t.Parallel()
+
+	backend, cleanUp := setup(t)
+	t.Cleanup(cleanUp)
+
+	for _, p := range []string{"repo/branch/a", "repo/branch/b", "repo/other/a", "repository/branch/a"} {
+		test.Ok(t, backend.Put(context.TODO(), p, strings.NewReader(p)))
+	}
+
+	entries, err := backend.List(context.TODO(), "repo/branch")
+	test.Ok(t, err)
+	test.Equals(t, 2, len(entries))
+	test.Equals(t, "repo/branch/a", entries[0].Path)
+	test.Equals(t, int64(len("repo/branch/a")), entries[0].Size)
+
+	entries, err = backend.List(context.TODO(), "missing")
+	test.Ok(t, err)
+	test.Equals(t, 0, len(entries))
+
+	test.Ok(t, backend.Delete(context.TODO(), "repo/branch/a"))
+
+	exists, err := backend.Exists(context.TODO(), "repo/branch/a")
+	test.Ok(t, err)
+	test.Equals(t, false, exists)
+}
+
+func TestPutInterrupted(t *testing.T) {
+	t.Parallel()
+
+	backend, cleanUp := setup(t)
+	t.Cleanup(cleanUp)
+
+	test.Ok(t, backend.Put(context.TODO(), "repo/key/test.t", strings.NewReader("Hello world4")))
+
+	r := io.MultiReader(strings.NewReader("Hello"), iotest.ErrReader(errors.New("connection reset")))
+	test.NotOk(t, backend.Put(context.TODO(), "repo/key/test.t", r))
+	test.NotOk(t, backend.Put(context.TODO(), "repo/key/other.t", r))
+
+	// Objects are replaced only by complete writes, and interrupted writes leave nothing behind.
+	var buf bytes.Buffer
+	test.Ok(t, backend.Get(context.TODO(), "repo/key/test.t", &buf))
+	test.Equals(t, "Hello world4", buf.String())
+
+	files, err := os.ReadDir(filepath.Join(backend.cacheRoot, "repo/key"))
+	test.Ok(t, err)
+	test.Equals(t, 1, len(files))
+}
+
+func TestRemoveTemp(t *testing.T) {
+	t.Parallel()
+
+	backend, cleanUp := setup(t)
+	t.Cleanup(cleanUp)
+
+	test.Ok(t, backend.Put(context.TODO(), "repo/key/test.t", strings.NewReader("Hello world4")))
+
+	stale := filepath.Join(backend.cacheRoot, "repo/key/.test.t.123.tmp")
+	fresh := filepath.Join(backend.cacheRoot, "repo/key/.test.t.456.tmp")
+	test.Ok(t, os.WriteFile(stale, []byte("Hello"), 0o600))
+	test.Ok(t, os.WriteFile(fresh, []byte("Hello"), 0o600))
+	test.Ok(t, os.Chtimes(stale, time.Now().Add(-2*time.Hour), time.Now().Add(-2*time.Hour)))
+
+	// Temporary files are not objects.
+	entries, err := backend.List(context.TODO(), "repo")
+	test.Ok(t, err)
+	test.Equals(t, 1, len(entries))
+
+	test.Ok(t, backend.RemoveTemp(time.Hour))
+
+	_, err = os.Stat(stale)
+	test.Assert(t, os.IsNotExist(err), "stale temporary file is not removed: %v", err)
+	test.Exists(t, fresh)
+}
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	backend, cleanUp := setup(t)
+	t.Cleanup(cleanUp)
+
+	backendtest.Run(t, backend)
+}
+
 // Helpers
 
 func setup(t *testing.T) (*Backend, func()) {
@@ -0,0 +1,54 @@
+package ftp
+
+import (
+	"time"
+
+	"github.com/meltwater/drone-cache/storage/common"
+)
+
+// TLSMode describes how the connection to the server is secured.
+type TLSMode string
+
+const (
+	// TLSNone uses plain FTP.
+	TLSNone TLSMode = ""
+	// TLSExplicit upgrades the connection with AUTH TLS, usually on port 21.
+	TLSExplicit TLSMode = "explicit"
+	// TLSImplicit connects with TLS from the start, usually on port 990.
+	TLSImplicit TLSMode = "implicit"
+)
+
+// Config is a structure to store FTP backend configuration.
+type Config struct {
+	CacheRoot string
+	Username  string
+	Password  common.Secret
+	// PasswordFile is a file to read the password from, instead of Password. It is read again for every connection.
+	PasswordFile string
+	Host         string
+	Port         string
+	TLS          TLSMode
+	Timeout      time.Duration
+
+	// SkipVerify skips verification of the certificate of the server.
+	SkipVerify bool
+	// DisableEPSV falls back to PASV for servers behind NATs, which advertise EPSV but can not handle it.
+	DisableEPSV bool
+	// MaxConnections caps the connections opened to the server, which are reused across operations.
+	MaxConnections int
+}
+
+// Validate reports every problem of the configuration.
+func (c Config) Validate() error {
+	var v common.Validation
+
+	v.Required("host", c.Host)
+	v.Port("port", c.Port)
+	v.OneOf("tls mode", string(c.TLS), string(TLSNone), string(TLSExplicit), string(TLSImplicit))
+	v.Together("username", "password", c.Username != "", c.Password != "" || c.PasswordFile != "")
+	v.SecretFile("password", c.Password, c.PasswordFile)
+	v.NonNegative("max connections", int64(c.MaxConnections))
+	v.NonNegative("timeout", int64(c.Timeout))
+
+	return v.Err()
+}
@@ -0,0 +1,453 @@
+package ftp
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	goftp "github.com/jlaffaye/ftp"
+
+	"github.com/meltwater/drone-cache/storage/common"
+)
+
+const (
+	// DefaultMaxConnections is the default maximum number of connections opened to the server.
+	DefaultMaxConnections = 4
+
+	defaultTimeout = 30 * time.Second
+)
+
+// Backend implements storage.Backend for FTP and FTPS.
+type Backend struct {
+	logger log.Logger
+
+	cacheRoot string
+	pool      *pool
+
+	// dirs keeps the directories already created, to skip recreating them for every object.
+	dirs sync.Map
+}
+
+// New creates a new FTP backend.
+func New(l log.Logger, c Config) (*Backend, error) {
+	if c.Host == "" {
+		return nil, errors.New("ftp host is required")
+	}
+
+	if c.TLS != TLSNone && c.TLS != TLSExplicit && c.TLS != TLSImplicit {
+		return nil, fmt.Errorf("unknown tls mode <%s>, expected one of <%s>, <%s>", c.TLS, TLSExplicit, TLSImplicit)
+	}
+
+	port := c.Port
+	if port == "" {
+		port = "21"
+		if c.TLS == TLSImplicit {
+			port = "990"
+		}
+	}
+
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	max := c.MaxConnections
+	if max <= 0 {
+		max = DefaultMaxConnections
+	}
+
+	password, err := common.NewSecretFile(c.Password, c.PasswordFile)
+	if err != nil {
+		return nil, fmt.Errorf("password, %w", err)
+	}
+
+	opts := []goftp.DialOption{
+		goftp.DialWithTimeout(timeout),
+		goftp.DialWithDisabledEPSV(c.DisableEPSV),
+	}
+
+	if c.TLS != TLSNone {
+		cfg := &tls.Config{
+			ServerName:         c.Host,
+			InsecureSkipVerify: c.SkipVerify, // #nosec G402 opt-in for self-signed drop servers
+			MinVersion:         tls.VersionTLS12,
+			// NOTICE: Most servers require data connections to resume the TLS session of the control connection.
+			ClientSessionCache: tls.NewLRUClientSessionCache(max),
+		}
+
+		if c.TLS == TLSImplicit {
+			opts = append(opts, goftp.DialWithTLS(cfg))
+		} else {
+			opts = append(opts, goftp.DialWithExplicitTLS(cfg))
+		}
+	}
+
+	p := &pool{
+		addr:     net.JoinHostPort(c.Host, port),
+		username: c.Username,
+		password: password,
+		options:  opts,
+		sem:      make(chan struct{}, max),
+	}
+
+	// Fail early on unreachable servers and wrong credentials, and check the cache root.
+	conn, err := p.get(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = conn.List(c.CacheRoot)
+	p.put(conn, err)
+
+	if err != nil {
+		return nil, fmt.Errorf("make sure cache root <%s> created, %w", c.CacheRoot, err)
+	}
+
+	level.Debug(l).Log("msg", "ftp backend", "addr", p.addr, "tls", c.TLS, "cache_root", c.CacheRoot, "max_connections", max)
+
+	return &Backend{logger: l, cacheRoot: c.CacheRoot, pool: p}, nil
+}
+
+// Get writes downloaded content to the given writer.
+func (b *Backend) Get(ctx context.Context, p string, w io.Writer) error {
+	return b.do(ctx, func(c *goftp.ServerConn) error {
+		resp, err := c.Retr(b.path(p))
+		if err != nil {
+			return fmt.Errorf("get the object, %w", classify(err))
+		}
+
+		_, err = io.Copy(w, resp)
+
+		// NOTICE: Response must always be closed to read the final reply, before the connection can be reused.
+		if cerr := resp.Close(); cerr != nil && err == nil {
+			return fmt.Errorf("finish the download, %w", classify(cerr))
+		}
+
+		if err != nil {
+			return fmt.Errorf("copy the object, %w", classify(err))
+		}
+
+		return nil
+	})
+}
+
+// Put uploads contents of the given reader.
+// Objects are uploaded under a temporary name and renamed, so that readers never see partial uploads.
+func (b *Backend) Put(ctx context.Context, p string, r io.Reader) error {
+	return b.do(ctx, func(c *goftp.ServerConn) error {
+		dst := b.path(p)
+		b.makeDirs(c, path.Dir(dst))
+
+		tmp := dst + ".tmp-" + strconv.FormatInt(time.Now().UnixNano(), 36) // nolint: gomnd
+		if err := c.Stor(tmp, r); err != nil {
+			if derr := c.Delete(tmp); derr != nil {
+				level.Debug(b.logger).Log("msg", "delete partial upload", "path", tmp, "err", derr)
+			}
+
+			return fmt.Errorf("put the object, %w", classify(err))
+		}
+
+		if err := c.Rename(tmp, dst); err != nil {
+			return fmt.Errorf("rename the object, %w", classify(err))
+		}
+
+		return nil
+	})
+}
+
+// Exists checks if path already exists.
+func (b *Backend) Exists(ctx context.Context, p string) (bool, error) {
+	var exists bool
+
+	err := b.do(ctx, func(c *goftp.ServerConn) error {
+		_, err := c.FileSize(b.path(p))
+		if isNotFound(err) {
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("check the object exists, %w", classify(err))
+		}
+
+		exists = true
+
+		return nil
+	})
+
+	return exists, err
+}
+
+// List lists all the objects that are the given path or under it, recursively.
+func (b *Backend) List(ctx context.Context, p string) ([]common.FileEntry, error) {
+	var entries []common.FileEntry
+
+	err := b.do(ctx, func(c *goftp.ServerConn) error {
+		root := b.path(p)
+
+		var err error
+
+		entries, err = b.walk(c, root)
+		if err != nil {
+			return err
+		}
+
+		if entries != nil || strings.Trim(p, "/") == "" {
+			return nil
+		}
+
+		// The prefix might be an object itself, which can not be listed as a directory.
+		size, err := c.FileSize(root)
+		if isNotFound(err) {
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("get the object size, %w", classify(err))
+		}
+
+		entry := common.FileEntry{Path: strings.Trim(p, "/"), Size: size}
+		if t, err := c.GetTime(root); err == nil {
+			entry.LastModified = t
+		}
+
+		entries = append(entries, entry)
+
+		return nil
+	})
+
+	return entries, err
+}
+
+// Delete deletes the object with the given path.
+func (b *Backend) Delete(ctx context.Context, p string) error {
+	return b.do(ctx, func(c *goftp.ServerConn) error {
+		if err := c.Delete(b.path(p)); err != nil {
+			return fmt.Errorf("delete the object, %w", classify(err))
+		}
+
+		return nil
+	})
+}
+
+// Close closes the idle connections to the server.
+func (b *Backend) Close() error {
+	b.pool.close()
+
+	return nil
+}
+
+// Helpers
+
+func (b *Backend) path(p string) string {
+	return path.Join(b.cacheRoot, p)
+}
+
+// do runs the given operation on a pooled connection, and returns when the context is done even if it blocks.
+func (b *Backend) do(ctx context.Context, op func(*goftp.ServerConn) error) error {
+	if err := ctx.Err(); err != nil {
+		return err // nolint: wrapcheck
+	}
+
+	conn, err := b.pool.get(ctx)
+	if err != nil {
+		return err
+	}
+
+	errCh := make(chan error, 1)
+
+	go func() {
+		err := op(conn)
+		b.pool.put(conn, err)
+		errCh <- err
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err() // nolint: wrapcheck
+	}
+}
+
+// makeDirs creates the given directory and its parents, as servers do not create them on upload.
+func (b *Backend) makeDirs(c *goftp.ServerConn, dir string) {
+	if dir == "." || dir == "/" || dir == "" {
+		return
+	}
+
+	if _, ok := b.dirs.Load(dir); ok {
+		return
+	}
+
+	b.makeDirs(c, path.Dir(dir))
+
+	// NOTICE: Servers reply inconsistently when the directory exists, so errors are ignored and surface on upload.
+	if err := c.MakeDir(dir); err != nil {
+		level.Debug(b.logger).Log("msg", "make directory", "path", dir, "err", err)
+	}
+
+	b.dirs.Store(dir, struct{}{})
+}
+
+// walk lists the files under the given directory recursively, with MLSD when the server supports it.
+func (b *Backend) walk(c *goftp.ServerConn, dir string) ([]common.FileEntry, error) {
+	list, err := c.List(dir)
+	if isNotFound(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("list <%s>, %w", dir, classify(err))
+	}
+
+	var entries []common.FileEntry
+
+	for _, e := range list {
+		if e.Name == "." || e.Name == ".." {
+			continue
+		}
+
+		p := path.Join(dir, e.Name)
+
+		switch e.Type {
+		case goftp.EntryTypeFolder:
+			sub, err := b.walk(c, p)
+			if err != nil {
+				return nil, err
+			}
+
+			entries = append(entries, sub...)
+		case goftp.EntryTypeFile:
+			entries = append(entries, common.FileEntry{
+				Path:         strings.TrimPrefix(strings.TrimPrefix(p, b.cacheRoot), "/"),
+				Size:         int64(e.Size),
+				LastModified: e.Time,
+			})
+		case goftp.EntryTypeLink:
+		}
+	}
+
+	return entries, nil
+}
+
+// isNotFound reports whether the server replied that the file is unavailable, some servers use 450 others 550.
+func isNotFound(err error) bool {
+	var terr *textproto.Error
+
+	return errors.As(err, &terr) && (terr.Code == goftp.StatusFileUnavailable || terr.Code == goftp.StatusFileActionIgnored)
+}
+
+// classify classifies the errors of FTP by the reply codes of the server.
+func classify(err error) error {
+	if isNotFound(err) {
+		return common.NewError(common.ErrNotFound, err)
+	}
+
+	var terr *textproto.Error
+	if !errors.As(err, &terr) {
+		return common.Classify(err)
+	}
+
+	switch terr.Code {
+	case goftp.StatusNotLoggedIn, goftp.StatusStorNeedAccount:
+		return common.NewError(common.ErrUnauthorized, err)
+	case goftp.Status452, goftp.StatusExceededStorage:
+		return common.NewError(common.ErrQuotaExceeded, err)
+	default:
+		return err
+	}
+}
+
+// pool keeps authenticated connections, since FTP connections are stateful and handle one transfer at a time.
+type pool struct {
+	addr     string
+	username string
+	password *common.SecretFile
+	options  []goftp.DialOption
+
+	sem  chan struct{}
+	mu   sync.Mutex
+	idle []*goftp.ServerConn
+}
+
+// get returns an idle connection or dials a new one, waiting while the maximum number of connections are in use.
+func (p *pool) get(ctx context.Context) (*goftp.ServerConn, error) {
+	select {
+	case p.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err() // nolint: wrapcheck
+	}
+
+	p.mu.Lock()
+	if n := len(p.idle); n > 0 {
+		conn := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+
+		return conn, nil
+	}
+	p.mu.Unlock()
+
+	conn, err := goftp.Dial(p.addr, append([]goftp.DialOption{goftp.DialWithContext(ctx)}, p.options...)...)
+	if err != nil {
+		<-p.sem
+
+		return nil, fmt.Errorf("connect to ftp server <%s>, %w", p.addr, err)
+	}
+
+	// NOTICE: Password is read for every connection, so that a rotated password is used by the connections opened later.
+	password, err := p.password.Read()
+	if err != nil {
+		conn.Quit()
+		<-p.sem
+
+		return nil, fmt.Errorf("read password, %w", err)
+	}
+
+	if err := conn.Login(p.username, string(password)); err != nil {
+		conn.Quit()
+		<-p.sem
+
+		return nil, fmt.Errorf("login to ftp server <%s>, %w", p.addr, classify(err))
+	}
+
+	return conn, nil
+}
+
+// put returns the connection to the pool, unless the error of its last operation leaves it in an unknown state.
+func (p *pool) put(conn *goftp.ServerConn, err error) {
+	defer func() { <-p.sem }()
+
+	var terr *textproto.Error
+	if err != nil && !errors.As(err, &terr) {
+		conn.Quit()
+
+		return
+	}
+
+	p.mu.Lock()
+	p.idle = append(p.idle, conn)
+	p.mu.Unlock()
+}
+
+func (p *pool) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, conn := range p.idle {
+		conn.Quit()
+	}
+
+	p.idle = nil
+}
@@ -0,0 +1,133 @@
+// +build integration
+
+package ftp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/meltwater/drone-cache/storage/backend/backendtest"
+	"github.com/meltwater/drone-cache/storage/common"
+	"github.com/meltwater/drone-cache/test"
+
+	"github.com/go-kit/log"
+)
+
+const (
+	defaultFTPHost   = "127.0.0.1"
+	defaultFTPPort   = "21"
+	defaultUsername  = "foo"
+	defaultPassword  = "pass"
+	defaultCacheRoot = "/"
+)
+
+var (
+	host      = getEnv("TEST_FTP_HOST", defaultFTPHost)
+	port      = getEnv("TEST_FTP_PORT", defaultFTPPort)
+	username  = getEnv("TEST_FTP_USERNAME", defaultUsername)
+	password  = getEnv("TEST_FTP_PASSWORD", defaultPassword)
+	cacheRoot = getEnv("TEST_FTP_CACHE_ROOT", defaultCacheRoot)
+)
+
+func TestRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	backend := setup(t)
+
+	content := "Hello world4"
+	test.Ok(t, backend.Put(context.TODO(), "round-trip/key/test.t", strings.NewReader(content)))
+	test.Ok(t, backend.Put(context.TODO(), "round-trip/other/test.t", strings.NewReader(content)))
+
+	var buf bytes.Buffer
+	test.Ok(t, backend.Get(context.TODO(), "round-trip/key/test.t", &buf))
+	test.Equals(t, content, buf.String())
+
+	exists, err := backend.Exists(context.TODO(), "round-trip/key/test.t")
+	test.Ok(t, err)
+	test.Equals(t, true, exists)
+
+	entries, err := backend.List(context.TODO(), "round-trip")
+	test.Ok(t, err)
+	test.Equals(t, 2, len(entries))
+	test.Equals(t, int64(len(content)), entries[0].Size)
+
+	entries, err = backend.List(context.TODO(), "round-trip/key/test.t")
+	test.Ok(t, err)
+	test.Equals(t, 1, len(entries))
+
+	test.Ok(t, backend.Delete(context.TODO(), "round-trip/key/test.t"))
+
+	exists, err = backend.Exists(context.TODO(), "round-trip/key/test.t")
+	test.Ok(t, err)
+	test.Equals(t, false, exists)
+
+	test.NotOk(t, backend.Get(context.TODO(), "round-trip/key/test.t", &buf))
+	test.Ok(t, backend.Delete(context.TODO(), "round-trip/other/test.t"))
+}
+
+func TestConcurrentMounts(t *testing.T) {
+	t.Parallel()
+
+	backend := setup(t)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 3*DefaultMaxConnections; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			p := fmt.Sprintf("concurrent/key/mount-%d", i)
+			content := strings.Repeat(p, 1000)
+			test.Ok(t, backend.Put(context.TODO(), p, strings.NewReader(content)))
+
+			var buf bytes.Buffer
+			test.Ok(t, backend.Get(context.TODO(), p, &buf))
+			test.Equals(t, content, buf.String())
+			test.Ok(t, backend.Delete(context.TODO(), p))
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	backendtest.Run(t, setup(t), backendtest.WithPrefix(fmt.Sprintf("conformance-%d", time.Now().UnixNano())))
+}
+
+// Helpers
+
+func setup(t *testing.T) *Backend {
+	b, err := New(
+		log.NewNopLogger(),
+		Config{
+			CacheRoot: cacheRoot,
+			Username:  username,
+			Password:  common.Secret(password),
+			Host:      host,
+			Port:      port,
+		},
+	)
+	test.Ok(t, err)
+	t.Cleanup(func() { b.Close() })
+
+	return b
+}
+
+func getEnv(key, defaultVal string) string {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return defaultVal
+	}
+
+	return value
+}
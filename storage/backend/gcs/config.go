@@ -1,14 +1,47 @@
 package gcs
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/meltwater/drone-cache/storage/common"
+)
 
 // Config is a structure to store Cloud Storage backend configuration.
 type Config struct {
 	Bucket     string
 	ACL        string
-	Encryption string
+	Encryption common.Secret
 	Endpoint   string
-	APIKey     string
-	JSONKey    string
-	Timeout    time.Duration
+	APIKey     common.Secret
+	JSONKey    common.Secret
+	// APIKeyFile and JSONKeyFile are files to read the keys from, instead of APIKey and JSONKey.
+	// They are read again when they change.
+	APIKeyFile  string
+	JSONKeyFile string
+	Timeout     time.Duration
+
+	// Transport configures the HTTP transport, e.g. the CA bundle, the client certificate and the proxy.
+	Transport common.TransportConfig
+}
+
+// Validate reports every problem of the configuration.
+func (c Config) Validate() error {
+	var v common.Validation
+
+	v.Required("bucket", c.Bucket)
+	v.Exclusive("api key", "json key", c.APIKey != "" || c.APIKeyFile != "", c.JSONKey != "" || c.JSONKeyFile != "")
+	v.SecretFile("api key", c.APIKey, c.APIKeyFile)
+	v.SecretFile("json key", c.JSONKey, c.JSONKeyFile)
+	v.Endpoint("endpoint", c.Endpoint)
+
+	if c.JSONKey != "" && !json.Valid([]byte(c.JSONKey)) {
+		v.Addf("json key is not valid JSON")
+	}
+
+	v.NonNegative("timeout", int64(c.Timeout))
+
+	v.Merge("transport", c.Transport.Validate())
+
+	return v.Err()
 }
@@ -8,13 +8,19 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"sync"
 
 	gcstorage "cloud.google.com/go/storage"
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/meltwater/drone-cache/internal"
+	"github.com/meltwater/drone-cache/storage/common"
+	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
 )
 
 // Backend is an Cloud Storage implementation of the Backend.
@@ -33,22 +39,63 @@ func New(l log.Logger, c Config) (*Backend, error) {
 
 	level.Debug(l).Log("msg", "gc storage backend", "config", fmt.Sprintf("%+v", c))
 
+	base := http.DefaultTransport
+
 	if c.Endpoint != "" {
 		opts = append(opts, option.WithEndpoint(c.Endpoint))
 
-		if !strings.HasPrefix(c.Endpoint, "https://") { // This is not settable from outside world, only used for mock tests.
-			opts = append(opts, option.WithHTTPClient(&http.Client{Transport: &http.Transport{
+		// This is not settable from outside world, only used for mock tests, configured transports take precedence.
+		if !strings.HasPrefix(c.Endpoint, "https://") && c.Transport.IsZero() {
+			base = &http.Transport{
 				// ignore unverified/expired SSL certificates for tests.
 				TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, //nolint:gosec
-			}}))
+			}
+			opts = append(opts, option.WithHTTPClient(&http.Client{Transport: base}))
+		}
+	}
+
+	if !c.Transport.IsZero() {
+		t, err := c.Transport.NewTransport()
+		if err != nil {
+			return nil, fmt.Errorf("gcs transport, %w", err)
 		}
+
+		base = t
 	}
 
-	opts = setAuthenticationMethod(l, c, opts)
+	switch {
+	case c.APIKeyFile != "":
+		key, err := common.NewSecretFile(c.APIKey, c.APIKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("gcs api key, %w", err)
+		}
+
+		// NOTICE: The API key is added to every request by the transport, so that a rotated key is used right away.
+		opts = append(opts, option.WithHTTPClient(&http.Client{Transport: &fileAPIKeyTransport{key: key, next: base}}))
+	case c.JSONKeyFile != "":
+		ts, err := newFileTokenSource(c.JSONKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("gcs json key, %w", err)
+		}
+
+		opts = append(opts, option.WithTokenSource(ts))
+	default:
+		opts = setAuthenticationMethod(l, c, opts)
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
 	defer cancel()
 
+	if !c.Transport.IsZero() && c.APIKeyFile == "" {
+		// NOTICE: Given HTTP clients are used as they are, so the authentication is wrapped around the transport here.
+		rt, err := htransport.NewTransport(ctx, base, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("gcs transport, %w", err)
+		}
+
+		opts = append(opts, option.WithHTTPClient(&http.Client{Transport: rt}))
+	}
+
 	client, err := gcstorage.NewClient(ctx, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("gcs client initialization, %w", err)
@@ -58,7 +105,7 @@ func New(l log.Logger, c Config) (*Backend, error) {
 		logger:     l,
 		bucket:     c.Bucket,
 		acl:        c.ACL,
-		encryption: c.Encryption,
+		encryption: string(c.Encryption),
 		client:     client,
 	}, nil
 }
@@ -79,7 +126,7 @@ func (b *Backend) Get(ctx context.Context, p string, w io.Writer) error {
 
 		r, err := obj.NewReader(ctx)
 		if err != nil {
-			errCh <- fmt.Errorf("get the object, %w", err)
+			errCh <- fmt.Errorf("get the object, %w", classify(err))
 
 			return
 		}
@@ -88,7 +135,7 @@ func (b *Backend) Get(ctx context.Context, p string, w io.Writer) error {
 
 		_, err = io.Copy(w, r)
 		if err != nil {
-			errCh <- fmt.Errorf("copy the object, %w", err)
+			errCh <- fmt.Errorf("copy the object, %w", classify(err))
 		}
 	}()
 
@@ -120,16 +167,16 @@ func (b *Backend) Put(ctx context.Context, p string, r io.Reader) error {
 
 		_, err := io.Copy(w, r)
 		if err != nil {
-			errCh <- fmt.Errorf("copy the object, %w", err)
+			errCh <- fmt.Errorf("copy the object, %w", classify(err))
 		}
 
 		if err := w.Close(); err != nil {
-			errCh <- fmt.Errorf("close the object, %w", err)
+			errCh <- fmt.Errorf("close the object, %w", classify(err))
 		}
 
 		if b.acl != "" {
 			if err := obj.ACL().Set(ctx, gcstorage.AllAuthenticatedUsers, gcstorage.ACLRole(b.acl)); err != nil {
-				errCh <- fmt.Errorf("set ACL of the object, %w", err)
+				errCh <- fmt.Errorf("set ACL of the object, %w", classify(err))
 			}
 		}
 	}()
@@ -163,7 +210,8 @@ func (b *Backend) Exists(ctx context.Context, p string) (bool, error) {
 		}
 
 		attrs, err := obj.Attrs(ctx)
-		if err != nil && !errors.Is(err, gcstorage.ErrObjectNotExist) {
+		err = classify(err)
+		if err != nil && !errors.Is(err, common.ErrNotFound) {
 			resCh <- &result{err: fmt.Errorf("get the object attrs, %w", err)}
 
 			return
@@ -181,11 +229,68 @@ func (b *Backend) Exists(ctx context.Context, p string) (bool, error) {
 	}
 }
 
+// List lists all the objects that are the given path or under it, recursively.
+func (b *Backend) List(ctx context.Context, p string) ([]common.FileEntry, error) {
+	var entries []common.FileEntry
+
+	it := b.client.Bucket(b.bucket).Objects(ctx, &gcstorage.Query{Prefix: p})
+
+	for {
+		attrs, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("list the objects, %w", classify(err))
+		}
+
+		if !common.InPrefix(attrs.Name, p) {
+			continue
+		}
+
+		entries = append(entries, common.FileEntry{
+			Path:         attrs.Name,
+			Size:         attrs.Size,
+			LastModified: attrs.Updated,
+		})
+	}
+
+	return entries, nil
+}
+
+// Delete deletes the object with the given path.
+func (b *Backend) Delete(ctx context.Context, p string) error {
+	if err := b.client.Bucket(b.bucket).Object(p).Delete(ctx); err != nil {
+		return fmt.Errorf("delete the object, %w", classify(err))
+	}
+
+	return nil
+}
+
 // Helpers
 
+// classify classifies the errors of GCS by the sentinel errors of the client, or by the status codes of the API.
+func classify(err error) error {
+	if errors.Is(err, gcstorage.ErrObjectNotExist) {
+		return common.NewError(common.ErrNotFound, err)
+	}
+
+	if errors.Is(err, gcstorage.ErrBucketNotExist) {
+		return common.NewError(common.ErrInvalidConfig, err)
+	}
+
+	var gerr *googleapi.Error
+	if errors.As(err, &gerr) {
+		return common.ClassifyStatus(gerr.Code, err)
+	}
+
+	return common.Classify(err)
+}
+
 func setAuthenticationMethod(l log.Logger, c Config, opts []option.ClientOption) []option.ClientOption {
 	if c.APIKey != "" {
-		opts = append(opts, option.WithAPIKey(c.APIKey))
+		opts = append(opts, option.WithAPIKey(string(c.APIKey)))
 
 		return opts
 	}
@@ -223,3 +328,76 @@ func credentials(l log.Logger, c Config) (*google.Credentials, error) {
 
 	return creds, nil
 }
+
+// fileAPIKeyTransport adds the API key read from a file to the requests,
+// and reads the key again when the file changes, e.g. when an agent rotates it.
+// NOTICE: If the file can not be read anymore, the last key read from it is used.
+type fileAPIKeyTransport struct {
+	key  *common.SecretFile
+	next http.RoundTripper
+}
+
+// RoundTrip adds the current key to the query of the request, the same way option.WithAPIKey does.
+func (t *fileAPIKeyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key, _ := t.key.Read()
+
+	r := req.Clone(req.Context())
+	query := r.URL.Query()
+	query.Set("key", string(key))
+	r.URL.RawQuery = query.Encode()
+
+	return t.next.RoundTrip(r) // nolint: wrapcheck
+}
+
+// fileTokenSource is an oauth2.TokenSource of the JSON key read from a file,
+// that reads the key again when the file changes, e.g. when an agent rotates it.
+type fileTokenSource struct {
+	key *common.SecretFile
+
+	mu  sync.Mutex
+	src oauth2.TokenSource
+}
+
+func newFileTokenSource(path string) (*fileTokenSource, error) {
+	key, err := common.NewSecretFile("", path)
+	if err != nil {
+		return nil, err
+	}
+
+	ts := &fileTokenSource{key: key}
+	if err := ts.reload(); err != nil {
+		return nil, err
+	}
+
+	return ts, nil
+}
+
+// Token returns a token of the current key.
+func (ts *fileTokenSource) Token() (*oauth2.Token, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.key.Changed() {
+		if err := ts.reload(); err != nil {
+			return nil, err
+		}
+	}
+
+	return ts.src.Token() // nolint: wrapcheck
+}
+
+func (ts *fileTokenSource) reload() error {
+	key, err := ts.key.Read()
+	if err != nil {
+		return err // nolint: wrapcheck
+	}
+
+	creds, err := google.CredentialsFromJSON(context.Background(), []byte(key), gcstorage.ScopeFullControl)
+	if err != nil {
+		return fmt.Errorf("credentials from json key file, %w", err)
+	}
+
+	ts.src = creds.TokenSource
+
+	return nil
+}
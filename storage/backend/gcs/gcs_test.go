@@ -6,15 +6,21 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
 	gcstorage "cloud.google.com/go/storage"
 	"github.com/go-kit/log"
+	"github.com/meltwater/drone-cache/storage/backend/backendtest"
+	"github.com/meltwater/drone-cache/storage/common"
 	"github.com/meltwater/drone-cache/test"
 	"google.golang.org/api/option"
 )
@@ -59,6 +65,53 @@ func TestRoundTrip(t *testing.T) {
 	test.Equals(t, true, exists)
 }
 
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	backend, cleanUp := setup(t)
+	t.Cleanup(cleanUp)
+
+	b := &emulated{Backend: backend, get: getBackend(t, bucketName)}
+
+	backendtest.Run(t, b, backendtest.WithPrefix(fmt.Sprintf("conformance-%d", time.Now().UnixNano())))
+}
+
+func TestAPIKeyFile(t *testing.T) {
+	t.Parallel()
+
+	keys := make(chan string, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys <- r.URL.Query().Get("key")
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, `{"error":{"code":404,"message":"Not Found"}}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	keyFile := filepath.Join(t.TempDir(), "api-key")
+	test.Ok(t, os.WriteFile(keyFile, []byte("key\n"), 0o600))
+
+	backend, err := New(log.NewNopLogger(), Config{
+		Bucket:     bucketName,
+		Endpoint:   srv.URL + "/storage/v1/",
+		APIKeyFile: keyFile,
+		Timeout:    30 * time.Second,
+	})
+	test.Ok(t, err)
+
+	_, err = backend.Exists(context.TODO(), "test.txt")
+	test.Ok(t, err)
+	test.Equals(t, "key", <-keys)
+
+	// Rotated key is used by the next request.
+	test.Ok(t, os.WriteFile(keyFile, []byte("rotated-key\n"), 0o600))
+
+	_, err = backend.Exists(context.TODO(), "test.txt")
+	test.Ok(t, err)
+	test.Equals(t, "rotated-key", <-keys)
+}
+
 // Helpers
 
 func setup(t *testing.T) (*Backend, func()) {
@@ -80,7 +133,7 @@ func putBackend(t *testing.T, bucketName string) *Backend {
 		Config{
 			Bucket:   bucketName,
 			Endpoint: endpoint,
-			APIKey:   apiKey,
+			APIKey:   common.Secret(apiKey),
 			Timeout:  30 * time.Second,
 		},
 	)
@@ -100,7 +153,7 @@ func getBackend(t *testing.T, bucketName string) *Backend {
 		Config{
 			Bucket:   bucketName,
 			Endpoint: endpoint,
-			APIKey:   apiKey,
+			APIKey:   common.Secret(apiKey),
 			Timeout:  30 * time.Second,
 		},
 	)
@@ -135,6 +188,17 @@ func newClient(t *testing.T) *gcstorage.Client {
 	return client
 }
 
+// emulated downloads with the backend that is set up for downloads from the emulator.
+type emulated struct {
+	*Backend
+
+	get *Backend
+}
+
+func (b *emulated) Get(ctx context.Context, p string, w io.Writer) error {
+	return b.get.Get(ctx, p, w)
+}
+
 func getEnv(key, defaultVal string) string {
 	value, ok := os.LookupEnv(key)
 	if !ok {
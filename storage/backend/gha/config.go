@@ -0,0 +1,40 @@
+package gha
+
+import "github.com/meltwater/drone-cache/storage/common"
+
+// Config is a structure to store GitHub Actions cache service backend configuration.
+type Config struct {
+	// URL is the base URL of the cache service, as ACTIONS_CACHE_URL of the runners.
+	URL string
+	// Token is the bearer token, as ACTIONS_RUNTIME_TOKEN of the runners.
+	Token common.Secret
+	// TokenFile is a file to read the token from, instead of Token. It is read again when it changes.
+	TokenFile string
+	// Version distinguishes caches with the same key, e.g. actions/cache derives it from the cached paths.
+	Version string
+	// RestoreKeys are key prefixes to fall back to, when there is no cache with the exact key.
+	RestoreKeys []string
+	// ChunkSize is the size of the chunks that uploads are split into.
+	ChunkSize int
+	// Concurrency is the number of chunks uploaded concurrently.
+	Concurrency int
+
+	// Transport configures the HTTP transport, e.g. the CA bundle, the client certificate and the proxy.
+	Transport common.TransportConfig
+}
+
+// Validate reports every problem of the configuration.
+func (c Config) Validate() error {
+	var v common.Validation
+
+	v.Required("url", c.URL)
+	v.URL("url", c.URL, "http", "https")
+	v.Required("token", string(c.Token)+c.TokenFile)
+	v.SecretFile("token", c.Token, c.TokenFile)
+	v.NonNegative("chunk size", int64(c.ChunkSize))
+	v.NonNegative("concurrency", int64(c.Concurrency))
+
+	v.Merge("transport", c.Transport.Validate())
+
+	return v.Err()
+}
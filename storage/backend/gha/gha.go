@@ -0,0 +1,432 @@
+package gha
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+
+	"github.com/meltwater/drone-cache/internal"
+	"github.com/meltwater/drone-cache/storage/common"
+)
+
+const (
+	// DefaultChunkSize is the default size of the chunks that uploads are split into, the same as actions/cache uses.
+	DefaultChunkSize = 32 << 20
+	// DefaultConcurrency is the default number of chunks uploaded concurrently.
+	DefaultConcurrency = 4
+
+	apiVersion = "application/json;api-version=6.0-preview.1"
+	apiPath    = "_apis/artifactcache/"
+)
+
+var (
+	// ErrNotSupported means that the operation is not supported by the cache service protocol,
+	// it wraps common.ErrNotSupported.
+	ErrNotSupported = fmt.Errorf("%w by the cache service protocol", common.ErrNotSupported)
+	// ErrCacheNotFound means that no cache entry matches the given key, it is classified as common.ErrNotFound.
+	ErrCacheNotFound = common.NewError(common.ErrNotFound, errors.New("cache entry not found"))
+	// ErrUnexpectedStatus means that the cache service responded with an unexpected status code.
+	ErrUnexpectedStatus = errors.New("unexpected status")
+)
+
+// Backend is a GitHub Actions cache service implementation of the Backend.
+// Objects are stored as cache entries keyed by their path, so that actions/cache can share them.
+type Backend struct {
+	logger log.Logger
+
+	base        *url.URL
+	token       *common.SecretFile
+	version     string
+	restoreKeys []string
+	chunkSize   int
+	concurrency int
+	client      *http.Client
+}
+
+// New creates a GitHub Actions cache service backend.
+func New(l log.Logger, c Config) (*Backend, error) {
+	base, err := url.Parse(c.URL)
+	if err != nil || base.Host == "" {
+		return nil, fmt.Errorf("parse cache service url <%s>, %v", c.URL, err) // nolint: errorlint
+	}
+
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+	}
+
+	version := c.Version
+	if version == "" {
+		sum := sha256.Sum256([]byte("drone-cache"))
+		version = hex.EncodeToString(sum[:])
+	}
+
+	chunkSize := c.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	token, err := common.NewSecretFile(c.Token, c.TokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("token, %w", err)
+	}
+
+	concurrency := c.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	client, err := c.Transport.NewClient()
+	if err != nil {
+		return nil, fmt.Errorf("transport, %w", err)
+	}
+
+	level.Debug(l).Log("msg", "github actions cache backend", "url", base.Redacted(), "version", version)
+
+	return &Backend{
+		logger:      l,
+		base:        base,
+		token:       token,
+		version:     version,
+		restoreKeys: c.RestoreKeys,
+		chunkSize:   chunkSize,
+		concurrency: concurrency,
+		client:      client,
+	}, nil
+}
+
+// Get writes downloaded content to the given writer.
+// When there is no cache entry with the exact key, restore keys are looked up as prefixes,
+// and the entry of the same mount of the matched key is written, which is reported with common.ReportFallback.
+func (b *Backend) Get(ctx context.Context, p string, w io.Writer) error {
+	entry, err := b.find(ctx, p)
+	if err != nil {
+		return fmt.Errorf("lookup the cache entry, %w", err)
+	}
+
+	if entry.CacheKey != p {
+		level.Info(b.logger).Log("msg", "restoring cache entry of restore key", "key", p, "matched", entry.CacheKey)
+		common.ReportFallback(ctx, entry.CacheKey)
+	}
+
+	// NOTICE: Archive location is a pre-signed URL, it must not receive the token.
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, entry.ArchiveLocation, nil)
+	if err != nil {
+		return fmt.Errorf("create download request, %w", err)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("download the archive, %w", err)
+	}
+
+	defer internal.CloseWithErrLogf(b.logger, resp.Body, "response body, close defer")
+
+	if err := expect(resp, http.StatusOK); err != nil {
+		return fmt.Errorf("download the archive, %w", err)
+	}
+
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("copy the archive, %w", err)
+	}
+
+	return nil
+}
+
+// Put uploads contents of the given reader as a new cache entry: reserve, upload chunks and commit.
+func (b *Backend) Put(ctx context.Context, p string, r io.Reader) error {
+	f, err := os.CreateTemp("", "drone-cache-upload-*")
+	if err != nil {
+		return fmt.Errorf("create temporary upload file, %w", err)
+	}
+
+	defer os.Remove(f.Name())
+	defer internal.CloseWithErrLogf(b.logger, f, "temporary upload file, close defer")
+
+	size, err := io.Copy(f, r)
+	if err != nil {
+		return fmt.Errorf("spool upload to temporary file, %w", err)
+	}
+
+	var reserved struct {
+		CacheID int64 `json:"cacheId"`
+	}
+
+	if err := b.call(ctx, http.MethodPost, "caches", map[string]interface{}{
+		"key": p, "version": b.version, "cacheSize": size,
+	}, &reserved, http.StatusOK, http.StatusCreated); err != nil {
+		return fmt.Errorf("reserve the cache entry, %w", err)
+	}
+
+	if err := b.upload(ctx, reserved.CacheID, f, size); err != nil {
+		return fmt.Errorf("upload the archive, %w", err)
+	}
+
+	if err := b.call(ctx, http.MethodPost, "caches/"+strconv.FormatInt(reserved.CacheID, 10), map[string]interface{}{
+		"size": size,
+	}, nil, http.StatusOK, http.StatusNoContent); err != nil {
+		return fmt.Errorf("commit the cache entry, %w", err)
+	}
+
+	return nil
+}
+
+// Exists checks if a cache entry with the exact key exists.
+func (b *Backend) Exists(ctx context.Context, p string) (bool, error) {
+	entry, err := b.lookup(ctx, []string{p})
+	if errors.Is(err, ErrCacheNotFound) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("lookup the cache entry, %w", err)
+	}
+
+	return entry.CacheKey == p, nil
+}
+
+// List is not supported by the cache service protocol.
+func (b *Backend) List(_ context.Context, _ string) ([]common.FileEntry, error) {
+	return nil, ErrNotSupported
+}
+
+// Delete is not supported by the cache service protocol, entries are evicted by the service.
+func (b *Backend) Delete(_ context.Context, _ string) error {
+	return ErrNotSupported
+}
+
+// Helpers
+
+type cacheEntry struct {
+	CacheKey        string `json:"cacheKey"`
+	Scope           string `json:"scope"`
+	ArchiveLocation string `json:"archiveLocation"`
+}
+
+// find looks up the entry of the exact key, then the entries of the same mount of the keys that the restore keys match.
+// NOTICE: Paths are <namespace>/<key>/<mount>, and restore keys are <namespace>/<key prefix>. A restore key matches
+// the entries of every mount of a key, so the entry of the requested mount of the matched key is looked up exactly.
+func (b *Backend) find(ctx context.Context, p string) (*cacheEntry, error) {
+	entry, err := b.lookup(ctx, []string{p})
+	if !errors.Is(err, ErrCacheNotFound) {
+		return entry, err
+	}
+
+	for _, rk := range b.restoreKeys {
+		namespace := rk[:strings.LastIndex(rk, "/")+1]
+
+		_, mount, ok := strings.Cut(strings.TrimPrefix(p, namespace), "/")
+		if !ok || !strings.HasPrefix(p, namespace) {
+			continue
+		}
+
+		matched, err := b.lookup(ctx, []string{p, rk})
+		if errors.Is(err, ErrCacheNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		key, _, _ := strings.Cut(strings.TrimPrefix(matched.CacheKey, namespace), "/")
+		if matched.CacheKey == namespace+key+"/"+mount {
+			return matched, nil
+		}
+
+		entry, err := b.lookup(ctx, []string{namespace + key + "/" + mount})
+		if errors.Is(err, ErrCacheNotFound) {
+			continue
+		}
+
+		return entry, err
+	}
+
+	return nil, err
+}
+
+func (b *Backend) lookup(ctx context.Context, keys []string) (*cacheEntry, error) {
+	q := url.Values{}
+	q.Set("keys", strings.Join(keys, ","))
+	q.Set("version", b.version)
+
+	req, err := b.request(ctx, http.MethodGet, "cache?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("lookup request, %w", err)
+	}
+
+	defer internal.CloseWithErrLogf(b.logger, resp.Body, "response body, close defer")
+
+	if resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("keys <%s>, %w", strings.Join(keys, ","), ErrCacheNotFound)
+	}
+
+	if err := expect(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+
+	var entry cacheEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entry); err != nil {
+		return nil, fmt.Errorf("decode the cache entry, %w", err)
+	}
+
+	if entry.ArchiveLocation == "" {
+		return nil, fmt.Errorf("keys <%s>, %w", strings.Join(keys, ","), ErrCacheNotFound)
+	}
+
+	return &entry, nil
+}
+
+func (b *Backend) upload(ctx context.Context, id int64, f io.ReaderAt, size int64) error {
+	var (
+		wg     sync.WaitGroup
+		errs   = &internal.MultiError{}
+		chunks = make(chan int64)
+	)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for i := 0; i < b.concurrency; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for start := range chunks {
+				end := start + int64(b.chunkSize)
+				if end > size {
+					end = size
+				}
+
+				if err := b.uploadChunk(ctx, id, io.NewSectionReader(f, start, end-start), start, end-1); err != nil {
+					errs.Add(err)
+					cancel()
+				}
+			}
+		}()
+	}
+
+	for start := int64(0); start < size; start += int64(b.chunkSize) {
+		select {
+		case chunks <- start:
+		case <-ctx.Done():
+		}
+	}
+
+	close(chunks)
+	wg.Wait()
+
+	return errs.Err()
+}
+
+func (b *Backend) uploadChunk(ctx context.Context, id int64, r io.Reader, start, end int64) error {
+	req, err := b.request(ctx, http.MethodPatch, "caches/"+strconv.FormatInt(id, 10), r)
+	if err != nil {
+		return err
+	}
+
+	req.ContentLength = end - start + 1
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/*", start, end))
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("upload chunk <%d-%d>, %w", start, end, err)
+	}
+
+	defer internal.CloseWithErrLogf(b.logger, resp.Body, "response body, close defer")
+
+	if err := expect(resp, http.StatusOK, http.StatusNoContent); err != nil {
+		return fmt.Errorf("upload chunk <%d-%d>, %w", start, end, err)
+	}
+
+	return nil
+}
+
+func (b *Backend) call(ctx context.Context, method, path string, in, out interface{}, codes ...int) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("marshal request, %w", err)
+	}
+
+	req, err := b.request(ctx, method, path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s, %w", method, path, err)
+	}
+
+	defer internal.CloseWithErrLogf(b.logger, resp.Body, "response body, close defer")
+
+	if err := expect(resp, codes...); err != nil {
+		return err
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response, %w", err)
+	}
+
+	return nil
+}
+
+func (b *Backend) request(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, b.base.String()+apiPath+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("create request, %w", err)
+	}
+
+	req.Header.Set("Accept", apiVersion)
+
+	// NOTICE: Token is read again when its file changes, as tokens are rotated during long operations.
+	token, err := b.token.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read token, %w", err)
+	}
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+string(token))
+	}
+
+	return req, nil
+}
+
+func expect(resp *http.Response, codes ...int) error {
+	for _, code := range codes {
+		if resp.StatusCode == code {
+			return nil
+		}
+	}
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512)) // nolint: gomnd
+
+	return common.ClassifyStatus(resp.StatusCode, fmt.Errorf("%s %s, %s %s, %w",
+		resp.Request.Method, resp.Request.URL.Redacted(), resp.Status, strings.TrimSpace(string(msg)), ErrUnexpectedStatus))
+}
@@ -0,0 +1,214 @@
+package gha
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-kit/log"
+
+	"github.com/meltwater/drone-cache/storage/backend/backendtest"
+	"github.com/meltwater/drone-cache/storage/common"
+	"github.com/meltwater/drone-cache/test"
+)
+
+func TestRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	backend, srv := setup(t, Config{ChunkSize: 4})
+
+	content := "Hello world4"
+	test.Ok(t, backend.Put(context.TODO(), "repo/key/test.t", strings.NewReader(content)))
+
+	var buf bytes.Buffer
+	test.Ok(t, backend.Get(context.TODO(), "repo/key/test.t", &buf))
+	test.Equals(t, content, buf.String())
+
+	exists, err := backend.Exists(context.TODO(), "repo/key/test.t")
+	test.Ok(t, err)
+	test.Equals(t, true, exists)
+
+	exists, err = backend.Exists(context.TODO(), "repo/key")
+	test.Ok(t, err)
+	test.Equals(t, false, exists)
+
+	test.Assert(t, srv.chunks > 1, "expected chunked upload, got %d chunks", srv.chunks)
+
+	test.Expected(t, backend.Get(context.TODO(), "missing", &buf), ErrCacheNotFound)
+
+	_, err = backend.List(context.TODO(), "repo")
+	test.Expected(t, err, ErrNotSupported)
+	test.Expected(t, backend.Delete(context.TODO(), "repo/key/test.t"), ErrNotSupported)
+}
+
+func TestRestoreKeys(t *testing.T) {
+	t.Parallel()
+
+	backend, _ := setup(t, Config{RestoreKeys: []string{"repo/other-", "repo/"}})
+
+	test.Ok(t, backend.Put(context.TODO(), "repo/main/test.t", strings.NewReader("Hello world4")))
+	// Latest entry of the matched key is of another mount, it must not be restored in place of the requested one.
+	test.Ok(t, backend.Put(context.TODO(), "repo/main/other.t", strings.NewReader("Other mount")))
+
+	var (
+		buf      bytes.Buffer
+		fallback string
+	)
+
+	ctx := common.WithFallback(context.TODO(), func(p string) { fallback = p })
+	test.Ok(t, backend.Get(ctx, "repo/feature/test.t", &buf))
+	test.Equals(t, "Hello world4", buf.String())
+	test.Equals(t, "repo/main/test.t", fallback)
+
+	test.Expected(t, backend.Get(context.TODO(), "repo/feature/missing.t", &buf), ErrCacheNotFound)
+}
+
+func TestVersion(t *testing.T) {
+	t.Parallel()
+
+	backend, srv := setup(t, Config{Version: "v1"})
+	test.Ok(t, backend.Put(context.TODO(), "repo/key/test.t", strings.NewReader("Hello world4")))
+
+	other, err := New(log.NewNopLogger(), Config{URL: srv.URL, Token: "token", Version: "v2"})
+	test.Ok(t, err)
+
+	exists, err := other.Exists(context.TODO(), "repo/key/test.t")
+	test.Ok(t, err)
+	test.Equals(t, false, exists)
+}
+
+func TestUnauthorized(t *testing.T) {
+	t.Parallel()
+
+	_, srv := setup(t, Config{})
+
+	backend, err := New(log.NewNopLogger(), Config{URL: srv.URL, Token: "wrong"})
+	test.Ok(t, err)
+	err = backend.Put(context.TODO(), "test.t", strings.NewReader("Hello world4"))
+	test.Expected(t, err, ErrUnexpectedStatus)
+	test.Expected(t, err, common.ErrUnauthorized)
+}
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	backend, _ := setup(t, Config{})
+
+	backendtest.Run(t, backend, backendtest.WithoutList(), backendtest.WithoutDelete())
+}
+
+// Helpers
+
+func setup(t *testing.T, c Config) (*Backend, *server) {
+	srv := newServer("token")
+	t.Cleanup(srv.Close)
+
+	c.URL = srv.URL
+	c.Token = "token"
+
+	b, err := New(log.NewNopLogger(), c)
+	test.Ok(t, err)
+
+	return b, srv
+}
+
+type entry struct {
+	key, version string
+	data         []byte
+	committed    bool
+}
+
+// server is a minimal in-memory implementation of the cache service protocol.
+type server struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	token   string
+	entries []*entry
+	chunks  int
+}
+
+func newServer(token string) *server {
+	s := &server{token: token}
+	s.Server = httptest.NewServer(s)
+
+	return s
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if strings.HasPrefix(r.URL.Path, "/archives/") {
+		id, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/archives/"))
+		w.Write(s.entries[id].data)
+
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer "+s.token {
+		w.WriteHeader(http.StatusUnauthorized)
+
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/_apis/artifactcache/")
+
+	switch {
+	case r.Method == http.MethodGet && path == "cache":
+		s.lookup(w, strings.Split(r.URL.Query().Get("keys"), ","), r.URL.Query().Get("version"))
+	case r.Method == http.MethodPost && path == "caches":
+		var req struct{ Key, Version string }
+		json.NewDecoder(r.Body).Decode(&req)
+		s.entries = append(s.entries, &entry{key: req.Key, version: req.Version})
+		json.NewEncoder(w).Encode(map[string]int{"cacheId": len(s.entries) - 1})
+	case r.Method == http.MethodPatch && strings.HasPrefix(path, "caches/"):
+		id, _ := strconv.Atoi(strings.TrimPrefix(path, "caches/"))
+		var start, end int
+		fmt.Sscanf(r.Header.Get("Content-Range"), "bytes %d-%d/*", &start, &end)
+		data, _ := io.ReadAll(r.Body)
+		e := s.entries[id]
+		if len(e.data) < end+1 {
+			e.data = append(e.data, make([]byte, end+1-len(e.data))...)
+		}
+		copy(e.data[start:], data)
+		s.chunks++
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && strings.HasPrefix(path, "caches/"):
+		id, _ := strconv.Atoi(strings.TrimPrefix(path, "caches/"))
+		s.entries[id].committed = true
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *server) lookup(w http.ResponseWriter, keys []string, version string) {
+	for i, k := range keys {
+		for id := len(s.entries) - 1; id >= 0; id-- {
+			e := s.entries[id]
+			if !e.committed || e.version != version {
+				continue
+			}
+
+			if e.key == k || (i > 0 && strings.HasPrefix(e.key, k)) {
+				json.NewEncoder(w).Encode(map[string]string{
+					"cacheKey":        e.key,
+					"archiveLocation": s.URL + "/archives/" + strconv.Itoa(id),
+				})
+
+				return
+			}
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
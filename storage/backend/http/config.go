@@ -0,0 +1,68 @@
+package http
+
+import (
+	"strings"
+
+	"github.com/meltwater/drone-cache/storage/common"
+)
+
+const (
+	// ListPropfind lists objects using WebDAV PROPFIND requests.
+	ListPropfind = "propfind"
+	// ListJSON lists objects using JSON directory listings, as nginx autoindex_format json produces.
+	ListJSON = "json"
+)
+
+// Config is a structure to store HTTP backend configuration.
+type Config struct {
+	// URL is the base URL, objects are stored under it with their paths.
+	URL         string
+	Username    string
+	Password    common.Secret
+	BearerToken common.Secret
+	// PasswordFile and BearerTokenFile are files to read the credentials from, instead of Password and BearerToken.
+	// They are read again when they change.
+	PasswordFile    string
+	BearerTokenFile string
+	// Headers are additional request headers in "Name: value" form.
+	Headers []string
+	// ListMethod is how objects are listed, either ListPropfind or ListJSON.
+	ListMethod string
+	// ChunkedUpload streams uploads using chunked transfer encoding,
+	// otherwise they are spooled to a temporary file to send their content length.
+	ChunkedUpload bool
+	// MakeCollections creates missing parent collections with MKCOL before uploads, as plain WebDAV servers require.
+	MakeCollections bool
+
+	// Transport configures the HTTP transport, e.g. the CA bundle, the client certificate and the proxy.
+	Transport common.TransportConfig
+}
+
+// Validate reports every problem of the configuration.
+func (c Config) Validate() error {
+	var v common.Validation
+
+	v.Required("url", c.URL)
+	v.URL("url", c.URL, "http", "https")
+	v.Exclusive("username", "bearer token", c.Username != "", c.BearerToken != "" || c.BearerTokenFile != "")
+	v.SecretFile("password", c.Password, c.PasswordFile)
+	v.SecretFile("bearer token", c.BearerToken, c.BearerTokenFile)
+
+	if (c.Password != "" || c.PasswordFile != "") && c.Username == "" {
+		v.Addf("<password> is given without <username>")
+	}
+
+	if c.ListMethod != "" {
+		v.OneOf("list method", c.ListMethod, ListPropfind, ListJSON)
+	}
+
+	for _, h := range c.Headers {
+		if name, _, ok := strings.Cut(h, ":"); !ok || strings.TrimSpace(name) == "" {
+			v.Addf("header <%s> is not in \"Name: value\" form", h)
+		}
+	}
+
+	v.Merge("transport", c.Transport.Validate())
+
+	return v.Err()
+}
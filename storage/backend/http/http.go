@@ -0,0 +1,332 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	nethttp "net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+
+	"github.com/meltwater/drone-cache/internal"
+	"github.com/meltwater/drone-cache/storage/common"
+)
+
+const methodMkcol = "MKCOL"
+
+// ErrUnexpectedStatus means that the server responded with an unexpected status code.
+var ErrUnexpectedStatus = errors.New("unexpected status")
+
+// Backend is an HTTP implementation of the Backend, compatible with WebDAV servers and simple HTTP caches.
+type Backend struct {
+	logger log.Logger
+
+	base            *url.URL
+	headers         nethttp.Header
+	listMethod      string
+	chunkedUpload   bool
+	makeCollections bool
+	client          *nethttp.Client
+
+	// username, password and token are set only if the credentials are read from files, to read them per request.
+	username string
+	password *common.SecretFile
+	token    *common.SecretFile
+}
+
+// New creates an HTTP backend.
+func New(l log.Logger, c Config) (*Backend, error) {
+	base, err := url.Parse(c.URL)
+	if err != nil {
+		return nil, fmt.Errorf("parse base url <%s>, %w", c.URL, err)
+	}
+
+	if base.Scheme != "http" && base.Scheme != "https" {
+		return nil, fmt.Errorf("base url <%s> must be an http or https url", c.URL)
+	}
+
+	base.Path = strings.TrimSuffix(base.Path, "/")
+
+	headers := nethttp.Header{}
+
+	for _, h := range c.Headers {
+		name, value, ok := strings.Cut(h, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("header <%s> must be in <name: value> form", h)
+		}
+
+		headers.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	var password, token *common.SecretFile
+
+	switch {
+	case c.BearerTokenFile != "":
+		if token, err = common.NewSecretFile("", c.BearerTokenFile); err != nil {
+			return nil, fmt.Errorf("bearer token, %w", err)
+		}
+	case c.BearerToken != "":
+		headers.Set("Authorization", "Bearer "+string(c.BearerToken))
+	case c.Username != "" && c.PasswordFile != "":
+		if password, err = common.NewSecretFile("", c.PasswordFile); err != nil {
+			return nil, fmt.Errorf("password, %w", err)
+		}
+	case c.Username != "":
+		base.User = url.UserPassword(c.Username, string(c.Password))
+	}
+
+	listMethod := c.ListMethod
+	if listMethod == "" {
+		listMethod = ListPropfind
+	}
+
+	if listMethod != ListPropfind && listMethod != ListJSON {
+		return nil, fmt.Errorf("unknown list method <%s>", c.ListMethod)
+	}
+
+	client, err := c.Transport.NewClient()
+	if err != nil {
+		return nil, fmt.Errorf("http transport, %w", err)
+	}
+
+	level.Debug(l).Log("msg", "http backend", "url", base.Redacted(), "list_method", listMethod)
+
+	return &Backend{
+		logger:          l,
+		base:            base,
+		headers:         headers,
+		listMethod:      listMethod,
+		chunkedUpload:   c.ChunkedUpload,
+		makeCollections: c.MakeCollections,
+		client:          client,
+		username:        c.Username,
+		password:        password,
+		token:           token,
+	}, nil
+}
+
+// Get writes downloaded content to the given writer.
+func (b *Backend) Get(ctx context.Context, p string, w io.Writer) error {
+	resp, err := b.do(ctx, nethttp.MethodGet, b.url(p), nil, nil)
+	if err != nil {
+		return fmt.Errorf("get the object, %w", err)
+	}
+
+	defer internal.CloseWithErrLogf(b.logger, resp.Body, "response body, close defer")
+
+	if err := expect(resp, nethttp.StatusOK); err != nil {
+		return fmt.Errorf("get the object, %w", err)
+	}
+
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("copy the object, %w", err)
+	}
+
+	return nil
+}
+
+// Put uploads contents of the given reader.
+func (b *Backend) Put(ctx context.Context, p string, r io.Reader) error {
+	if b.makeCollections {
+		if err := b.mkcol(ctx, path.Dir(strings.TrimPrefix(p, "/"))); err != nil {
+			return fmt.Errorf("create parent collections, %w", err)
+		}
+	}
+
+	var size int64 = -1
+
+	if !b.chunkedUpload {
+		f, err := os.CreateTemp("", "drone-cache-upload-*")
+		if err != nil {
+			return fmt.Errorf("create temporary upload file, %w", err)
+		}
+
+		defer os.Remove(f.Name())
+		defer internal.CloseWithErrLogf(b.logger, f, "temporary upload file, close defer")
+
+		if size, err = io.Copy(f, r); err != nil {
+			return fmt.Errorf("spool upload to temporary file, %w", err)
+		}
+
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("rewind temporary upload file, %w", err)
+		}
+
+		r = f
+	}
+
+	resp, err := b.do(ctx, nethttp.MethodPut, b.url(p), r, func(req *nethttp.Request) {
+		req.ContentLength = size
+		req.Header.Set("Content-Type", "application/octet-stream")
+	})
+	if err != nil {
+		return fmt.Errorf("put the object, %w", err)
+	}
+
+	defer internal.CloseWithErrLogf(b.logger, resp.Body, "response body, close defer")
+
+	if err := expect(resp, nethttp.StatusOK, nethttp.StatusCreated, nethttp.StatusNoContent); err != nil {
+		return fmt.Errorf("put the object, %w", err)
+	}
+
+	return nil
+}
+
+// Exists checks if object already exists.
+func (b *Backend) Exists(ctx context.Context, p string) (bool, error) {
+	resp, err := b.do(ctx, nethttp.MethodHead, b.url(p), nil, nil)
+	if err != nil {
+		return false, fmt.Errorf("check the object exists, %w", err)
+	}
+
+	defer internal.CloseWithErrLogf(b.logger, resp.Body, "response body, close defer")
+
+	if resp.StatusCode == nethttp.StatusNotFound {
+		return false, nil
+	}
+
+	if err := expect(resp, nethttp.StatusOK); err != nil {
+		return false, fmt.Errorf("check the object exists, %w", err)
+	}
+
+	return true, nil
+}
+
+// List lists all the objects that are the given path or under it, recursively.
+func (b *Backend) List(ctx context.Context, p string) ([]common.FileEntry, error) {
+	var (
+		entries []common.FileEntry
+		err     error
+	)
+
+	switch b.listMethod {
+	case ListJSON:
+		entries, err = b.listJSON(ctx, strings.Trim(p, "/"))
+	default:
+		entries, err = b.listPropfind(ctx, strings.Trim(p, "/"))
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("list the objects, %w", err)
+	}
+
+	return entries, nil
+}
+
+// Delete deletes the object with the given path.
+func (b *Backend) Delete(ctx context.Context, p string) error {
+	resp, err := b.do(ctx, nethttp.MethodDelete, b.url(p), nil, nil)
+	if err != nil {
+		return fmt.Errorf("delete the object, %w", err)
+	}
+
+	defer internal.CloseWithErrLogf(b.logger, resp.Body, "response body, close defer")
+
+	if err := expect(resp, nethttp.StatusOK, nethttp.StatusAccepted, nethttp.StatusNoContent); err != nil {
+		return fmt.Errorf("delete the object, %w", err)
+	}
+
+	return nil
+}
+
+// Helpers
+
+func (b *Backend) url(p string) *url.URL {
+	u := *b.base
+	u.Path = b.base.Path + "/" + strings.TrimPrefix(p, "/")
+
+	return &u
+}
+
+func (b *Backend) do(
+	ctx context.Context, method string, u *url.URL, body io.Reader, modify func(*nethttp.Request),
+) (*nethttp.Response, error) {
+	req, err := nethttp.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("create request, %w", err)
+	}
+
+	for name, values := range b.headers {
+		req.Header[name] = values
+	}
+
+	if err := b.authorize(req); err != nil {
+		return nil, err
+	}
+
+	if modify != nil {
+		modify(req)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s <%s>, %w", method, u.Redacted(), err)
+	}
+
+	return resp, nil
+}
+
+// authorize sets the credentials read from files, which are read again when the files change.
+func (b *Backend) authorize(req *nethttp.Request) error {
+	switch {
+	case b.token != nil:
+		token, err := b.token.Read()
+		if err != nil {
+			return fmt.Errorf("read bearer token, %w", err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+string(token))
+	case b.password != nil:
+		password, err := b.password.Read()
+		if err != nil {
+			return fmt.Errorf("read password, %w", err)
+		}
+
+		req.SetBasicAuth(b.username, string(password))
+	}
+
+	return nil
+}
+
+// mkcol creates the given collection and its parents, if they do not exist yet.
+func (b *Backend) mkcol(ctx context.Context, dir string) error {
+	if dir == "." || dir == "" {
+		return nil
+	}
+
+	parts := strings.Split(dir, "/")
+	for i := range parts {
+		u := b.url(strings.Join(parts[:i+1], "/") + "/")
+
+		resp, err := b.do(ctx, methodMkcol, u, nil, nil)
+		if err != nil {
+			return err
+		}
+
+		internal.CloseWithErrLogf(b.logger, resp.Body, "response body, close")
+
+		// NOTICE: Existing collections are reported as 405 Method Not Allowed.
+		if err := expect(resp, nethttp.StatusCreated, nethttp.StatusMethodNotAllowed); err != nil {
+			return fmt.Errorf("create collection <%s>, %w", u.Path, err)
+		}
+	}
+
+	return nil
+}
+
+func expect(resp *nethttp.Response, codes ...int) error {
+	for _, code := range codes {
+		if resp.StatusCode == code {
+			return nil
+		}
+	}
+
+	return common.ClassifyStatus(resp.StatusCode,
+		fmt.Errorf("%s %s, %s, %w", resp.Request.Method, resp.Request.URL.Redacted(), resp.Status, ErrUnexpectedStatus))
+}
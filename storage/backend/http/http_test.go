@@ -0,0 +1,281 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	nethttp "net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"golang.org/x/net/webdav"
+
+	"github.com/meltwater/drone-cache/storage/backend/backendtest"
+	"github.com/meltwater/drone-cache/test"
+)
+
+func TestRoundTripWebDAV(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu      sync.Mutex
+		headers []nethttp.Header
+	)
+
+	dav := &webdav.Handler{FileSystem: webdav.NewMemFS(), LockSystem: webdav.NewMemLS()}
+	srv := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		mu.Lock()
+		headers = append(headers, r.Header.Clone())
+		mu.Unlock()
+
+		dav.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	backend, err := New(log.NewNopLogger(), Config{
+		URL:             srv.URL + "/",
+		BearerToken:     "s3cr3t",
+		Headers:         []string{"X-Cache-Tenant: drone"},
+		ChunkedUpload:   true,
+		MakeCollections: true,
+	})
+	test.Ok(t, err)
+
+	roundTrip(t, backend)
+
+	for _, h := range headers {
+		test.Equals(t, "Bearer s3cr3t", h.Get("Authorization"))
+		test.Equals(t, "drone", h.Get("X-Cache-Tenant"))
+	}
+}
+
+func TestRoundTripJSON(t *testing.T) {
+	t.Parallel()
+
+	store := &autoindexStore{objects: map[string][]byte{}}
+	srv := httptest.NewServer(store)
+	t.Cleanup(srv.Close)
+
+	backend, err := New(log.NewNopLogger(), Config{
+		URL:        srv.URL + "/cache",
+		Username:   "drone",
+		Password:   "s3cr3t",
+		ListMethod: ListJSON,
+	})
+	test.Ok(t, err)
+
+	roundTrip(t, backend)
+
+	test.Equals(t, false, store.chunked)
+	test.Equals(t, "drone", store.username)
+}
+
+func TestBearerTokenFileRotation(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu             sync.Mutex
+		authorizations []string
+	)
+
+	srv := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		mu.Lock()
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+		mu.Unlock()
+
+		w.WriteHeader(nethttp.StatusNotFound)
+	}))
+	t.Cleanup(srv.Close)
+
+	path := filepath.Join(t.TempDir(), "token")
+	test.Ok(t, os.WriteFile(path, []byte("first\n"), 0o600))
+
+	backend, err := New(log.NewNopLogger(), Config{URL: srv.URL, BearerTokenFile: path})
+	test.Ok(t, err)
+
+	ctx := context.Background()
+
+	_, err = backend.Exists(ctx, "object")
+	test.Ok(t, err)
+
+	test.Ok(t, os.WriteFile(path, []byte("second\n"), 0o600))
+	test.Ok(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+
+	_, err = backend.Exists(ctx, "object")
+	test.Ok(t, err)
+
+	test.Equals(t, []string{"Bearer first", "Bearer second"}, authorizations)
+}
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	t.Run("WebDAV", func(t *testing.T) {
+		t.Parallel()
+
+		dav := &webdav.Handler{FileSystem: webdav.NewMemFS(), LockSystem: webdav.NewMemLS()}
+		srv := httptest.NewServer(dav)
+		t.Cleanup(srv.Close)
+
+		backend, err := New(log.NewNopLogger(), Config{URL: srv.URL, MakeCollections: true})
+		test.Ok(t, err)
+
+		backendtest.Run(t, backend)
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		t.Parallel()
+
+		srv := httptest.NewServer(&autoindexStore{objects: map[string][]byte{}})
+		t.Cleanup(srv.Close)
+
+		backend, err := New(log.NewNopLogger(), Config{URL: srv.URL + "/cache", ListMethod: ListJSON})
+		test.Ok(t, err)
+
+		backendtest.Run(t, backend)
+	})
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range []Config{
+		{URL: "ftp://example.com"},
+		{URL: "http://example.com", Headers: []string{"invalid"}},
+		{URL: "http://example.com", ListMethod: "ls"},
+	} {
+		_, err := New(log.NewNopLogger(), c)
+		test.NotOk(t, err)
+	}
+}
+
+// Helpers
+
+func roundTrip(t *testing.T, backend *Backend) {
+	t.Helper()
+
+	content := "Hello world4"
+
+	test.Ok(t, backend.Put(context.TODO(), "repo/key/test.t", strings.NewReader(content)))
+	test.Ok(t, backend.Put(context.TODO(), "repo/other/test.t", strings.NewReader(content)))
+
+	var buf bytes.Buffer
+	test.Ok(t, backend.Get(context.TODO(), "repo/key/test.t", &buf))
+	test.Equals(t, content, buf.String())
+
+	exists, err := backend.Exists(context.TODO(), "repo/key/test.t")
+	test.Ok(t, err)
+	test.Equals(t, true, exists)
+
+	entries, err := backend.List(context.TODO(), "repo")
+	test.Ok(t, err)
+	test.Equals(t, 2, len(entries))
+
+	entries, err = backend.List(context.TODO(), "repo/key/test.t")
+	test.Ok(t, err)
+	test.Equals(t, 1, len(entries))
+	test.Equals(t, "repo/key/test.t", entries[0].Path)
+	test.Equals(t, int64(len(content)), entries[0].Size)
+
+	entries, err = backend.List(context.TODO(), "missing")
+	test.Ok(t, err)
+	test.Equals(t, 0, len(entries))
+
+	test.Ok(t, backend.Delete(context.TODO(), "repo/key/test.t"))
+
+	exists, err = backend.Exists(context.TODO(), "repo/key/test.t")
+	test.Ok(t, err)
+	test.Equals(t, false, exists)
+
+	test.NotOk(t, backend.Get(context.TODO(), "repo/key/test.t", &buf))
+}
+
+// autoindexStore mimics nginx with dav_methods and autoindex_format json.
+type autoindexStore struct {
+	mu       sync.Mutex
+	objects  map[string][]byte
+	chunked  bool
+	username string
+}
+
+func (s *autoindexStore) ServeHTTP(w nethttp.ResponseWriter, r *nethttp.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.username, _, _ = r.BasicAuth()
+	p := strings.TrimPrefix(r.URL.Path, "/cache/")
+
+	switch r.Method {
+	case nethttp.MethodPut:
+		s.chunked = s.chunked || len(r.TransferEncoding) > 0
+		b, _ := io.ReadAll(r.Body)
+		s.objects[p] = b
+
+		w.WriteHeader(nethttp.StatusCreated)
+	case nethttp.MethodGet, nethttp.MethodHead:
+		if b, ok := s.objects[p]; ok {
+			w.Header().Set("Last-Modified", time.Now().UTC().Format(nethttp.TimeFormat))
+			w.Write(b) // nolint: errcheck
+
+			return
+		}
+
+		if !strings.HasSuffix(p, "/") && p != "" {
+			nethttp.NotFound(w, r)
+
+			return
+		}
+
+		s.index(w, r, p)
+	case nethttp.MethodDelete:
+		delete(s.objects, p)
+		w.WriteHeader(nethttp.StatusNoContent)
+	default:
+		w.WriteHeader(nethttp.StatusMethodNotAllowed)
+	}
+}
+
+func (s *autoindexStore) index(w nethttp.ResponseWriter, r *nethttp.Request, dir string) {
+	var (
+		items = []autoindexEntry{}
+		seen  = map[string]bool{}
+	)
+
+	for name, b := range s.objects {
+		if !strings.HasPrefix(name, dir) {
+			continue
+		}
+
+		rest := strings.TrimPrefix(name, dir)
+		if i := strings.Index(rest, "/"); i >= 0 {
+			if !seen[rest[:i]] {
+				seen[rest[:i]] = true
+				items = append(items, autoindexEntry{Name: rest[:i], Type: "directory"})
+			}
+
+			continue
+		}
+
+		items = append(items, autoindexEntry{
+			Name:  rest,
+			Type:  "file",
+			MTime: time.Now().UTC().Format(nethttp.TimeFormat),
+			Size:  int64(len(b)),
+		})
+	}
+
+	if len(items) == 0 && dir != "" {
+		nethttp.NotFound(w, r)
+
+		return
+	}
+
+	json.NewEncoder(w).Encode(items) // nolint: errcheck
+}
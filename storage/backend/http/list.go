@@ -0,0 +1,207 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	nethttp "net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/meltwater/drone-cache/internal"
+	"github.com/meltwater/drone-cache/storage/common"
+)
+
+const (
+	methodPropfind = "PROPFIND"
+
+	propfindBody = `<?xml version="1.0" encoding="utf-8"?>` +
+		`<D:propfind xmlns:D="DAV:"><D:prop><D:resourcetype/><D:getcontentlength/><D:getlastmodified/></D:prop></D:propfind>`
+)
+
+type multistatus struct {
+	Responses []struct {
+		Href     string `xml:"href"`
+		Propstat []struct {
+			Prop struct {
+				ResourceType struct {
+					Collection *struct{} `xml:"collection"`
+				} `xml:"resourcetype"`
+				ContentLength int64  `xml:"getcontentlength"`
+				LastModified  string `xml:"getlastmodified"`
+			} `xml:"prop"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+// autoindexEntry is an item of nginx autoindex JSON listing.
+type autoindexEntry struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	MTime string `json:"mtime"`
+	Size  int64  `json:"size"`
+}
+
+// listPropfind walks the collections under the given path one level at a time,
+// since many servers refuse "Depth: infinity" requests.
+func (b *Backend) listPropfind(ctx context.Context, p string) ([]common.FileEntry, error) {
+	resp, err := b.do(ctx, methodPropfind, b.url(p), strings.NewReader(propfindBody), func(req *nethttp.Request) {
+		req.Header.Set("Depth", "1")
+		req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	defer internal.CloseWithErrLogf(b.logger, resp.Body, "response body, close defer")
+
+	if resp.StatusCode == nethttp.StatusNotFound {
+		return nil, nil
+	}
+
+	if err := expect(resp, nethttp.StatusMultiStatus); err != nil {
+		return nil, err
+	}
+
+	var ms multistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("decode multistatus response, %w", err)
+	}
+
+	var entries []common.FileEntry
+
+	for _, r := range ms.Responses {
+		rel, err := b.relative(r.Href)
+		if err != nil {
+			return nil, err
+		}
+
+		var (
+			collection bool
+			entry      = common.FileEntry{Path: rel}
+		)
+
+		for _, ps := range r.Propstat {
+			if ps.Prop.ResourceType.Collection != nil {
+				collection = true
+			}
+
+			if ps.Prop.ContentLength > 0 {
+				entry.Size = ps.Prop.ContentLength
+			}
+
+			if t, err := nethttp.ParseTime(ps.Prop.LastModified); err == nil {
+				entry.LastModified = t
+			}
+		}
+
+		switch {
+		case collection && rel == p:
+			continue
+		case collection:
+			children, err := b.listPropfind(ctx, rel)
+			if err != nil {
+				return nil, err
+			}
+
+			entries = append(entries, children...)
+		case common.InPrefix(rel, p):
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+func (b *Backend) listJSON(ctx context.Context, p string) ([]common.FileEntry, error) {
+	resp, err := b.do(ctx, nethttp.MethodGet, b.url(p+"/"), nil, func(req *nethttp.Request) {
+		req.Header.Set("Accept", "application/json")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	defer internal.CloseWithErrLogf(b.logger, resp.Body, "response body, close defer")
+
+	if resp.StatusCode == nethttp.StatusNotFound {
+		// Given path might be an object itself.
+		return b.stat(ctx, p)
+	}
+
+	if err := expect(resp, nethttp.StatusOK); err != nil {
+		return nil, err
+	}
+
+	var items []autoindexEntry
+	if err := json.NewDecoder(resp.Body).Decode(&items); err != nil {
+		return nil, fmt.Errorf("decode json listing, %w", err)
+	}
+
+	var entries []common.FileEntry
+
+	for _, item := range items {
+		rel := strings.TrimPrefix(path.Join(p, item.Name), "/")
+
+		if item.Type == "directory" {
+			children, err := b.listJSON(ctx, rel)
+			if err != nil {
+				return nil, err
+			}
+
+			entries = append(entries, children...)
+
+			continue
+		}
+
+		entry := common.FileEntry{Path: rel, Size: item.Size}
+		if t, err := nethttp.ParseTime(item.MTime); err == nil {
+			entry.LastModified = t
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func (b *Backend) stat(ctx context.Context, p string) ([]common.FileEntry, error) {
+	if p == "" {
+		return nil, nil
+	}
+
+	resp, err := b.do(ctx, nethttp.MethodHead, b.url(p), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer internal.CloseWithErrLogf(b.logger, resp.Body, "response body, close defer")
+
+	if resp.StatusCode == nethttp.StatusNotFound {
+		return nil, nil
+	}
+
+	if err := expect(resp, nethttp.StatusOK); err != nil {
+		return nil, err
+	}
+
+	entry := common.FileEntry{Path: p, Size: resp.ContentLength}
+	if t, err := nethttp.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		entry.LastModified = t
+	}
+
+	return []common.FileEntry{entry}, nil
+}
+
+// relative returns the object path of the given href, which is either an absolute URL or an absolute path.
+func (b *Backend) relative(href string) (string, error) {
+	u, err := url.Parse(href)
+	if err != nil {
+		return "", fmt.Errorf("parse href <%s>, %w", href, err)
+	}
+
+	rel := strings.TrimPrefix(u.Path, b.base.Path)
+
+	return strings.Trim(rel, "/"), nil
+}
@@ -0,0 +1,143 @@
+// Package memory provides a Backend that keeps objects in memory, for tests and short-lived processes.
+package memory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/meltwater/drone-cache/storage/common"
+)
+
+// Backend is an in-memory implementation of the Backend.
+// Objects become visible only after they are completely read, as they would with an atomic upload.
+type Backend struct {
+	mu      sync.RWMutex
+	objects map[string]object
+}
+
+type object struct {
+	data     []byte
+	modified time.Time
+}
+
+// New creates an in-memory backend.
+func New() *Backend {
+	return &Backend{objects: map[string]object{}}
+}
+
+// Get writes downloaded content to the given writer.
+func (b *Backend) Get(ctx context.Context, p string, w io.Writer) error {
+	if err := ctx.Err(); err != nil {
+		return err // nolint: wrapcheck
+	}
+
+	b.mu.RLock()
+	o, ok := b.objects[key(p)]
+	b.mu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("object <%s>, %w", p, common.ErrNotFound)
+	}
+
+	if _, err := io.Copy(w, &ctxReader{ctx: ctx, r: bytes.NewReader(o.data)}); err != nil {
+		return fmt.Errorf("copy the object, %w", err)
+	}
+
+	return nil
+}
+
+// Put uploads contents of the given reader.
+func (b *Backend) Put(ctx context.Context, p string, r io.Reader) error {
+	data, err := io.ReadAll(&ctxReader{ctx: ctx, r: r})
+	if err != nil {
+		return fmt.Errorf("read the object, %w", err)
+	}
+
+	b.mu.Lock()
+	b.objects[key(p)] = object{data: data, modified: time.Now()}
+	b.mu.Unlock()
+
+	return nil
+}
+
+// Exists checks if object already exists.
+func (b *Backend) Exists(ctx context.Context, p string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err // nolint: wrapcheck
+	}
+
+	b.mu.RLock()
+	_, ok := b.objects[key(p)]
+	b.mu.RUnlock()
+
+	return ok, nil
+}
+
+// List lists all the objects that are the given path or under it, recursively, ordered by their paths.
+func (b *Backend) List(ctx context.Context, p string) ([]common.FileEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err // nolint: wrapcheck
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var entries []common.FileEntry
+
+	for path, o := range b.objects {
+		if common.InPrefix(path, p) {
+			entries = append(entries, common.FileEntry{Path: path, Size: int64(len(o.data)), LastModified: o.modified})
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+
+	return entries, nil
+}
+
+// Delete deletes the object with the given path.
+func (b *Backend) Delete(ctx context.Context, p string) error {
+	if err := ctx.Err(); err != nil {
+		return err // nolint: wrapcheck
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.objects[key(p)]; !ok {
+		return fmt.Errorf("object <%s>, %w", p, common.ErrNotFound)
+	}
+
+	delete(b.objects, key(p))
+
+	return nil
+}
+
+// Helpers
+
+func key(p string) string {
+	for len(p) > 0 && p[0] == '/' {
+		p = p[1:]
+	}
+
+	return p
+}
+
+// ctxReader stops reading once the context is done, so that long streams can be canceled.
+type ctxReader struct {
+	ctx context.Context // nolint: containedctx
+	r   io.Reader
+}
+
+func (r *ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err // nolint: wrapcheck
+	}
+
+	return r.r.Read(p) // nolint: wrapcheck
+}
@@ -0,0 +1,32 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/meltwater/drone-cache/storage/backend/backendtest"
+	"github.com/meltwater/drone-cache/storage/common"
+	"github.com/meltwater/drone-cache/test"
+)
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	backendtest.Run(t, New())
+}
+
+func TestMissing(t *testing.T) {
+	t.Parallel()
+
+	backend := New()
+
+	var buf bytes.Buffer
+	test.Expected(t, backend.Get(context.TODO(), "repo/key", &buf), common.ErrNotFound)
+	test.Expected(t, backend.Delete(context.TODO(), "repo/key"), common.ErrNotFound)
+
+	test.Ok(t, backend.Put(context.TODO(), "/repo/key", strings.NewReader("Hello world4")))
+	test.Ok(t, backend.Get(context.TODO(), "repo/key", &buf))
+	test.Equals(t, "Hello world4", buf.String())
+}
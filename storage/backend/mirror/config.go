@@ -0,0 +1,24 @@
+package mirror
+
+import "github.com/meltwater/drone-cache/storage/common"
+
+// Config is a structure to store mirror backend configuration.
+type Config struct {
+	// WriteQuorum is the number of backends a put must succeed on. Zero means all of them.
+	WriteQuorum int
+}
+
+// Validate reports every problem of the configuration of a mirror of the given number of backends.
+func (c Config) Validate(backends int) error {
+	var v common.Validation
+
+	if backends < 1 {
+		v.Addf("at least one backend is required")
+	}
+
+	if c.WriteQuorum < 0 || c.WriteQuorum > backends {
+		v.Addf("write quorum <%d> must be between 0 and the number of backends <%d>", c.WriteQuorum, backends)
+	}
+
+	return v.Err()
+}
@@ -0,0 +1,270 @@
+package mirror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+
+	"github.com/meltwater/drone-cache/internal"
+	"github.com/meltwater/drone-cache/storage/common"
+)
+
+// ErrQuorumNotReached means that an object could not be written to enough backends.
+var ErrQuorumNotReached = errors.New("write quorum not reached")
+
+// Backend is a composite backend that writes every object to all of the given backends,
+// and reads from the first healthy one in priority order.
+type Backend struct {
+	logger log.Logger
+
+	backends    []common.Backend
+	writeQuorum int
+}
+
+// New creates a mirror backend of the given backends, in priority order.
+func New(l log.Logger, c Config, backends ...common.Backend) (*Backend, error) {
+	if len(backends) == 0 {
+		return nil, errors.New("at least one backend is required")
+	}
+
+	quorum := c.WriteQuorum
+	if quorum <= 0 {
+		quorum = len(backends)
+	}
+
+	if quorum > len(backends) {
+		return nil, fmt.Errorf("write quorum <%d> is greater than number of backends <%d>", quorum, len(backends))
+	}
+
+	level.Debug(l).Log("msg", "mirror backend", "backends", len(backends), "write_quorum", quorum)
+
+	return &Backend{logger: l, backends: backends, writeQuorum: quorum}, nil
+}
+
+// Get writes downloaded content to the given writer.
+// Backends are tried in priority order, until one of them fails before writing anything.
+func (b *Backend) Get(ctx context.Context, p string, w io.Writer) error {
+	errs := &internal.MultiError{}
+
+	for i, backend := range b.backends {
+		cw := &countingWriter{w: w}
+
+		err := backend.Get(ctx, p, cw)
+		if err == nil {
+			return nil
+		}
+
+		if cw.n > 0 {
+			// Content is partially written already, it can not be recovered from another backend.
+			return fmt.Errorf("get from backend <%d>, %w", i, err)
+		}
+
+		level.Warn(b.logger).Log("msg", "get from backend failed, trying next", "backend", i, "path", p, "err", err)
+		errs.Add(fmt.Errorf("get from backend <%d>, %w", i, err))
+	}
+
+	return errs.Err()
+}
+
+// Put uploads contents of the given reader to all backends in parallel.
+// It succeeds when at least write quorum of backends succeed.
+func (b *Backend) Put(ctx context.Context, p string, r io.Reader) error {
+	var (
+		wg      sync.WaitGroup
+		results = make([]error, len(b.backends))
+		writers = make([]io.Writer, len(b.backends))
+		pipes   = make([]*io.PipeWriter, len(b.backends))
+	)
+
+	for i, backend := range b.backends {
+		pr, pw := io.Pipe()
+		pipes[i] = pw
+		writers[i] = &bestEffortWriter{w: pw}
+
+		wg.Add(1)
+
+		go func(i int, backend common.Backend) {
+			defer wg.Done()
+
+			err := backend.Put(ctx, p, pr)
+			// Unblock remaining writes, when backend stops reading early.
+			pr.CloseWithError(err) // nolint: errcheck
+
+			results[i] = err
+		}(i, backend)
+	}
+
+	_, err := io.Copy(io.MultiWriter(writers...), r)
+	for _, pw := range pipes {
+		pw.CloseWithError(err) // nolint: errcheck
+	}
+
+	wg.Wait()
+
+	if err != nil {
+		return fmt.Errorf("read content, %w", err)
+	}
+
+	var (
+		succeeded int
+		errs      = &internal.MultiError{}
+	)
+
+	for i, err := range results {
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "put to backend failed", "backend", i, "path", p, "err", err)
+			errs.Add(fmt.Errorf("put to backend <%d>, %w", i, err))
+
+			continue
+		}
+
+		succeeded++
+	}
+
+	if succeeded < b.writeQuorum {
+		errs.Add(fmt.Errorf("%d of %d succeeded, %w", succeeded, b.writeQuorum, ErrQuorumNotReached))
+
+		return errs.Err()
+	}
+
+	return nil
+}
+
+// Exists checks if object exists in any of the healthy backends.
+func (b *Backend) Exists(ctx context.Context, p string) (bool, error) {
+	var (
+		healthy bool
+		errs    = &internal.MultiError{}
+	)
+
+	for i, backend := range b.backends {
+		exists, err := backend.Exists(ctx, p)
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "check existence failed, trying next", "backend", i, "path", p, "err", err)
+			errs.Add(fmt.Errorf("check existence in backend <%d>, %w", i, err))
+
+			continue
+		}
+
+		if exists {
+			return true, nil
+		}
+
+		healthy = true
+	}
+
+	if healthy {
+		return false, nil
+	}
+
+	return false, errs.Err()
+}
+
+// List lists all the objects that are the given path or under it, recursively, from the first healthy backend.
+func (b *Backend) List(ctx context.Context, p string) ([]common.FileEntry, error) {
+	errs := &internal.MultiError{}
+
+	for i, backend := range b.backends {
+		entries, err := backend.List(ctx, p)
+		if err == nil {
+			return entries, nil
+		}
+
+		level.Warn(b.logger).Log("msg", "list failed, trying next", "backend", i, "path", p, "err", err)
+		errs.Add(fmt.Errorf("list backend <%d>, %w", i, err))
+	}
+
+	return nil, errs.Err()
+}
+
+// Delete deletes the object with the given path from all backends.
+func (b *Backend) Delete(ctx context.Context, p string) error {
+	errs := &internal.MultiError{}
+
+	for i, backend := range b.backends {
+		if err := backend.Delete(ctx, p); err != nil {
+			errs.Add(fmt.Errorf("delete from backend <%d>, %w", i, err))
+		}
+	}
+
+	return errs.Err()
+}
+
+// Close closes all backends which support it.
+func (b *Backend) Close() error {
+	errs := &internal.MultiError{}
+
+	for i, backend := range b.backends {
+		if c, ok := backend.(io.Closer); ok {
+			if err := c.Close(); err != nil {
+				errs.Add(fmt.Errorf("close backend <%d>, %w", i, err))
+			}
+		}
+	}
+
+	return errs.Err()
+}
+
+// Unavailable returns a member that fails every operation with the given error, for backends that could not be
+// initialized, e.g. as their servers are unreachable, so that they count against the write quorum as failed members.
+func Unavailable(err error) common.Backend {
+	return unavailable{err: err}
+}
+
+// Helpers
+
+type unavailable struct {
+	err error
+}
+
+func (u unavailable) Get(context.Context, string, io.Writer) error { return u.unavailable() }
+
+func (u unavailable) Put(context.Context, string, io.Reader) error { return u.unavailable() }
+
+func (u unavailable) Exists(context.Context, string) (bool, error) { return false, u.unavailable() }
+
+func (u unavailable) List(context.Context, string) ([]common.FileEntry, error) {
+	return nil, u.unavailable()
+}
+
+func (u unavailable) Delete(context.Context, string) error { return u.unavailable() }
+
+func (u unavailable) unavailable() error {
+	return fmt.Errorf("backend is unavailable, %w", u.err)
+}
+
+// countingWriter counts bytes written to the underlying writer.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+
+	return n, err // nolint: wrapcheck
+}
+
+// bestEffortWriter stops writing to the underlying writer after the first failure, without failing the others.
+type bestEffortWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (b *bestEffortWriter) Write(p []byte) (int, error) {
+	if b.err != nil {
+		return len(p), nil
+	}
+
+	if _, err := b.w.Write(p); err != nil {
+		b.err = err
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,119 @@
+package mirror
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+
+	"github.com/meltwater/drone-cache/storage/backend/backendtest"
+	"github.com/meltwater/drone-cache/storage/backend/filesystem"
+	"github.com/meltwater/drone-cache/storage/common"
+	"github.com/meltwater/drone-cache/test"
+)
+
+var errUnavailable = errors.New("unavailable")
+
+func TestRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	primary, secondary := setup(t), setup(t)
+
+	backend, err := New(log.NewNopLogger(), Config{}, primary, secondary)
+	test.Ok(t, err)
+
+	content := "Hello world4"
+	test.Ok(t, backend.Put(context.TODO(), "test.t", strings.NewReader(content)))
+
+	for _, b := range []common.Backend{primary, secondary, backend} {
+		var buf bytes.Buffer
+		test.Ok(t, b.Get(context.TODO(), "test.t", &buf))
+		test.Equals(t, content, buf.String())
+	}
+
+	exists, err := backend.Exists(context.TODO(), "test.t")
+	test.Ok(t, err)
+	test.Equals(t, true, exists)
+
+	test.Ok(t, backend.Delete(context.TODO(), "test.t"))
+
+	exists, err = backend.Exists(context.TODO(), "test.t")
+	test.Ok(t, err)
+	test.Equals(t, false, exists)
+}
+
+func TestReadFailover(t *testing.T) {
+	t.Parallel()
+
+	secondary := setup(t)
+	test.Ok(t, secondary.Put(context.TODO(), "test.t", strings.NewReader("Hello world4")))
+
+	backend, err := New(log.NewNopLogger(), Config{}, Unavailable(errUnavailable), secondary)
+	test.Ok(t, err)
+
+	var buf bytes.Buffer
+	test.Ok(t, backend.Get(context.TODO(), "test.t", &buf))
+	test.Equals(t, "Hello world4", buf.String())
+
+	exists, err := backend.Exists(context.TODO(), "test.t")
+	test.Ok(t, err)
+	test.Equals(t, true, exists)
+
+	entries, err := backend.List(context.TODO(), "")
+	test.Ok(t, err)
+	test.Equals(t, 1, len(entries))
+
+	backend, err = New(log.NewNopLogger(), Config{}, Unavailable(errUnavailable))
+	test.Ok(t, err)
+
+	_, err = backend.Exists(context.TODO(), "test.t")
+	test.NotOk(t, err)
+}
+
+func TestWriteQuorum(t *testing.T) {
+	t.Parallel()
+
+	healthy := setup(t)
+
+	backend, err := New(log.NewNopLogger(), Config{WriteQuorum: 1}, Unavailable(errUnavailable), healthy)
+	test.Ok(t, err)
+	test.Ok(t, backend.Put(context.TODO(), "test.t", strings.NewReader("Hello world4")))
+
+	exists, err := healthy.Exists(context.TODO(), "test.t")
+	test.Ok(t, err)
+	test.Equals(t, true, exists)
+
+	backend, err = New(log.NewNopLogger(), Config{}, Unavailable(errUnavailable), healthy)
+	test.Ok(t, err)
+
+	err = backend.Put(context.TODO(), "test.t", strings.NewReader("Hello world4"))
+	test.NotOk(t, err)
+	test.Assert(t, strings.Contains(err.Error(), ErrQuorumNotReached.Error()), "unexpected error: %v", err)
+
+	_, err = New(log.NewNopLogger(), Config{WriteQuorum: 3}, Unavailable(errUnavailable), healthy)
+	test.NotOk(t, err)
+}
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	backend, err := New(log.NewNopLogger(), Config{}, setup(t), setup(t))
+	test.Ok(t, err)
+
+	backendtest.Run(t, backend)
+}
+
+// Helpers
+
+func setup(t *testing.T) *filesystem.Backend {
+	dir, cleanUp := test.CreateTempDir(t, "mirror-test")
+	t.Cleanup(cleanUp)
+
+	b, err := filesystem.New(log.NewNopLogger(), filesystem.Config{CacheRoot: dir})
+	test.Ok(t, err)
+
+	return b
+}
@@ -0,0 +1,43 @@
+package oci
+
+import (
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/meltwater/drone-cache/storage/common"
+)
+
+// Config is a structure to store OCI registry backend configuration.
+type Config struct {
+	// Repository is the repository to store caches in, e.g. registry.example.com/ci/caches.
+	Repository string
+	// Username and Password are used when given, otherwise credentials are read from the docker config
+	// and its credential helpers.
+	Username string
+	Password common.Secret
+	// PasswordFile is a file to read the password from, instead of Password. It is read again when it changes.
+	PasswordFile string
+	// Insecure allows plain http connections to the registry.
+	Insecure bool
+
+	// Transport configures the HTTP transport, e.g. the CA bundle, the client certificate and the proxy.
+	Transport common.TransportConfig
+}
+
+// Validate reports every problem of the configuration.
+func (c Config) Validate() error {
+	var v common.Validation
+
+	v.Required("repository", c.Repository)
+
+	if c.Repository != "" {
+		if _, err := name.NewRepository(c.Repository); err != nil {
+			v.Addf("repository <%s> is malformed, %v", c.Repository, err)
+		}
+	}
+
+	v.Together("username", "password", c.Username != "", c.Password != "" || c.PasswordFile != "")
+	v.SecretFile("password", c.Password, c.PasswordFile)
+
+	v.Merge("transport", c.Transport.Validate())
+
+	return v.Err()
+}
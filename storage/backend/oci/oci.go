@@ -0,0 +1,454 @@
+package oci
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+
+	"github.com/meltwater/drone-cache/internal"
+	"github.com/meltwater/drone-cache/storage/common"
+)
+
+const (
+	// ArtifactType is the artifact type of the manifests of stored caches.
+	ArtifactType = "application/vnd.drone-cache.v1"
+	// LayerMediaType is the media type of the blobs of stored caches.
+	LayerMediaType types.MediaType = "application/vnd.drone-cache.archive.v1"
+
+	// AnnotationPath keeps the object path, since tags can not represent it.
+	AnnotationPath = "io.drone-cache.path"
+
+	annotationCreated = "org.opencontainers.image.created"
+
+	emptyMediaType types.MediaType = "application/vnd.oci.empty.v1+json"
+
+	maxTagLength  = 128
+	tagHashLength = 16
+)
+
+// Backend is an OCI registry implementation of the Backend, that stores objects as artifacts.
+type Backend struct {
+	logger log.Logger
+
+	repo    name.Repository
+	options []remote.Option
+}
+
+// New creates an OCI registry backend.
+func New(l log.Logger, c Config) (*Backend, error) {
+	var opts []name.Option
+	if c.Insecure {
+		opts = append(opts, name.Insecure)
+	}
+
+	repo, err := name.NewRepository(c.Repository, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("parse repository <%s>, %w", c.Repository, err)
+	}
+
+	var options []remote.Option
+
+	switch {
+	case c.Username != "" && c.PasswordFile != "":
+		password, err := common.NewSecretFile("", c.PasswordFile)
+		if err != nil {
+			return nil, fmt.Errorf("password, %w", err)
+		}
+
+		options = append(options, remote.WithAuth(&fileAuthenticator{username: c.Username, password: password}))
+	case c.Username != "":
+		options = append(options, remote.WithAuth(&authn.Basic{Username: c.Username, Password: string(c.Password)}))
+	default:
+		options = append(options, remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	}
+
+	if !c.Transport.IsZero() {
+		t, err := c.Transport.NewTransport()
+		if err != nil {
+			return nil, fmt.Errorf("transport, %w", err)
+		}
+
+		options = append(options, remote.WithTransport(t))
+	}
+
+	level.Debug(l).Log("msg", "oci registry backend", "repository", repo.String(), "insecure", c.Insecure)
+
+	return &Backend{logger: l, repo: repo, options: options}, nil
+}
+
+// Get writes downloaded content to the given writer.
+func (b *Backend) Get(ctx context.Context, p string, w io.Writer) error {
+	m, err := b.manifest(ctx, b.repo.Tag(Tag(p)))
+	if err != nil {
+		return fmt.Errorf("get the manifest, %w", classify(err))
+	}
+
+	if len(m.Layers) != 1 {
+		return fmt.Errorf("manifest of <%s> has <%d> layers, expected exactly one", p, len(m.Layers))
+	}
+
+	layer, err := remote.Layer(b.repo.Digest(m.Layers[0].Digest.String()), b.opts(ctx)...)
+	if err != nil {
+		return fmt.Errorf("get the blob, %w", classify(err))
+	}
+
+	rc, err := layer.Compressed()
+	if err != nil {
+		return fmt.Errorf("read the blob, %w", classify(err))
+	}
+
+	defer internal.CloseWithErrLogf(b.logger, rc, "blob, close defer")
+
+	if _, err := io.Copy(w, rc); err != nil {
+		return fmt.Errorf("copy the blob, %w", classify(err))
+	}
+
+	return nil
+}
+
+// Put uploads contents of the given reader as a blob, and tags an artifact manifest referring it.
+func (b *Backend) Put(ctx context.Context, p string, r io.Reader) error {
+	layer, err := newFileLayer(r)
+	if err != nil {
+		return fmt.Errorf("spool the blob, %w", classify(err))
+	}
+
+	defer internal.CloseWithErrLogf(b.logger, layer, "spooled blob, close defer")
+
+	cfg := static.NewLayer([]byte("{}"), emptyMediaType)
+
+	for _, l := range []v1.Layer{cfg, layer} {
+		if err := remote.WriteLayer(b.repo, l, b.opts(ctx)...); err != nil {
+			return fmt.Errorf("upload the blob, %w", classify(err))
+		}
+	}
+
+	cfgDesc, err := describe(cfg)
+	if err != nil {
+		return err
+	}
+
+	layerDesc, err := describe(layer)
+	if err != nil {
+		return err
+	}
+
+	raw, err := json.Marshal(manifest{
+		SchemaVersion: 2, // nolint: gomnd
+		MediaType:     types.OCIManifestSchema1,
+		ArtifactType:  ArtifactType,
+		Config:        cfgDesc,
+		Layers:        []v1.Descriptor{layerDesc},
+		Annotations: map[string]string{
+			AnnotationPath:    p,
+			annotationCreated: time.Now().UTC().Format(time.RFC3339),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("marshal the manifest, %w", classify(err))
+	}
+
+	if err := remote.Put(b.repo.Tag(Tag(p)), rawManifest(raw), b.opts(ctx)...); err != nil {
+		return fmt.Errorf("put the manifest, %w", classify(err))
+	}
+
+	return nil
+}
+
+// Exists checks if object already exists.
+func (b *Backend) Exists(ctx context.Context, p string) (bool, error) {
+	_, err := remote.Head(b.repo.Tag(Tag(p)), b.opts(ctx)...)
+	if isNotFound(err) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("check the manifest exists, %w", classify(err))
+	}
+
+	return true, nil
+}
+
+// List lists all the objects that are the given path or under it, recursively.
+func (b *Backend) List(ctx context.Context, p string) ([]common.FileEntry, error) {
+	tags, err := remote.List(b.repo, b.opts(ctx)...)
+	if isNotFound(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("list the tags, %w", classify(err))
+	}
+
+	// Tags start with the sanitized path, which rules out most of them without fetching their manifests.
+	prefix := sanitize(strings.Trim(p, "/"))
+	if len(prefix) > maxTagLength-tagHashLength-1 {
+		prefix = ""
+	}
+
+	var entries []common.FileEntry
+
+	for _, t := range tags {
+		if !strings.HasPrefix(t, prefix) {
+			continue
+		}
+
+		m, err := b.manifest(ctx, b.repo.Tag(t))
+		if err != nil {
+			return nil, fmt.Errorf("get the manifest of <%s>, %w", t, err)
+		}
+
+		path, ok := m.Annotations[AnnotationPath]
+		if !ok || m.ArtifactType != ArtifactType || !common.InPrefix(path, p) {
+			continue
+		}
+
+		entry := common.FileEntry{Path: path}
+
+		for _, l := range m.Layers {
+			entry.Size += l.Size
+		}
+
+		if created, err := time.Parse(time.RFC3339, m.Annotations[annotationCreated]); err == nil {
+			entry.LastModified = created
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// Delete deletes the manifest of the object with the given path.
+// NOTICE: Blobs are left to the garbage collection of the registry.
+func (b *Backend) Delete(ctx context.Context, p string) error {
+	ref := b.repo.Tag(Tag(p))
+
+	desc, err := remote.Head(ref, b.opts(ctx)...)
+	if err != nil {
+		return fmt.Errorf("resolve the manifest, %w", classify(err))
+	}
+
+	// Registries either untag on tag deletion (OCI 1.1) or drop the tags of a deleted manifest, try both.
+	if err := remote.Delete(ref, b.opts(ctx)...); err != nil && !isNotFound(err) {
+		level.Debug(b.logger).Log("msg", "delete by tag is not supported, deleting by digest", "tag", ref, "err", err)
+	}
+
+	err = remote.Delete(b.repo.Digest(desc.Digest.String()), b.opts(ctx)...)
+	if err != nil && !isNotFound(err) {
+		return fmt.Errorf("delete the manifest, %w", classify(err))
+	}
+
+	return nil
+}
+
+// Tag returns the tag that the object with the given path is stored with.
+// Tags consist of the sanitized path and a hash of it, to keep them both readable and unique.
+func Tag(p string) string {
+	sum := sha256.Sum256([]byte(p))
+	hash := hex.EncodeToString(sum[:])[:tagHashLength]
+
+	tag := sanitize(strings.Trim(p, "/"))
+	if max := maxTagLength - tagHashLength - 1; len(tag) > max {
+		tag = tag[:max]
+	}
+
+	return tag + "-" + hash
+}
+
+// Helpers
+
+// manifest is an OCI image manifest carrying an artifact type, as OCI 1.1 artifacts do.
+type manifest struct {
+	SchemaVersion int64             `json:"schemaVersion"`
+	MediaType     types.MediaType   `json:"mediaType"`
+	ArtifactType  string            `json:"artifactType,omitempty"`
+	Config        v1.Descriptor     `json:"config"`
+	Layers        []v1.Descriptor   `json:"layers"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+type rawManifest []byte
+
+func (r rawManifest) RawManifest() ([]byte, error) { return r, nil }
+
+func (r rawManifest) MediaType() (types.MediaType, error) { return types.OCIManifestSchema1, nil }
+
+func (b *Backend) opts(ctx context.Context) []remote.Option {
+	return append([]remote.Option{remote.WithContext(ctx)}, b.options...)
+}
+
+func (b *Backend) manifest(ctx context.Context, ref name.Reference) (*manifest, error) {
+	desc, err := remote.Get(ref, b.opts(ctx)...)
+	if err != nil {
+		return nil, err // nolint: wrapcheck
+	}
+
+	var m manifest
+	if err := json.Unmarshal(desc.Manifest, &m); err != nil {
+		return nil, fmt.Errorf("parse the manifest, %w", err)
+	}
+
+	return &m, nil
+}
+
+func describe(l v1.Layer) (v1.Descriptor, error) {
+	digest, err := l.Digest()
+	if err != nil {
+		return v1.Descriptor{}, fmt.Errorf("layer digest, %w", err)
+	}
+
+	size, err := l.Size()
+	if err != nil {
+		return v1.Descriptor{}, fmt.Errorf("layer size, %w", err)
+	}
+
+	mt, err := l.MediaType()
+	if err != nil {
+		return v1.Descriptor{}, fmt.Errorf("layer media type, %w", err)
+	}
+
+	return v1.Descriptor{MediaType: mt, Size: size, Digest: digest}, nil
+}
+
+// sanitize replaces characters which are not allowed in tags.
+func sanitize(s string) string {
+	b := []byte(s)
+
+	for i, c := range b {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '_':
+		case (c == '.' || c == '-') && i > 0:
+		default:
+			b[i] = '_'
+		}
+	}
+
+	return string(b)
+}
+
+// classify classifies the errors of the registry, and of the verification of downloaded blobs.
+func classify(err error) error {
+	var terr *transport.Error
+	if !errors.As(err, &terr) {
+		// NOTICE: Verification errors of downloaded blobs are not exported.
+		if strings.Contains(err.Error(), "error verifying") {
+			return common.NewError(common.ErrIntegrity, err)
+		}
+
+		return common.Classify(err)
+	}
+
+	for _, d := range terr.Errors {
+		switch d.Code { // nolint: exhaustive
+		case transport.ManifestUnknownErrorCode, transport.BlobUnknownErrorCode, transport.NameUnknownErrorCode:
+			return common.NewError(common.ErrNotFound, err)
+		case transport.UnauthorizedErrorCode, transport.DeniedErrorCode:
+			return common.NewError(common.ErrUnauthorized, err)
+		case transport.DigestInvalidErrorCode, transport.SizeInvalidErrorCode:
+			return common.NewError(common.ErrIntegrity, err)
+		case transport.TooManyRequestsErrorCode:
+			return common.NewError(common.ErrQuotaExceeded, err)
+		}
+	}
+
+	return common.ClassifyStatus(terr.StatusCode, err)
+}
+
+func isNotFound(err error) bool {
+	var terr *transport.Error
+
+	return errors.As(err, &terr) && terr.StatusCode == http.StatusNotFound
+}
+
+// fileLayer is a layer spooled to a temporary file, since blobs must be addressed by their digest before upload.
+type fileLayer struct {
+	f      *os.File
+	digest v1.Hash
+	size   int64
+
+	once sync.Once
+}
+
+func newFileLayer(r io.Reader) (*fileLayer, error) {
+	f, err := os.CreateTemp("", "drone-cache-blob-*")
+	if err != nil {
+		return nil, fmt.Errorf("create temporary file, %w", err)
+	}
+
+	h := sha256.New()
+
+	size, err := io.Copy(io.MultiWriter(f, h), r)
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+
+		return nil, fmt.Errorf("write temporary file, %w", err)
+	}
+
+	return &fileLayer{
+		f:      f,
+		digest: v1.Hash{Algorithm: "sha256", Hex: hex.EncodeToString(h.Sum(nil))},
+		size:   size,
+	}, nil
+}
+
+func (l *fileLayer) Digest() (v1.Hash, error) { return l.digest, nil }
+
+func (l *fileLayer) DiffID() (v1.Hash, error) { return l.digest, nil }
+
+func (l *fileLayer) Size() (int64, error) { return l.size, nil }
+
+func (l *fileLayer) MediaType() (types.MediaType, error) { return LayerMediaType, nil }
+
+func (l *fileLayer) Compressed() (io.ReadCloser, error) {
+	return os.Open(l.f.Name()) // nolint: wrapcheck
+}
+
+func (l *fileLayer) Uncompressed() (io.ReadCloser, error) { return l.Compressed() }
+
+func (l *fileLayer) Close() error {
+	var err error
+
+	l.once.Do(func() {
+		err = l.f.Close()
+		os.Remove(l.f.Name())
+	})
+
+	return err // nolint: wrapcheck
+}
+
+// fileAuthenticator implements authn.Authenticator for a password read from a file,
+// which is read again when the file changes.
+type fileAuthenticator struct {
+	username string
+	password *common.SecretFile
+}
+
+func (a *fileAuthenticator) Authorization() (*authn.AuthConfig, error) {
+	password, err := a.password.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read password, %w", err)
+	}
+
+	return &authn.AuthConfig{Username: a.username, Password: string(password)}, nil
+}
@@ -0,0 +1,100 @@
+package oci
+
+import (
+	"bytes"
+	"context"
+	"io"
+	stdlog "log"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/google/go-containerregistry/pkg/registry"
+
+	"github.com/meltwater/drone-cache/storage/backend/backendtest"
+	"github.com/meltwater/drone-cache/test"
+)
+
+func TestRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	backend := setup(t)
+
+	content := "Hello world4"
+	test.Ok(t, backend.Put(context.TODO(), "repo/key/test.t", strings.NewReader(content)))
+	test.Ok(t, backend.Put(context.TODO(), "repo/other/test.t", strings.NewReader(content)))
+	test.Ok(t, backend.Put(context.TODO(), "repository/key/test.t", strings.NewReader(content)))
+
+	var buf bytes.Buffer
+	test.Ok(t, backend.Get(context.TODO(), "repo/key/test.t", &buf))
+	test.Equals(t, content, buf.String())
+
+	exists, err := backend.Exists(context.TODO(), "repo/key/test.t")
+	test.Ok(t, err)
+	test.Equals(t, true, exists)
+
+	exists, err = backend.Exists(context.TODO(), "missing")
+	test.Ok(t, err)
+	test.Equals(t, false, exists)
+
+	entries, err := backend.List(context.TODO(), "repo")
+	test.Ok(t, err)
+	test.Equals(t, 2, len(entries))
+
+	for _, e := range entries {
+		test.Assert(t, strings.HasPrefix(e.Path, "repo/"), "unexpected entry: %s", e.Path)
+		test.Equals(t, int64(len(content)), e.Size)
+		test.Assert(t, !e.LastModified.IsZero(), "missing last modified of %s", e.Path)
+	}
+
+	test.Ok(t, backend.Delete(context.TODO(), "repo/key/test.t"))
+
+	exists, err = backend.Exists(context.TODO(), "repo/key/test.t")
+	test.Ok(t, err)
+	test.Equals(t, false, exists)
+
+	test.NotOk(t, backend.Get(context.TODO(), "repo/key/test.t", &buf))
+}
+
+func TestTag(t *testing.T) {
+	t.Parallel()
+
+	valid := regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9._-]{0,127}$`)
+
+	for _, p := range []string{
+		"repo/key/test.t",
+		"/absolute/path",
+		".hidden",
+		"-dash",
+		strings.Repeat("very-long-path/", 20),
+	} {
+		tag := Tag(p)
+		test.Assert(t, valid.MatchString(tag), "invalid tag <%s> for <%s>", tag, p)
+	}
+
+	test.Assert(t, Tag("a/b") != Tag("a_b"), "tags collide")
+	test.Assert(t, strings.HasPrefix(Tag("repo/key/test.t"), "repo_key_test.t-"), "unexpected tag %s", Tag("repo/key/test.t"))
+}
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	backendtest.Run(t, setup(t), backendtest.WithLargeObjectSize(4<<20))
+}
+
+// Helpers
+
+func setup(t *testing.T) *Backend {
+	srv := httptest.NewServer(registry.New(registry.Logger(stdlog.New(io.Discard, "", 0))))
+	t.Cleanup(srv.Close)
+
+	b, err := New(log.NewNopLogger(), Config{
+		Repository: strings.TrimPrefix(srv.URL, "http://") + "/ci/caches",
+		Insecure:   true,
+	})
+	test.Ok(t, err)
+
+	return b
+}
@@ -0,0 +1,51 @@
+package redis
+
+import (
+	"net"
+	"time"
+
+	"github.com/meltwater/drone-cache/storage/common"
+)
+
+// Config is a structure to store Redis backend configuration.
+type Config struct {
+	Addr     string
+	Username string
+	Password common.Secret
+	// PasswordFile is a file to read the password from, instead of Password. It is read again for every connection.
+	PasswordFile string
+	DB           int
+	// TLS enables TLS connections to the server.
+	TLS bool
+	// KeyPrefix is prepended to all keys.
+	KeyPrefix string
+	// TTL expires objects after the given duration since they are stored. Zero means they never expire.
+	TTL time.Duration
+	// MaxSize is the maximum size of an object in bytes. Zero means unlimited.
+	MaxSize int64
+	// ChunkSize is the size of the values that objects are split into.
+	ChunkSize int
+}
+
+// Validate reports every problem of the configuration.
+func (c Config) Validate() error {
+	var v common.Validation
+
+	v.Required("address", c.Addr)
+	v.SecretFile("password", c.Password, c.PasswordFile)
+
+	if c.Addr != "" {
+		if _, port, err := net.SplitHostPort(c.Addr); err != nil {
+			v.Addf("address <%s> is not in <host>:<port> form", c.Addr)
+		} else {
+			v.Port("address port", port)
+		}
+	}
+
+	v.NonNegative("db", int64(c.DB))
+	v.NonNegative("ttl", int64(c.TTL))
+	v.NonNegative("max size", c.MaxSize)
+	v.NonNegative("chunk size", int64(c.ChunkSize))
+
+	return v.Err()
+}
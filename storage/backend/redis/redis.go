@@ -0,0 +1,430 @@
+package redis
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/meltwater/drone-cache/storage/common"
+)
+
+const (
+	// DefaultChunkSize is the default size of the values that objects are split into.
+	DefaultChunkSize = 1 << 20
+	// DefaultKeyPrefix is the default prefix of all keys.
+	DefaultKeyPrefix = "drone-cache"
+	// DefaultMaxSize is the default maximum size of an object of the plugin, as Redis is meant for small caches.
+	DefaultMaxSize = 64 << 20
+
+	// chunkTTLGrace keeps chunks a bit longer than their metadata, so that objects never expire partially.
+	chunkTTLGrace = time.Minute
+	// staleChunkTTL keeps chunks of overwritten and deleted objects a while, so that reads in flight can finish.
+	staleChunkTTL = 10 * time.Minute
+	scanCount     = 1000
+
+	fieldSize       = "size"
+	fieldChunks     = "chunks"
+	fieldGeneration = "generation"
+	fieldModified   = "modified"
+)
+
+// swapScript replaces the metadata of an object and returns the generation and chunks of the replaced one,
+// so that concurrent puts of the same object never lose track of the chunks of a generation.
+// nolint: gochecknoglobals
+var swapScript = goredis.NewScript(`
+local previous = redis.call('HMGET', KEYS[1], 'generation', 'chunks')
+redis.call('DEL', KEYS[1])
+redis.call('HSET', KEYS[1], 'size', ARGV[1], 'chunks', ARGV[2], 'generation', ARGV[3], 'modified', ARGV[4])
+if tonumber(ARGV[5]) > 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[5])
+end
+return previous
+`)
+
+// deleteScript deletes the metadata of an object and returns the generation and chunks of it.
+// nolint: gochecknoglobals
+var deleteScript = goredis.NewScript(`
+local previous = redis.call('HMGET', KEYS[1], 'generation', 'chunks')
+redis.call('DEL', KEYS[1])
+return previous
+`)
+
+var (
+	// ErrObjectTooLarge means that the object exceeds the maximum object size, it is classified as common.ErrQuotaExceeded.
+	ErrObjectTooLarge = common.NewError(common.ErrQuotaExceeded, errors.New("object too large"))
+	// ErrObjectNotFound means that the object does not exist or it has expired, it is classified as common.ErrNotFound.
+	ErrObjectNotFound = common.NewError(common.ErrNotFound, errors.New("object not found"))
+)
+
+// Backend is a Redis implementation of the Backend, that stores objects chunked across keys.
+//
+// Every object consists of a metadata hash and chunk keys of its current generation.
+// Overwriting an object writes the chunks of a new generation and swaps the metadata atomically,
+// chunks of the previous generation expire after a while instead of being deleted,
+// so that reads that started before the swap still find them.
+type Backend struct {
+	logger log.Logger
+
+	client    goredis.UniversalClient
+	prefix    string
+	ttl       time.Duration
+	maxSize   int64
+	chunkSize int
+}
+
+// New creates a Redis backend.
+func New(l log.Logger, c Config) (*Backend, error) {
+	if c.Addr == "" {
+		return nil, errors.New("redis address is required")
+	}
+
+	opts := &goredis.Options{
+		Addr:     c.Addr,
+		Username: c.Username,
+		Password: string(c.Password),
+		DB:       c.DB,
+	}
+
+	if c.PasswordFile != "" {
+		password, err := common.NewSecretFile("", c.PasswordFile)
+		if err != nil {
+			return nil, fmt.Errorf("password, %w", err)
+		}
+
+		// NOTICE: Password is read for every connection, so that a rotated password is used by the connections opened later.
+		opts.CredentialsProvider = func() (string, string) {
+			secret, _ := password.Read()
+
+			return c.Username, string(secret)
+		}
+	}
+
+	if c.TLS {
+		opts.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	prefix := c.KeyPrefix
+	if prefix == "" {
+		prefix = DefaultKeyPrefix
+	}
+
+	chunkSize := c.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	level.Debug(l).Log("msg", "redis backend", "addr", c.Addr, "tls", c.TLS, "ttl", c.TTL, "max_size", c.MaxSize)
+
+	return &Backend{
+		logger:    l,
+		client:    goredis.NewClient(opts),
+		prefix:    prefix,
+		ttl:       c.TTL,
+		maxSize:   c.MaxSize,
+		chunkSize: chunkSize,
+	}, nil
+}
+
+// Get writes downloaded content to the given writer.
+func (b *Backend) Get(ctx context.Context, p string, w io.Writer) error {
+	meta, err := b.meta(ctx, p)
+	if err != nil {
+		return fmt.Errorf("get the object metadata, %w", err)
+	}
+
+	for i := 0; i < meta.chunks; i++ {
+		chunk, err := b.client.Get(ctx, b.chunkKey(p, meta.generation, i)).Bytes()
+		if errors.Is(err, goredis.Nil) {
+			return fmt.Errorf("chunk <%d> of <%s> is gone, %w", i, p, ErrObjectNotFound)
+		}
+
+		if err != nil {
+			return fmt.Errorf("get chunk <%d>, %w", i, classify(err))
+		}
+
+		if _, err := w.Write(chunk); err != nil {
+			return fmt.Errorf("write chunk <%d>, %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// Put uploads contents of the given reader.
+func (b *Backend) Put(ctx context.Context, p string, r io.Reader) error {
+	var (
+		// NOTICE: Random part keeps puts of the same object that start at the same time apart.
+		generation = strconv.FormatInt(time.Now().UnixNano(), 36) + "-" + strconv.FormatInt(rand.Int63(), 36) // nolint: gomnd // #nosec G404 not a secret
+		buf        = make([]byte, b.chunkSize)
+		size       int64
+		chunks     int
+	)
+
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			size += int64(n)
+
+			if b.maxSize > 0 && size > b.maxSize {
+				b.deleteChunks(p, generation, chunks)
+
+				return fmt.Errorf("<%s> exceeds <%d> bytes, %w", p, b.maxSize, ErrObjectTooLarge)
+			}
+
+			if err := b.client.Set(ctx, b.chunkKey(p, generation, chunks), buf[:n], b.chunkTTL()).Err(); err != nil {
+				b.deleteChunks(p, generation, chunks)
+
+				return fmt.Errorf("set chunk <%d>, %w", chunks, classify(err))
+			}
+
+			chunks++
+		}
+
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+
+		if err != nil {
+			b.deleteChunks(p, generation, chunks)
+
+			return fmt.Errorf("read the object, %w", err)
+		}
+	}
+
+	previous, err := b.swap(ctx, swapScript, p,
+		size, chunks, generation, time.Now().UnixNano(), b.ttl.Milliseconds(),
+	)
+	if err != nil {
+		b.deleteChunks(p, generation, chunks)
+
+		return fmt.Errorf("set the object metadata, %w", classify(err))
+	}
+
+	if previous != nil && previous.generation != generation {
+		b.expireChunks(p, previous.generation, previous.chunks)
+	}
+
+	return nil
+}
+
+// Exists checks if object already exists and has not expired.
+func (b *Backend) Exists(ctx context.Context, p string) (bool, error) {
+	n, err := b.client.Exists(ctx, b.metaKey(p)).Result()
+	if err != nil {
+		return false, fmt.Errorf("check the object exists, %w", classify(err))
+	}
+
+	return n > 0, nil
+}
+
+// List lists all the objects that are the given path or under it, recursively, that have not expired.
+func (b *Backend) List(ctx context.Context, p string) ([]common.FileEntry, error) {
+	var (
+		entries []common.FileEntry
+		prefix  = b.metaKey("")
+		match   = prefix + escapeGlob(strings.Trim(p, "/")) + "*"
+		iter    = b.client.Scan(ctx, 0, match, scanCount).Iterator()
+	)
+
+	for iter.Next(ctx) {
+		path := strings.TrimPrefix(iter.Val(), prefix)
+		if !common.InPrefix(path, p) {
+			continue
+		}
+
+		meta, err := b.meta(ctx, path)
+		if errors.Is(err, ErrObjectNotFound) {
+			// Expired meanwhile.
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("get the object metadata <%s>, %w", path, err)
+		}
+
+		entries = append(entries, common.FileEntry{Path: path, Size: meta.size, LastModified: meta.modified})
+	}
+
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("scan the objects, %w", classify(err))
+	}
+
+	return entries, nil
+}
+
+// Delete deletes the object with the given path.
+func (b *Backend) Delete(ctx context.Context, p string) error {
+	previous, err := b.swap(ctx, deleteScript, p)
+	if err != nil {
+		return fmt.Errorf("delete the object metadata, %w", classify(err))
+	}
+
+	if previous == nil {
+		return fmt.Errorf("<%s>, %w", p, ErrObjectNotFound)
+	}
+
+	b.expireChunks(p, previous.generation, previous.chunks)
+
+	return nil
+}
+
+// Close closes the connections to the server.
+func (b *Backend) Close() error {
+	return b.client.Close() // nolint: wrapcheck
+}
+
+// Helpers
+
+type meta struct {
+	size       int64
+	chunks     int
+	generation string
+	modified   time.Time
+}
+
+func (b *Backend) meta(ctx context.Context, p string) (*meta, error) {
+	fields, err := b.client.HGetAll(ctx, b.metaKey(p)).Result()
+	if err != nil {
+		return nil, classify(err)
+	}
+
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("<%s>, %w", p, ErrObjectNotFound)
+	}
+
+	var m meta
+
+	if m.size, err = strconv.ParseInt(fields[fieldSize], 10, 64); err != nil {
+		return nil, fmt.Errorf("parse size, %w", err)
+	}
+
+	if m.chunks, err = strconv.Atoi(fields[fieldChunks]); err != nil {
+		return nil, fmt.Errorf("parse chunks, %w", err)
+	}
+
+	modified, err := strconv.ParseInt(fields[fieldModified], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("parse modified, %w", err)
+	}
+
+	m.generation = fields[fieldGeneration]
+	m.modified = time.Unix(0, modified)
+
+	return &m, nil
+}
+
+// swap runs the given script on the metadata of the object, and returns the generation and chunks
+// of the metadata that it replaced, nil if there was none.
+func (b *Backend) swap(ctx context.Context, script *goredis.Script, p string, args ...interface{}) (*meta, error) {
+	res, err := script.Run(ctx, b.client, []string{b.metaKey(p)}, args...).Slice()
+	if err != nil {
+		return nil, err // nolint: wrapcheck
+	}
+
+	// NOTICE: Missing fields are replied as nil.
+	generation, _ := res[0].(string)
+	if generation == "" {
+		return nil, nil
+	}
+
+	chunks, err := strconv.Atoi(fmt.Sprint(res[1]))
+	if err != nil {
+		return nil, fmt.Errorf("parse chunks, %w", err)
+	}
+
+	return &meta{generation: generation, chunks: chunks}, nil
+}
+
+// expireChunks lets chunks that are no longer referenced expire after staleChunkTTL, on a best effort basis.
+func (b *Backend) expireChunks(p, generation string, chunks int) {
+	if chunks == 0 {
+		return
+	}
+
+	_, err := b.client.Pipelined(context.Background(), func(pipe goredis.Pipeliner) error {
+		for i := 0; i < chunks; i++ {
+			pipe.Expire(context.Background(), b.chunkKey(p, generation, i), staleChunkTTL)
+		}
+
+		return nil
+	})
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "expire chunks", "path", p, "generation", generation, "err", err)
+	}
+}
+
+// deleteChunks removes chunks on a best effort basis, leftovers expire along with their TTL.
+func (b *Backend) deleteChunks(p, generation string, chunks int) {
+	if chunks == 0 {
+		return
+	}
+
+	keys := make([]string, 0, chunks)
+	for i := 0; i < chunks; i++ {
+		keys = append(keys, b.chunkKey(p, generation, i))
+	}
+
+	if err := b.client.Del(context.Background(), keys...).Err(); err != nil {
+		level.Warn(b.logger).Log("msg", "delete chunks", "path", p, "generation", generation, "err", err)
+	}
+}
+
+func (b *Backend) chunkTTL() time.Duration {
+	if b.ttl <= 0 {
+		return 0
+	}
+
+	return b.ttl + chunkTTLGrace
+}
+
+func (b *Backend) metaKey(p string) string {
+	return b.prefix + ":meta:" + strings.TrimPrefix(p, "/")
+}
+
+func (b *Backend) chunkKey(p, generation string, i int) string {
+	return b.prefix + ":chunk:" + generation + ":" + strconv.Itoa(i) + ":" + strings.TrimPrefix(p, "/")
+}
+
+// escapeGlob escapes the special characters of the patterns of SCAN.
+func escapeGlob(s string) string {
+	var sb strings.Builder
+
+	for _, c := range s {
+		if strings.ContainsRune(`*?[]^\`, c) {
+			sb.WriteRune('\\')
+		}
+
+		sb.WriteRune(c)
+	}
+
+	return sb.String()
+}
+
+// classify classifies the errors replied by the server, e.g. for wrong credentials or when it is out of memory.
+func classify(err error) error {
+	var rerr goredis.Error
+	if !errors.As(err, &rerr) {
+		return common.Classify(err)
+	}
+
+	code, _, _ := strings.Cut(rerr.Error(), " ")
+
+	switch code {
+	case "NOAUTH", "WRONGPASS", "NOPERM":
+		return common.NewError(common.ErrUnauthorized, err)
+	case "OOM":
+		return common.NewError(common.ErrQuotaExceeded, err)
+	default:
+		return err
+	}
+}
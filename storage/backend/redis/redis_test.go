@@ -0,0 +1,170 @@
+package redis
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-kit/log"
+
+	"github.com/meltwater/drone-cache/storage/backend/backendtest"
+	"github.com/meltwater/drone-cache/storage/common"
+	"github.com/meltwater/drone-cache/test"
+)
+
+func TestRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	backend, srv := setup(t, Config{ChunkSize: 4})
+
+	content := "Hello world4"
+	test.Ok(t, backend.Put(context.TODO(), "repo/key/test.t", strings.NewReader(content)))
+	test.Ok(t, backend.Put(context.TODO(), "repo/other/test.t", strings.NewReader(content)))
+	test.Ok(t, backend.Put(context.TODO(), "repository/key/test.t", strings.NewReader(content)))
+
+	var buf bytes.Buffer
+	test.Ok(t, backend.Get(context.TODO(), "repo/key/test.t", &buf))
+	test.Equals(t, content, buf.String())
+
+	exists, err := backend.Exists(context.TODO(), "repo/key/test.t")
+	test.Ok(t, err)
+	test.Equals(t, true, exists)
+
+	entries, err := backend.List(context.TODO(), "repo")
+	test.Ok(t, err)
+	test.Equals(t, 2, len(entries))
+	test.Equals(t, int64(len(content)), entries[0].Size)
+
+	// Overwriting lets chunks of the previous generation expire, after reads in flight can finish.
+	keys := len(srv.Keys())
+	test.Ok(t, backend.Put(context.TODO(), "repo/key/test.t", strings.NewReader("Hello")))
+	test.Equals(t, keys+2, len(srv.Keys()))
+
+	srv.FastForward(staleChunkTTL)
+	test.Equals(t, keys-1, len(srv.Keys()))
+
+	buf.Reset()
+	test.Ok(t, backend.Get(context.TODO(), "repo/key/test.t", &buf))
+	test.Equals(t, "Hello", buf.String())
+
+	test.Ok(t, backend.Delete(context.TODO(), "repo/key/test.t"))
+	test.Expected(t, backend.Delete(context.TODO(), "repo/key/test.t"), ErrObjectNotFound)
+
+	srv.FastForward(staleChunkTTL)
+	test.Equals(t, keys-4, len(srv.Keys()))
+
+	exists, err = backend.Exists(context.TODO(), "repo/key/test.t")
+	test.Ok(t, err)
+	test.Equals(t, false, exists)
+
+	test.Expected(t, backend.Get(context.TODO(), "repo/key/test.t", &buf), ErrObjectNotFound)
+}
+
+func TestTTL(t *testing.T) {
+	t.Parallel()
+
+	backend, srv := setup(t, Config{TTL: time.Hour})
+
+	test.Ok(t, backend.Put(context.TODO(), "repo/key/test.t", strings.NewReader("Hello world4")))
+
+	srv.FastForward(2 * time.Hour)
+
+	exists, err := backend.Exists(context.TODO(), "repo/key/test.t")
+	test.Ok(t, err)
+	test.Equals(t, false, exists)
+
+	entries, err := backend.List(context.TODO(), "")
+	test.Ok(t, err)
+	test.Equals(t, 0, len(entries))
+	test.Equals(t, 0, len(srv.Keys()))
+}
+
+func TestConcurrentPut(t *testing.T) {
+	t.Parallel()
+
+	backend, srv := setup(t, Config{ChunkSize: 4})
+
+	var (
+		wg    sync.WaitGroup
+		errCh = make(chan error, 8)
+	)
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			errCh <- backend.Put(context.TODO(), "repo/key/test.t", strings.NewReader("Hello world4"))
+		}()
+	}
+
+	wg.Wait()
+	close(errCh)
+
+	for err := range errCh {
+		test.Ok(t, err)
+	}
+
+	var buf bytes.Buffer
+	test.Ok(t, backend.Get(context.TODO(), "repo/key/test.t", &buf))
+	test.Equals(t, "Hello world4", buf.String())
+
+	// Only the metadata and the chunks of the last generation are left, none of the others leak.
+	srv.FastForward(staleChunkTTL)
+	test.Equals(t, 4, len(srv.Keys()))
+}
+
+func TestMaxSize(t *testing.T) {
+	t.Parallel()
+
+	backend, srv := setup(t, Config{MaxSize: 8, ChunkSize: 4})
+
+	err := backend.Put(context.TODO(), "repo/key/test.t", strings.NewReader("Hello world4"))
+	test.Expected(t, err, ErrObjectTooLarge)
+	test.Expected(t, err, common.ErrQuotaExceeded)
+	test.Equals(t, 0, len(srv.Keys()))
+
+	test.Ok(t, backend.Put(context.TODO(), "repo/key/test.t", strings.NewReader("Hello")))
+}
+
+func TestACL(t *testing.T) {
+	t.Parallel()
+
+	srv := miniredis.RunT(t)
+	srv.RequireUserAuth("drone", "s3cr3t")
+
+	backend, err := New(log.NewNopLogger(), Config{Addr: srv.Addr(), Username: "drone", Password: "wrong"})
+	test.Ok(t, err)
+	test.Expected(t, backend.Put(context.TODO(), "test.t", strings.NewReader("Hello world4")), common.ErrUnauthorized)
+
+	backend, err = New(log.NewNopLogger(), Config{Addr: srv.Addr(), Username: "drone", Password: "s3cr3t"})
+	test.Ok(t, err)
+	test.Ok(t, backend.Put(context.TODO(), "test.t", strings.NewReader("Hello world4")))
+}
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	backend, _ := setup(t, Config{})
+
+	backendtest.Run(t, backend)
+}
+
+// Helpers
+
+func setup(t *testing.T, c Config) (*Backend, *miniredis.Miniredis) {
+	srv := miniredis.RunT(t)
+
+	c.Addr = srv.Addr()
+
+	b, err := New(log.NewNopLogger(), c)
+	test.Ok(t, err)
+	t.Cleanup(func() { b.Close() })
+
+	return b, srv
+}
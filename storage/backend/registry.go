@@ -0,0 +1,111 @@
+package backend
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/go-kit/log"
+
+	"github.com/meltwater/drone-cache/internal/settings"
+)
+
+// Factory creates a backend from the given configuration.
+type Factory func(l log.Logger, cfg Config) (Backend, error)
+
+// nolint: gochecknoglobals
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Factory{}
+	validators = map[string]func(cfg Config) error{}
+)
+
+// Register makes a backend available with the given type, both as the backend of the plugin and of composite backends.
+// It is meant to be called from init functions, and panics if the type is empty or already registered.
+func Register(backendType string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if backendType == "" || factory == nil {
+		panic("backend: register of empty backend type or nil factory")
+	}
+
+	if _, ok := registry[backendType]; ok {
+		panic("backend: register of already registered backend type " + backendType)
+	}
+
+	registry[backendType] = factory
+}
+
+// RegisterTyped registers a backend which is configured with its own type of configuration.
+// The configuration is decoded from Settings of Config, fields are named by their `setting` tag
+// or their lower-cased name, unknown settings are rejected.
+// If the type of the configuration has a Validate() error method, it is used to validate the configuration.
+func RegisterTyped[T any](backendType string, factory func(l log.Logger, c T) (Backend, error)) {
+	Register(backendType, func(l log.Logger, cfg Config) (Backend, error) {
+		c, err := decodeTyped[T](backendType, cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		return factory(log.With(l, "backend", backendType), c)
+	})
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	validators[backendType] = func(cfg Config) error {
+		c, err := decodeTyped[T](backendType, cfg)
+		if err != nil {
+			return err
+		}
+
+		if v, ok := interface{}(c).(interface{ Validate() error }); ok {
+			return v.Validate() // nolint: wrapcheck
+		}
+
+		return nil
+	}
+}
+
+// Registered returns the registered backend types in alphabetical order.
+func Registered() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	types := make([]string, 0, len(registry))
+	for t := range registry {
+		types = append(types, t)
+	}
+
+	sort.Strings(types)
+
+	return types
+}
+
+func lookup(backendType string) (Factory, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	f, ok := registry[backendType]
+
+	return f, ok
+}
+
+func lookupValidator(backendType string) (func(cfg Config) error, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	v, ok := validators[backendType]
+
+	return v, ok
+}
+
+func decodeTyped[T any](backendType string, cfg Config) (T, error) {
+	var c T
+	if err := settings.Decode(cfg.Settings, &c); err != nil {
+		return c, fmt.Errorf("decode settings of backend <%s>, %w", backendType, err)
+	}
+
+	return c, nil
+}
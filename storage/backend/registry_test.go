@@ -0,0 +1,65 @@
+package backend
+
+import (
+	"testing"
+
+	"github.com/go-kit/log"
+
+	"github.com/meltwater/drone-cache/internal/settings"
+	"github.com/meltwater/drone-cache/storage/backend/filesystem"
+	"github.com/meltwater/drone-cache/test"
+)
+
+type customConfig struct {
+	Root  string `setting:"root"`
+	Depth int
+}
+
+func TestRegisterTyped(t *testing.T) {
+	dir, cleanUp := test.CreateTempDir(t, "registry-test")
+	t.Cleanup(cleanUp)
+
+	var got customConfig
+
+	RegisterTyped("custom-typed", func(l log.Logger, c customConfig) (Backend, error) {
+		got = c
+
+		return filesystem.New(l, filesystem.Config{CacheRoot: c.Root}) // nolint: wrapcheck
+	})
+
+	b, err := FromConfig(log.NewNopLogger(), "custom-typed", Config{Settings: map[string]string{"root": dir, "depth": "2"}})
+	test.Ok(t, err)
+	test.Assert(t, b != nil, "expected backend")
+	test.Equals(t, customConfig{Root: dir, Depth: 2}, got)
+
+	_, err = FromConfig(log.NewNopLogger(), "custom-typed", Config{Settings: map[string]string{"rot": dir}})
+	test.Expected(t, err, settings.ErrUnknownSetting)
+
+	test.Assert(t, contains(Registered(), "custom-typed"), "custom backend is not registered: %v", Registered())
+	test.Assert(t, contains(Registered(), S3), "built-in backend is not registered: %v", Registered())
+}
+
+func TestRegisterDuplicate(t *testing.T) {
+	defer func() {
+		test.Assert(t, recover() != nil, "expected panic on duplicate registration")
+	}()
+
+	Register(FileSystem, func(l log.Logger, cfg Config) (Backend, error) { return nil, nil })
+}
+
+func TestFromConfigUnknown(t *testing.T) {
+	_, err := FromConfig(log.NewNopLogger(), "unknown", Config{})
+	test.Expected(t, err, ErrUnknownBackend)
+}
+
+// Helpers
+
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,5 +1,11 @@
 package s3
 
+import (
+	"strings"
+
+	"github.com/meltwater/drone-cache/storage/common"
+)
+
 // Config is a structure to store S3  backend configuration.
 type Config struct {
 	// Indicates the files ACL, which should be one,
@@ -27,9 +33,46 @@ type Config struct {
 	// ap-northeast-1
 	// sa-east-1
 	Region string
-	Secret string
+	Secret common.Secret
+
+	// KeyFile and SecretFile are files to read the access key and the secret key from, instead of Key and Secret.
+	// They are read again when they change, e.g. when an agent rotates them.
+	KeyFile    string
+	SecretFile string
 
 	PathStyle  bool // Use path style instead of domain style. Should be true for minio and false for AWS.
 	DisableSSL bool // Set SSL mode for connection to AWS S3. default is false.
 	Public     bool
+
+	// Transport configures the HTTP transport, e.g. the CA bundle, the client certificate and the proxy.
+	Transport common.TransportConfig
+}
+
+// Validate reports every problem of the configuration.
+func (c Config) Validate() error {
+	var v common.Validation
+
+	v.Required("bucket", c.Bucket)
+	v.Together("access key", "secret key", c.Key != "" || c.KeyFile != "", c.Secret != "" || c.SecretFile != "")
+	v.SecretFile("access key", common.Secret(c.Key), c.KeyFile)
+	v.SecretFile("secret key", c.Secret, c.SecretFile)
+	v.Endpoint("endpoint", c.Endpoint)
+	v.Endpoint("sts endpoint", c.StsEndpoint)
+
+	if c.ACL != "" {
+		v.OneOf("acl", c.ACL, "private", "public-read", "public-read-write", "authenticated-read",
+			"aws-exec-read", "bucket-owner-read", "bucket-owner-full-control")
+	}
+
+	if c.Encryption != "" {
+		v.OneOf("encryption", c.Encryption, "AES256", "aws:kms")
+	}
+
+	if c.RoleArn != "" && !strings.HasPrefix(c.RoleArn, "arn:") {
+		v.Addf("role arn <%s> is not an ARN", c.RoleArn)
+	}
+
+	v.Merge("transport", c.Transport.Validate())
+
+	return v.Err()
 }
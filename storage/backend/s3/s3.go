@@ -2,6 +2,7 @@ package s3
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -16,6 +17,7 @@ import (
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/meltwater/drone-cache/internal"
+	"github.com/meltwater/drone-cache/storage/common"
 )
 
 // Backend implements storage.Backend for AWs S3.
@@ -40,13 +42,27 @@ func New(l log.Logger, c Config, debug bool) (*Backend, error) {
 		S3ForcePathStyle: aws.Bool(c.PathStyle),
 	}
 
+	if !c.Transport.IsZero() {
+		client, err := c.Transport.NewClient()
+		if err != nil {
+			return nil, fmt.Errorf("s3 transport, %w", err)
+		}
+
+		conf.HTTPClient = client
+	}
+
 	// Use anonymous credentials if the S3 bucket is public
 	if c.Public {
 		conf.Credentials = credentials.AnonymousCredentials
 	}
 
-	if c.Key != "" && c.Secret != "" {
-		conf.Credentials = credentials.NewStaticCredentials(c.Key, c.Secret, "")
+	creds, err := newCredentials(c)
+	if err != nil {
+		return nil, fmt.Errorf("s3 credentials, %w", err)
+	}
+
+	if creds != nil {
+		conf.Credentials = creds
 	} else {
 		level.Warn(l).Log("msg", "aws key and/or Secret not provided (falling back to anonymous credentials)")
 	}
@@ -63,15 +79,23 @@ func New(l log.Logger, c Config, debug bool) (*Backend, error) {
 			stsConf.DisableSSL = nil
 		}
 
-		conf.Credentials = credentials.NewStaticCredentials(c.Key, c.Secret, "")
-		crds := assumeRole(l, stsConf, c.RoleArn)
-		conf.Credentials = credentials.NewStaticCredentials(crds.AccessKeyID, crds.SecretAccessKey, crds.SessionToken)
+		conf.Credentials = credentials.NewStaticCredentials(c.Key, string(c.Secret), "")
+		if creds != nil {
+			conf.Credentials = creds
+		}
+
+		// NOTICE: Credentials of the role are assumed again when they expire, with the credentials read from files then.
+		conf.Credentials = assumeRole(l, stsConf, c.RoleArn)
 	}
 
 	level.Debug(l).Log("msg", "s3 backend", "config", fmt.Sprintf("%#v", c))
 
 	if debug {
-		conf.WithLogLevel(aws.LogDebugWithHTTPBody)
+		// NOTICE: Only headers are dumped, bodies may hold credentials too (e.g. of STS), and headers are redacted.
+		conf.WithLogLevel(aws.LogDebug)
+		conf.Logger = aws.LoggerFunc(func(args ...interface{}) {
+			level.Debug(l).Log("msg", "aws sdk", "dump", internal.RedactHTTPDump(fmt.Sprint(args...)))
+		})
 	}
 
 	client := s3.New(session.Must(session.NewSessionWithOptions(session.Options{})), conf)
@@ -99,7 +123,7 @@ func (b *Backend) Get(ctx context.Context, p string, w io.Writer) error {
 
 		out, err := b.client.GetObjectWithContext(ctx, in)
 		if err != nil {
-			errCh <- fmt.Errorf("get the object, %w", err)
+			errCh <- fmt.Errorf("get the object, %w", classify(err))
 
 			return
 		}
@@ -108,7 +132,7 @@ func (b *Backend) Get(ctx context.Context, p string, w io.Writer) error {
 
 		_, err = io.Copy(w, out.Body)
 		if err != nil {
-			errCh <- fmt.Errorf("copy the object, %w", err)
+			errCh <- fmt.Errorf("copy the object, %w", classify(err))
 		}
 	}()
 
@@ -138,7 +162,7 @@ func (b *Backend) Put(ctx context.Context, p string, r io.Reader) error {
 	}
 
 	if _, err := uploader.UploadWithContext(ctx, in); err != nil {
-		return fmt.Errorf("put the object, %w", err)
+		return fmt.Errorf("put the object, %w", classify(err))
 	}
 
 	return nil
@@ -153,8 +177,8 @@ func (b *Backend) Exists(ctx context.Context, p string) (bool, error) {
 
 	out, err := b.client.HeadObjectWithContext(ctx, in)
 	if err != nil {
-		// nolint: errorlint
-		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == s3.ErrCodeNoSuchKey || awsErr.Code() == "NotFound" {
+		err = classify(err)
+		if errors.Is(err, common.ErrNotFound) {
 			return false, nil
 		}
 
@@ -166,26 +190,149 @@ func (b *Backend) Exists(ctx context.Context, p string) (bool, error) {
 	return *out.ETag != "", nil
 }
 
-func assumeRole(l log.Logger, c *aws.Config, roleArn string) credentials.Value {
+// List lists all the objects that are the given path or under it, recursively.
+func (b *Backend) List(ctx context.Context, p string) ([]common.FileEntry, error) {
+	in := &s3.ListObjectsV2Input{
+		Bucket: aws.String(b.bucket),
+		Prefix: aws.String(p),
+	}
+
+	var entries []common.FileEntry
+
+	if err := b.client.ListObjectsV2PagesWithContext(ctx, in, func(out *s3.ListObjectsV2Output, _ bool) bool {
+		for _, obj := range out.Contents {
+			if !common.InPrefix(aws.StringValue(obj.Key), p) {
+				continue
+			}
+
+			entries = append(entries, common.FileEntry{
+				Path:         aws.StringValue(obj.Key),
+				Size:         aws.Int64Value(obj.Size),
+				LastModified: aws.TimeValue(obj.LastModified),
+			})
+		}
+
+		return true
+	}); err != nil {
+		return nil, fmt.Errorf("list the objects, %w", classify(err))
+	}
+
+	return entries, nil
+}
+
+// Delete deletes the object with the given path.
+func (b *Backend) Delete(ctx context.Context, p string) error {
+	in := &s3.DeleteObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(p),
+	}
+
+	if _, err := b.client.DeleteObjectWithContext(ctx, in); err != nil {
+		return fmt.Errorf("delete the object, %w", classify(err))
+	}
+
+	return nil
+}
+
+// classify classifies the errors of S3 by their error codes, or by their status codes for responses without a body.
+func classify(err error) error {
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) {
+		return common.Classify(err)
+	}
+
+	switch aerr.Code() {
+	case s3.ErrCodeNoSuchKey, "NotFound":
+		return common.NewError(common.ErrNotFound, err)
+	case s3.ErrCodeNoSuchBucket:
+		// NOTICE: A missing bucket is a misconfiguration, not a cache miss that failure policies may ignore.
+		return common.NewError(common.ErrInvalidConfig, err)
+	case "AccessDenied", "AllAccessDisabled", "InvalidAccessKeyId", "SignatureDoesNotMatch", "ExpiredToken", "InvalidToken":
+		return common.NewError(common.ErrUnauthorized, err)
+	case "RequestTimeout":
+		return common.NewError(common.ErrTimeout, err)
+	case "BadDigest", "InvalidDigest", "XAmzContentSHA256Mismatch", "IncompleteBody":
+		return common.NewError(common.ErrIntegrity, err)
+	case "EntityTooLarge", "SlowDown":
+		return common.NewError(common.ErrQuotaExceeded, err)
+	}
+
+	// NOTICE: Canceled requests keep the error of the context as their original error.
+	if class := common.Class(aerr.OrigErr()); class != nil {
+		return common.NewError(class, err)
+	}
+
+	var rerr awserr.RequestFailure
+	if errors.As(err, &rerr) {
+		return common.ClassifyStatus(rerr.StatusCode(), err)
+	}
+
+	return err
+}
+
+// newCredentials returns the credentials of the given key and secret, or of the files to read them from, nil if none.
+// NOTICE: Credentials read from files expire when the files change, so that rotated keys are picked up mid-operation.
+func newCredentials(c Config) (*credentials.Credentials, error) {
+	if c.KeyFile == "" && c.SecretFile == "" {
+		if c.Key == "" || c.Secret == "" {
+			return nil, nil
+		}
+
+		return credentials.NewStaticCredentials(c.Key, string(c.Secret), ""), nil
+	}
+
+	key, err := common.NewSecretFile(common.Secret(c.Key), c.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := common.NewSecretFile(c.Secret, c.SecretFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return credentials.NewCredentials(&fileProvider{key: key, secret: secret}), nil
+}
+
+// fileProvider implements credentials.Provider for keys read from files.
+type fileProvider struct {
+	key, secret *common.SecretFile
+}
+
+func (p *fileProvider) Retrieve() (credentials.Value, error) {
+	key, err := p.key.Read()
+	if err != nil {
+		return credentials.Value{}, err
+	}
+
+	secret, err := p.secret.Read()
+	if err != nil {
+		return credentials.Value{}, err
+	}
+
+	return credentials.Value{AccessKeyID: string(key), SecretAccessKey: string(secret), ProviderName: "FileProvider"}, nil
+}
+
+func (p *fileProvider) IsExpired() bool {
+	return p.key.Changed() || p.secret.Changed()
+}
+
+func assumeRole(l log.Logger, c *aws.Config, roleArn string) *credentials.Credentials {
 	sess, err := session.NewSession(&aws.Config{
 		Credentials:                   c.Credentials,
 		Region:                        c.Region,
 		Endpoint:                      c.Endpoint,
 		DisableSSL:                    c.DisableSSL,
+		HTTPClient:                    c.HTTPClient,
 		CredentialsChainVerboseErrors: aws.Bool(true),
 	})
 	if err != nil {
 		level.Error(l).Log("msg", "s3 backend", "assume-role", err.Error())
 	}
 
-	creds, err := stscreds.NewCredentials(sess, roleArn, func(p *stscreds.AssumeRoleProvider) {
+	return stscreds.NewCredentials(sess, roleArn, func(p *stscreds.AssumeRoleProvider) {
 		p.RoleSessionName = "drone-cache"
-	}).Get()
-	if err != nil {
-		level.Error(l).Log("msg", "s3 backend", "assume-role", err.Error())
-	}
-
-	return creds
+	})
 }
 
 // Set the mode for SSL for S3 connectivity. Default mode is enabled.
@@ -6,17 +6,27 @@ package s3
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
 
+	"github.com/meltwater/drone-cache/storage/backend/backendtest"
+	"github.com/meltwater/drone-cache/storage/common"
 	"github.com/meltwater/drone-cache/test"
 )
 
@@ -49,7 +59,7 @@ func TestRoundTrip(t *testing.T) {
 		Key:        accessKey,
 		PathStyle:  true, // Should be true for minio and false for AWS.
 		Region:     defaultRegion,
-		Secret:     secretAccessKey,
+		Secret:     common.Secret(secretAccessKey),
 		DisableSSL: true, // minio unable to handle https requests
 	})
 	t.Cleanup(cleanUp)
@@ -67,7 +77,7 @@ func TestRoundTripWithAssumeRole(t *testing.T) {
 		Key:         userAccessKey,
 		PathStyle:   true, // Should be true for minio and false for AWS.
 		Region:      defaultRegion,
-		Secret:      userSecretAccessKey,
+		Secret:      common.Secret(userSecretAccessKey),
 		RoleArn:     "arn:aws:iam::account-id:role/TestRole",
 		DisableSSL:  true, // setting to true so minio doesn't crash
 	})
@@ -75,6 +85,138 @@ func TestRoundTripWithAssumeRole(t *testing.T) {
 	roundTrip(t, backend)
 }
 
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	backend, cleanUp := setup(t, Config{
+		ACL:        acl,
+		Bucket:     "s3-conformance",
+		Endpoint:   endpoint,
+		Key:        accessKey,
+		PathStyle:  true, // Should be true for minio and false for AWS.
+		Region:     defaultRegion,
+		Secret:     common.Secret(secretAccessKey),
+		DisableSSL: true, // minio unable to handle https requests
+	})
+	t.Cleanup(cleanUp)
+
+	backendtest.Run(t, backend, backendtest.WithPrefix(fmt.Sprintf("conformance-%d", time.Now().UnixNano())))
+}
+
+func TestMissingBucket(t *testing.T) {
+	t.Parallel()
+
+	backend, err := New(log.NewNopLogger(), Config{
+		ACL:        acl,
+		Bucket:     "s3-missing-bucket",
+		Endpoint:   endpoint,
+		Key:        accessKey,
+		PathStyle:  true, // Should be true for minio and false for AWS.
+		Region:     defaultRegion,
+		Secret:     common.Secret(secretAccessKey),
+		DisableSSL: true, // minio unable to handle https requests
+	}, false)
+	test.Ok(t, err)
+
+	// A missing bucket is a misconfiguration, not a cache miss.
+	err = backend.Get(context.TODO(), "test.t", ioutil.Discard)
+	test.Expected(t, err, common.ErrInvalidConfig)
+	test.Assert(t, !errors.Is(err, common.ErrNotFound), "missing bucket must not be a cache miss: %v", err)
+}
+
+func TestDebugRedactsCredentials(t *testing.T) {
+	t.Parallel()
+
+	var authorization string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(srv.Close)
+
+	var buf bytes.Buffer
+
+	backend, err := New(level.NewFilter(log.NewLogfmtLogger(&buf), level.AllowDebug()), Config{
+		Bucket:     "s3-debug",
+		Endpoint:   srv.URL,
+		Key:        "AKIDDEBUGTEST",
+		Secret:     common.Secret("debug-secret-access-key"),
+		PathStyle:  true,
+		Region:     defaultRegion,
+		DisableSSL: true,
+	}, true)
+	test.Ok(t, err)
+
+	_, err = backend.Exists(context.TODO(), "test.t")
+	test.Ok(t, err)
+
+	out := buf.String()
+	test.Assert(t, strings.Contains(out, "aws sdk"), "requests are not logged in debug mode: %s", out)
+	test.Assert(t, authorization != "" && !strings.Contains(out, authorization), "authorization is logged: %s", out)
+	test.Assert(t, !strings.Contains(out, "debug-secret-access-key"), "secret is logged: %s", out)
+}
+
+func TestAssumeRoleFollowsKeyFile(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu      sync.Mutex
+		assumed []string
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.FormValue("Action") != "AssumeRole" {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		mu.Lock()
+		assumed = append(assumed, r.Header.Get("Authorization"))
+		mu.Unlock()
+
+		// NOTICE: Credentials of the role are already expired, so that they are assumed again for every request.
+		fmt.Fprint(w, `<AssumeRoleResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/"><AssumeRoleResult>`+
+			`<Credentials><AccessKeyId>ASIAROLE</AccessKeyId><SecretAccessKey>role-secret</SecretAccessKey>`+
+			`<SessionToken>token</SessionToken><Expiration>2000-01-01T00:00:00Z</Expiration></Credentials>`+
+			`</AssumeRoleResult></AssumeRoleResponse>`)
+	}))
+	t.Cleanup(srv.Close)
+
+	keyFile := filepath.Join(t.TempDir(), "key")
+	test.Ok(t, os.WriteFile(keyFile, []byte("AKIDFIRST"), 0o600))
+
+	backend, err := New(log.NewNopLogger(), Config{
+		Bucket:      "s3-assume-role",
+		Endpoint:    srv.URL,
+		StsEndpoint: srv.URL,
+		KeyFile:     keyFile,
+		Secret:      common.Secret("secret-access-key"),
+		RoleArn:     "arn:aws:iam::account-id:role/TestRole",
+		PathStyle:   true,
+		Region:      defaultRegion,
+		DisableSSL:  true,
+	}, false)
+	test.Ok(t, err)
+
+	_, err = backend.Exists(context.TODO(), "test.t")
+	test.Ok(t, err)
+
+	test.Ok(t, os.WriteFile(keyFile, []byte("AKIDROTATED"), 0o600))
+
+	_, err = backend.Exists(context.TODO(), "test.t")
+	test.Ok(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	test.Equals(t, 2, len(assumed))
+	test.Assert(t, strings.Contains(assumed[0], "Credential=AKIDFIRST/"), "role is not assumed with the key: %s", assumed[0])
+	test.Assert(t, strings.Contains(assumed[1], "Credential=AKIDROTATED/"), "role is not assumed with the rotated key: %s", assumed[1])
+}
+
 func roundTrip(t *testing.T, backend *Backend) {
 	content := "Hello world4"
 
@@ -127,7 +269,7 @@ func newClient(config Config) *s3.S3 {
 		// DisableSSL:       aws.Bool(!strings.HasPrefix(endpoint, "https://")),
 		DisableSSL:       aws.Bool(true),
 		S3ForcePathStyle: aws.Bool(true),
-		Credentials:      credentials.NewStaticCredentials(config.Key, config.Secret, ""),
+		Credentials:      credentials.NewStaticCredentials(config.Key, string(config.Secret), ""),
 	}
 
 	return s3.New(session.Must(session.NewSessionWithOptions(session.Options{})), conf)
@@ -1,6 +1,10 @@
 package sftp
 
-import "time"
+import (
+	"time"
+
+	"github.com/meltwater/drone-cache/storage/common"
+)
 
 // SSHAuthMethod describes the type of authentication method.
 type SSHAuthMethod string
@@ -12,7 +16,9 @@ const (
 
 // SSHAuth is a structure to store authentication information for SSH connection.
 type SSHAuth struct {
-	Password      string
+	Password common.Secret
+	// PasswordFile is a file to read the password from, instead of Password. It is read when connecting.
+	PasswordFile  string
 	PublicKeyFile string
 	Method        SSHAuthMethod
 }
@@ -26,3 +32,26 @@ type Config struct {
 	Auth      SSHAuth
 	Timeout   time.Duration
 }
+
+// Validate reports every problem of the configuration.
+func (c Config) Validate() error {
+	var v common.Validation
+
+	v.Required("host", c.Host)
+	v.Port("port", c.Port)
+	v.Required("username", c.Username)
+	v.Required("cache root", c.CacheRoot)
+	v.OneOf("auth method", string(c.Auth.Method), string(SSHAuthMethodPassword), string(SSHAuthMethodPublicKeyFile))
+
+	switch c.Auth.Method {
+	case SSHAuthMethodPassword:
+		v.Required("password", string(c.Auth.Password)+c.Auth.PasswordFile)
+		v.SecretFile("password", c.Auth.Password, c.Auth.PasswordFile)
+	case SSHAuthMethodPublicKeyFile:
+		v.Required("public key file", c.Auth.PublicKeyFile)
+	}
+
+	v.NonNegative("timeout", int64(c.Timeout))
+
+	return v.Err()
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/meltwater/drone-cache/internal"
+	"github.com/meltwater/drone-cache/storage/common"
 	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ssh"
 )
@@ -72,7 +73,7 @@ func (b *Backend) Get(ctx context.Context, p string, w io.Writer) error {
 
 		rc, err := b.client.Open(path)
 		if err != nil {
-			errCh <- fmt.Errorf("get the object, %w", err)
+			errCh <- fmt.Errorf("get the object, %w", common.Classify(err))
 
 			return
 		}
@@ -81,7 +82,7 @@ func (b *Backend) Get(ctx context.Context, p string, w io.Writer) error {
 
 		_, err = io.Copy(w, rc)
 		if err != nil {
-			errCh <- fmt.Errorf("copy the object, %w", err)
+			errCh <- fmt.Errorf("copy the object, %w", common.Classify(err))
 		}
 	}()
 
@@ -105,14 +106,14 @@ func (b *Backend) Put(ctx context.Context, p string, r io.Reader) error {
 
 		dir := filepath.Dir(path)
 		if err := b.client.MkdirAll(dir); err != nil {
-			errCh <- fmt.Errorf("create directory, %w", err)
+			errCh <- fmt.Errorf("create directory, %w", common.Classify(err))
 
 			return
 		}
 
 		w, err := b.client.Create(path)
 		if err != nil {
-			errCh <- fmt.Errorf("create cache file, %w", err)
+			errCh <- fmt.Errorf("create cache file, %w", common.Classify(err))
 
 			return
 		}
@@ -120,11 +121,11 @@ func (b *Backend) Put(ctx context.Context, p string, r io.Reader) error {
 		defer internal.CloseWithErrLogf(b.logger, w, "writer close defer")
 
 		if _, err := io.Copy(w, r); err != nil {
-			errCh <- fmt.Errorf("write contents of reader to a file, %w", err)
+			errCh <- fmt.Errorf("write contents of reader to a file, %w", common.Classify(err))
 		}
 
 		if err := w.Close(); err != nil {
-			errCh <- fmt.Errorf("close the object, %w", err)
+			errCh <- fmt.Errorf("close the object, %w", common.Classify(err))
 		}
 	}()
 
@@ -156,7 +157,7 @@ func (b *Backend) Exists(ctx context.Context, p string) (bool, error) {
 
 		_, err := b.client.Stat(path)
 		if err != nil && !os.IsNotExist(err) {
-			resCh <- &result{err: fmt.Errorf("check the object exists, %w", err)}
+			resCh <- &result{err: fmt.Errorf("check the object exists, %w", common.Classify(err))}
 
 			return
 		}
@@ -172,12 +173,101 @@ func (b *Backend) Exists(ctx context.Context, p string) (bool, error) {
 	}
 }
 
+// List lists all the objects that are the given path or under it, recursively.
+func (b *Backend) List(ctx context.Context, p string) ([]common.FileEntry, error) {
+	type result struct {
+		val []common.FileEntry
+		err error
+	}
+
+	resCh := make(chan *result, 1)
+
+	go func() {
+		defer close(resCh)
+
+		var (
+			entries []common.FileEntry
+			walker  = b.client.Walk(filepath.Join(b.cacheRoot, p))
+		)
+
+		for walker.Step() {
+			if err := walker.Err(); err != nil {
+				if os.IsNotExist(err) {
+					break
+				}
+
+				resCh <- &result{err: fmt.Errorf("walk the objects, %w", common.Classify(err))}
+
+				return
+			}
+
+			fi := walker.Stat()
+			if fi.IsDir() {
+				continue
+			}
+
+			rel, err := filepath.Rel(b.cacheRoot, walker.Path())
+			if err != nil {
+				resCh <- &result{err: fmt.Errorf("relative path, %w", err)}
+
+				return
+			}
+
+			entries = append(entries, common.FileEntry{
+				Path:         filepath.ToSlash(rel),
+				Size:         fi.Size(),
+				LastModified: fi.ModTime(),
+			})
+		}
+
+		resCh <- &result{val: entries}
+	}()
+
+	select {
+	case res := <-resCh:
+		return res.val, res.err
+	case <-ctx.Done():
+		// nolint: wrapcheck
+		return nil, ctx.Err()
+	}
+}
+
+// Delete deletes the object with the given path.
+func (b *Backend) Delete(ctx context.Context, p string) error {
+	errCh := make(chan error, 1)
+
+	go func() {
+		defer close(errCh)
+
+		if err := b.client.Remove(filepath.Clean(filepath.Join(b.cacheRoot, p))); err != nil {
+			errCh <- fmt.Errorf("delete the object, %w", common.Classify(err))
+		}
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		// nolint: wrapcheck
+		return ctx.Err()
+	}
+}
+
 // Helpers
 
 func authMethod(c Config) ([]ssh.AuthMethod, error) {
 	switch c.Auth.Method {
 	case SSHAuthMethodPassword:
-		return []ssh.AuthMethod{ssh.Password(c.Auth.Password)}, nil
+		password, err := common.NewSecretFile(c.Auth.Password, c.Auth.PasswordFile)
+		if err != nil {
+			return nil, err // nolint: wrapcheck
+		}
+
+		return []ssh.AuthMethod{ssh.PasswordCallback(func() (string, error) {
+			secret, err := password.Read()
+
+			return string(secret), err
+		})}, nil
 	case SSHAuthMethodPublicKeyFile:
 		pkAuthMethod, err := readPublicKeyFile(c.Auth.PublicKeyFile)
 
@@ -5,11 +5,15 @@ package sftp
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/meltwater/drone-cache/storage/backend/backendtest"
+	"github.com/meltwater/drone-cache/storage/common"
 	"github.com/meltwater/drone-cache/test"
 
 	"github.com/go-kit/log"
@@ -57,6 +61,15 @@ func TestRoundTrip(t *testing.T) {
 	test.Equals(t, true, exists)
 }
 
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	backend, cleanUp := setup(t)
+	t.Cleanup(cleanUp)
+
+	backendtest.Run(t, backend, backendtest.WithPrefix(fmt.Sprintf("conformance-%d", time.Now().UnixNano())))
+}
+
 // Helpers
 
 func setup(t *testing.T) (*Backend, func()) {
@@ -66,7 +79,7 @@ func setup(t *testing.T) (*Backend, func()) {
 			CacheRoot: cacheRoot,
 			Username:  username,
 			Auth: SSHAuth{
-				Password: password,
+				Password: common.Secret(password),
 				Method:   SSHAuthMethodPassword,
 			},
 			Host: host,
@@ -0,0 +1,22 @@
+package shard
+
+import "github.com/meltwater/drone-cache/storage/common"
+
+// Config is a structure to store shard backend configuration.
+type Config struct {
+	// Replicas is the number of points of every shard on the hash ring. More points spread keys more evenly.
+	Replicas int
+}
+
+// Validate reports every problem of the configuration of a shard of the given number of backends.
+func (c Config) Validate(backends int) error {
+	var v common.Validation
+
+	if backends < 1 {
+		v.Addf("at least one backend is required")
+	}
+
+	v.NonNegative("replicas", int64(c.Replicas))
+
+	return v.Err()
+}
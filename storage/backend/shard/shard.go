@@ -0,0 +1,186 @@
+package shard
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+
+	"github.com/meltwater/drone-cache/internal"
+	"github.com/meltwater/drone-cache/storage/common"
+)
+
+// DefaultReplicas is the default number of points of every shard on the hash ring.
+const DefaultReplicas = 128
+
+// Backend is a composite backend that spreads objects across the given backends with consistent hashing.
+//
+// Shards are identified by their position, so new shards must be appended to the end.
+// Then adding a shard remaps only the share of objects it takes over, roughly 1/N of them.
+type Backend struct {
+	logger log.Logger
+
+	backends []common.Backend
+	ring     []point
+}
+
+type point struct {
+	hash  uint64
+	shard int
+}
+
+// New creates a shard backend of the given backends.
+func New(l log.Logger, c Config, backends ...common.Backend) (*Backend, error) {
+	if len(backends) == 0 {
+		return nil, errors.New("at least one backend is required")
+	}
+
+	replicas := c.Replicas
+	if replicas <= 0 {
+		replicas = DefaultReplicas
+	}
+
+	ring := make([]point, 0, len(backends)*replicas)
+
+	for i := range backends {
+		for r := 0; r < replicas; r++ {
+			ring = append(ring, point{hash: hash("shard-" + strconv.Itoa(i) + "-" + strconv.Itoa(r)), shard: i})
+		}
+	}
+
+	sort.Slice(ring, func(i, j int) bool { return ring[i].hash < ring[j].hash })
+
+	level.Debug(l).Log("msg", "shard backend", "backends", len(backends), "replicas", replicas)
+
+	return &Backend{logger: l, backends: backends, ring: ring}, nil
+}
+
+// Shard returns the index of the backend that the object with the given path is stored in.
+func (b *Backend) Shard(p string) int {
+	h := hash(strings.TrimLeft(p, "/"))
+
+	i := sort.Search(len(b.ring), func(i int) bool { return b.ring[i].hash >= h })
+	if i == len(b.ring) {
+		i = 0
+	}
+
+	return b.ring[i].shard
+}
+
+// Get writes downloaded content to the given writer.
+func (b *Backend) Get(ctx context.Context, p string, w io.Writer) error {
+	i := b.Shard(p)
+
+	if err := b.backends[i].Get(ctx, p, w); err != nil {
+		return fmt.Errorf("get from shard <%d>, %w", i, err)
+	}
+
+	return nil
+}
+
+// Put uploads contents of the given reader.
+func (b *Backend) Put(ctx context.Context, p string, r io.Reader) error {
+	i := b.Shard(p)
+
+	if err := b.backends[i].Put(ctx, p, r); err != nil {
+		return fmt.Errorf("put to shard <%d>, %w", i, err)
+	}
+
+	return nil
+}
+
+// Exists checks if object already exists.
+func (b *Backend) Exists(ctx context.Context, p string) (bool, error) {
+	i := b.Shard(p)
+
+	exists, err := b.backends[i].Exists(ctx, p)
+	if err != nil {
+		return false, fmt.Errorf("check existence in shard <%d>, %w", i, err)
+	}
+
+	return exists, nil
+}
+
+// List lists all the objects that are the given path or under it, recursively, from all shards in parallel.
+func (b *Backend) List(ctx context.Context, p string) ([]common.FileEntry, error) {
+	var (
+		wg      sync.WaitGroup
+		results = make([][]common.FileEntry, len(b.backends))
+		errs    = &internal.MultiError{}
+	)
+
+	for i, backend := range b.backends {
+		wg.Add(1)
+
+		go func(i int, backend common.Backend) {
+			defer wg.Done()
+
+			entries, err := backend.List(ctx, p)
+			if err != nil {
+				errs.Add(fmt.Errorf("list shard <%d>, %w", i, err))
+
+				return
+			}
+
+			results[i] = entries
+		}(i, backend)
+	}
+
+	wg.Wait()
+
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	var entries []common.FileEntry
+	for _, r := range results {
+		entries = append(entries, r...)
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+
+	return entries, nil
+}
+
+// Delete deletes the object with the given path.
+func (b *Backend) Delete(ctx context.Context, p string) error {
+	i := b.Shard(p)
+
+	if err := b.backends[i].Delete(ctx, p); err != nil {
+		return fmt.Errorf("delete from shard <%d>, %w", i, err)
+	}
+
+	return nil
+}
+
+// Close closes all backends which support it.
+func (b *Backend) Close() error {
+	errs := &internal.MultiError{}
+
+	for i, backend := range b.backends {
+		if c, ok := backend.(io.Closer); ok {
+			if err := c.Close(); err != nil {
+				errs.Add(fmt.Errorf("close shard <%d>, %w", i, err))
+			}
+		}
+	}
+
+	return errs.Err()
+}
+
+// Helpers
+
+func hash(s string) uint64 {
+	sum := sha256.Sum256([]byte(s))
+
+	return binary.BigEndian.Uint64(sum[:8])
+}
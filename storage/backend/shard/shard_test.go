@@ -0,0 +1,107 @@
+package shard
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+
+	"github.com/meltwater/drone-cache/storage/backend/backendtest"
+	"github.com/meltwater/drone-cache/storage/backend/filesystem"
+	"github.com/meltwater/drone-cache/storage/common"
+	"github.com/meltwater/drone-cache/test"
+)
+
+func TestRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	shards := []common.Backend{setup(t), setup(t), setup(t)}
+
+	backend, err := New(log.NewNopLogger(), Config{}, shards...)
+	test.Ok(t, err)
+
+	content := "Hello world4"
+
+	for i := 0; i < 30; i++ {
+		test.Ok(t, backend.Put(context.TODO(), fmt.Sprintf("repo/key-%d/test.t", i), strings.NewReader(content)))
+	}
+
+	for i, s := range shards {
+		entries, err := s.List(context.TODO(), "")
+		test.Ok(t, err)
+		test.Assert(t, len(entries) > 0, "shard <%d> received no objects", i)
+	}
+
+	var buf bytes.Buffer
+	test.Ok(t, backend.Get(context.TODO(), "repo/key-7/test.t", &buf))
+	test.Equals(t, content, buf.String())
+
+	exists, err := shards[backend.Shard("repo/key-7/test.t")].Exists(context.TODO(), "repo/key-7/test.t")
+	test.Ok(t, err)
+	test.Equals(t, true, exists)
+
+	entries, err := backend.List(context.TODO(), "repo")
+	test.Ok(t, err)
+	test.Equals(t, 30, len(entries))
+	test.Equals(t, "repo/key-0/test.t", entries[0].Path)
+
+	test.Ok(t, backend.Delete(context.TODO(), "repo/key-7/test.t"))
+
+	exists, err = backend.Exists(context.TODO(), "repo/key-7/test.t")
+	test.Ok(t, err)
+	test.Equals(t, false, exists)
+}
+
+func TestAddShard(t *testing.T) {
+	t.Parallel()
+
+	shards := []common.Backend{setup(t), setup(t), setup(t), setup(t)}
+
+	before, err := New(log.NewNopLogger(), Config{}, shards[:3]...)
+	test.Ok(t, err)
+
+	after, err := New(log.NewNopLogger(), Config{}, shards...)
+	test.Ok(t, err)
+
+	const keys = 10000
+
+	moved := 0
+
+	for i := 0; i < keys; i++ {
+		p := fmt.Sprintf("repo/key-%d/mount", i)
+
+		from, to := before.Shard(p), after.Shard(p)
+		if from != to {
+			test.Equals(t, 3, to)
+
+			moved++
+		}
+	}
+
+	// Ideally a quarter of the keys move to the new shard, none move between the existing ones.
+	test.Assert(t, moved > keys/8 && moved < keys/2, "unexpected number of remapped keys: %d", moved)
+}
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	backend, err := New(log.NewNopLogger(), Config{}, setup(t), setup(t), setup(t))
+	test.Ok(t, err)
+
+	backendtest.Run(t, backend)
+}
+
+// Helpers
+
+func setup(t *testing.T) *filesystem.Backend {
+	dir, cleanUp := test.CreateTempDir(t, "shard-test")
+	t.Cleanup(cleanUp)
+
+	b, err := filesystem.New(log.NewNopLogger(), filesystem.Config{CacheRoot: dir})
+	test.Ok(t, err)
+
+	return b
+}
@@ -0,0 +1,34 @@
+package tiered
+
+import (
+	"path/filepath"
+
+	"github.com/meltwater/drone-cache/storage/common"
+)
+
+// Config is a structure to store tiered backend configuration.
+type Config struct {
+	// Remote is the type of the backend used as L2, e.g. s3.
+	Remote string
+	// CacheRoot is the local directory used as L1.
+	CacheRoot string
+	// MaxSize is the maximum size of L1 in bytes, least recently used objects are evicted above it. Zero means unlimited.
+	MaxSize int64
+	// AsyncUpload uploads objects to L2 in the background, Close waits for pending uploads.
+	AsyncUpload bool
+}
+
+// Validate reports every problem of the configuration, the remote backend is validated by its own configuration.
+func (c Config) Validate() error {
+	var v common.Validation
+
+	v.Required("remote", c.Remote)
+
+	if c.CacheRoot == "" || filepath.Clean(c.CacheRoot) == "/" {
+		v.Addf("cache root <%s> must not be empty or root", c.CacheRoot)
+	}
+
+	v.NonNegative("max size", c.MaxSize)
+
+	return v.Err()
+}
@@ -0,0 +1,336 @@
+package tiered
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+
+	"github.com/meltwater/drone-cache/internal"
+	"github.com/meltwater/drone-cache/storage/backend/filesystem"
+	"github.com/meltwater/drone-cache/storage/common"
+)
+
+// staleTempAge is the time after which temporary files that are not written anymore are considered left behind,
+// e.g. by a build that was killed while filling the local tier.
+const staleTempAge = time.Hour
+
+// errFallback stops filling the local tier with an object of another path than the requested one.
+var errFallback = errors.New("fell back to another object")
+
+// Backend is a composite backend that keeps a local filesystem (L1) in front of a remote backend (L2).
+type Backend struct {
+	logger log.Logger
+
+	local  *filesystem.Backend
+	remote common.Backend
+
+	cacheRoot   string
+	maxSize     int64
+	asyncUpload bool
+
+	evictMu sync.Mutex
+
+	uploads   sync.WaitGroup
+	uploadsMu sync.Mutex
+	pending   map[string]struct{}
+	uploadErr internal.MultiError
+}
+
+// New creates a tiered backend using the given remote backend as L2.
+func New(l log.Logger, c Config, remote common.Backend) (*Backend, error) {
+	if remote == nil {
+		return nil, errors.New("remote backend is required")
+	}
+
+	if err := os.MkdirAll(c.CacheRoot, os.FileMode(0o755)); err != nil { // nolint: gomnd
+		return nil, fmt.Errorf("create local cache root <%s>, %w", c.CacheRoot, err)
+	}
+
+	local, err := filesystem.New(log.With(l, "tier", "local"), filesystem.Config{CacheRoot: c.CacheRoot})
+	if err != nil {
+		return nil, fmt.Errorf("initialize local tier, %w", err)
+	}
+
+	level.Debug(l).Log("msg", "tiered backend", "config", fmt.Sprintf("%#v", c))
+
+	return &Backend{
+		logger:      l,
+		local:       local,
+		remote:      remote,
+		cacheRoot:   c.CacheRoot,
+		maxSize:     c.MaxSize,
+		asyncUpload: c.AsyncUpload,
+		pending:     map[string]struct{}{},
+	}, nil
+}
+
+// Get writes downloaded content to the given writer.
+// Objects are served from the local tier when present, otherwise downloaded from remote and stored locally.
+func (b *Backend) Get(ctx context.Context, p string, w io.Writer) error {
+	exists, err := b.local.Exists(ctx, p)
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "check local tier", "path", p, "err", err)
+	}
+
+	if exists {
+		b.touch(p)
+
+		err := b.local.Get(ctx, p, w)
+		if err == nil {
+			level.Debug(b.logger).Log("msg", "local tier hit", "path", p)
+
+			return nil
+		}
+
+		// NOTICE: Nothing is written yet when the object is gone (e.g. evicted meanwhile), so fall back to remote.
+		if !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("get from local tier, %w", err)
+		}
+	}
+
+	level.Debug(b.logger).Log("msg", "local tier miss", "path", p)
+
+	pr, pw := io.Pipe()
+	fill := &fillWriter{w: pw}
+
+	errCh := make(chan error, 1)
+
+	go func() {
+		defer close(errCh)
+
+		err := b.local.Put(ctx, p, pr)
+		// Unblock remaining writes, when local tier stops reading early.
+		pr.CloseWithError(err) // nolint: errcheck
+
+		errCh <- err
+	}()
+
+	var fallback bool
+
+	// NOTICE: Objects of other paths, e.g. of restore keys, must not be stored locally as the requested one.
+	rctx := common.WithFallback(ctx, func(matched string) {
+		fallback = true
+
+		common.ReportFallback(ctx, matched)
+	})
+
+	// NOTICE: Failed fills are never renamed into place by the local tier, so that partial objects are never seen.
+	if err := b.remote.Get(rctx, p, io.MultiWriter(w, fill)); err != nil {
+		pw.CloseWithError(err) // nolint: errcheck
+		<-errCh
+
+		return fmt.Errorf("get from remote tier, %w", err)
+	}
+
+	if fallback {
+		pw.CloseWithError(errFallback) // nolint: errcheck
+		<-errCh
+
+		return nil
+	}
+
+	internal.CloseWithErrLogf(b.logger, pw, "local tier writer")
+
+	if err := <-errCh; err != nil || fill.err != nil {
+		level.Warn(b.logger).Log("msg", "fill local tier", "path", p, "err", err, "write_err", fill.err)
+
+		return nil
+	}
+
+	b.evict()
+
+	return nil
+}
+
+// Put uploads contents of the given reader to both tiers.
+func (b *Backend) Put(ctx context.Context, p string, r io.Reader) error {
+	if err := b.local.Put(ctx, p, r); err != nil {
+		return fmt.Errorf("put to local tier, %w", err)
+	}
+
+	if !b.asyncUpload {
+		defer b.evict()
+
+		return b.upload(ctx, p)
+	}
+
+	b.uploads.Add(1)
+	b.uploadsMu.Lock()
+	b.pending[p] = struct{}{}
+	b.uploadsMu.Unlock()
+
+	go func() {
+		defer b.uploads.Done()
+		defer b.evict()
+
+		// NOTICE: Request context ends as soon as Put returns, uploads are bound by Close instead.
+		err := b.upload(context.Background(), p)
+
+		b.uploadsMu.Lock()
+		defer b.uploadsMu.Unlock()
+
+		delete(b.pending, p)
+
+		if err != nil {
+			level.Error(b.logger).Log("msg", "async upload to remote tier", "path", p, "err", err)
+			b.uploadErr.Add(err)
+		}
+	}()
+
+	return nil
+}
+
+// Exists checks if object already exists in either of the tiers.
+func (b *Backend) Exists(ctx context.Context, p string) (bool, error) {
+	exists, err := b.local.Exists(ctx, p)
+	if err == nil && exists {
+		return true, nil
+	}
+
+	return b.remote.Exists(ctx, p) // nolint: wrapcheck
+}
+
+// List lists all the objects that are the given path or under it, recursively, as stored in the remote tier.
+func (b *Backend) List(ctx context.Context, p string) ([]common.FileEntry, error) {
+	return b.remote.List(ctx, p) // nolint: wrapcheck
+}
+
+// Delete deletes the object with the given path from both tiers.
+func (b *Backend) Delete(ctx context.Context, p string) error {
+	if err := b.local.Delete(ctx, p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("delete from local tier, %w", err)
+	}
+
+	if err := b.remote.Delete(ctx, p); err != nil {
+		return fmt.Errorf("delete from remote tier, %w", err)
+	}
+
+	return nil
+}
+
+// Close waits for pending uploads to the remote tier and reports their errors.
+func (b *Backend) Close() error {
+	b.uploads.Wait()
+
+	b.uploadsMu.Lock()
+	defer b.uploadsMu.Unlock()
+
+	return b.uploadErr.Err()
+}
+
+// Helpers
+
+func (b *Backend) upload(ctx context.Context, p string) error {
+	pr, pw := io.Pipe()
+	defer internal.CloseWithErrLogf(b.logger, pr, "pr close defer")
+
+	go func() {
+		defer internal.CloseWithErrLogf(b.logger, pw, "pw close defer")
+
+		if err := b.local.Get(ctx, p, pw); err != nil {
+			if err := pw.CloseWithError(fmt.Errorf("get from local tier, %w", err)); err != nil {
+				level.Error(b.logger).Log("msg", "pw close", "err", err)
+			}
+		}
+	}()
+
+	if err := b.remote.Put(ctx, p, pr); err != nil {
+		return fmt.Errorf("put to remote tier, %w", err)
+	}
+
+	return nil
+}
+
+// evict removes stale temporary files of the local tier,
+// and deletes least recently used objects from it until it fits into the maximum size.
+func (b *Backend) evict() {
+	b.evictMu.Lock()
+	defer b.evictMu.Unlock()
+
+	if err := b.local.RemoveTemp(staleTempAge); err != nil {
+		level.Warn(b.logger).Log("msg", "remove stale temporary files of local tier", "err", err)
+	}
+
+	if b.maxSize <= 0 {
+		return
+	}
+
+	entries, err := b.local.List(context.Background(), "")
+	if err != nil {
+		level.Warn(b.logger).Log("msg", "list local tier for eviction", "err", err)
+
+		return
+	}
+
+	var size int64
+	for _, e := range entries {
+		size += e.Size
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].LastModified.Before(entries[j].LastModified) })
+
+	for _, e := range entries {
+		if size <= b.maxSize {
+			return
+		}
+
+		if b.isPending(e.Path) {
+			// Objects which are not uploaded yet only live in the local tier.
+			continue
+		}
+
+		if err := b.local.Delete(context.Background(), e.Path); err != nil {
+			level.Warn(b.logger).Log("msg", "evict from local tier", "path", e.Path, "err", err)
+
+			continue
+		}
+
+		level.Debug(b.logger).Log("msg", "evicted from local tier", "path", e.Path, "size", e.Size)
+
+		size -= e.Size
+	}
+}
+
+func (b *Backend) isPending(p string) bool {
+	b.uploadsMu.Lock()
+	defer b.uploadsMu.Unlock()
+
+	_, ok := b.pending[p]
+
+	return ok
+}
+
+// touch marks the object as recently used.
+func (b *Backend) touch(p string) {
+	now := time.Now()
+	if err := os.Chtimes(filepath.Join(b.cacheRoot, p), now, now); err != nil {
+		level.Debug(b.logger).Log("msg", "touch local object", "path", p, "err", err)
+	}
+}
+
+// fillWriter writes to the local tier on a best effort basis, so that a failing local tier never fails a download.
+type fillWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (f *fillWriter) Write(p []byte) (int, error) {
+	if f.err != nil {
+		return len(p), nil
+	}
+
+	if _, err := f.w.Write(p); err != nil {
+		f.err = err
+	}
+
+	return len(p), nil
+}
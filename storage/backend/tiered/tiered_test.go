@@ -0,0 +1,133 @@
+package tiered
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/meltwater/drone-cache/storage/backend/backendtest"
+	"github.com/meltwater/drone-cache/storage/backend/filesystem"
+	"github.com/meltwater/drone-cache/test"
+)
+
+func TestRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	for _, async := range []bool{false, true} {
+		backend, remote, _ := setup(t, Config{AsyncUpload: async})
+
+		content := "Hello world4"
+		test.Ok(t, backend.Put(context.TODO(), "test.t", strings.NewReader(content)))
+		test.Ok(t, backend.Close())
+
+		var buf bytes.Buffer
+		test.Ok(t, remote.Get(context.TODO(), "test.t", &buf))
+		test.Equals(t, content, buf.String())
+
+		buf.Reset()
+		test.Ok(t, backend.Get(context.TODO(), "test.t", &buf))
+		test.Equals(t, content, buf.String())
+
+		exists, err := backend.Exists(context.TODO(), "test.t")
+		test.Ok(t, err)
+		test.Equals(t, true, exists)
+	}
+}
+
+func TestGetFillsLocalTier(t *testing.T) {
+	t.Parallel()
+
+	backend, remote, root := setup(t, Config{})
+
+	content := "Hello world4"
+	test.Ok(t, remote.Put(context.TODO(), "repo/key/test.t", strings.NewReader(content)))
+
+	var buf bytes.Buffer
+	test.Ok(t, backend.Get(context.TODO(), "repo/key/test.t", &buf))
+	test.Equals(t, content, buf.String())
+
+	test.Exists(t, filepath.Join(root, "repo/key/test.t"))
+
+	// Served from the local tier, once it is removed from remote.
+	test.Ok(t, remote.Delete(context.TODO(), "repo/key/test.t"))
+
+	buf.Reset()
+	test.Ok(t, backend.Get(context.TODO(), "repo/key/test.t", &buf))
+	test.Equals(t, content, buf.String())
+
+	test.NotOk(t, backend.Get(context.TODO(), "missing", &buf))
+
+	_, err := os.Stat(filepath.Join(root, "missing"))
+	test.Assert(t, os.IsNotExist(err), "partial object is left in local tier: %v", err)
+}
+
+func TestEviction(t *testing.T) {
+	t.Parallel()
+
+	backend, _, root := setup(t, Config{MaxSize: 10})
+
+	test.Ok(t, backend.Put(context.TODO(), "old", strings.NewReader("12345")))
+	test.Ok(t, os.Chtimes(filepath.Join(root, "old"), time.Now().Add(-time.Hour), time.Now().Add(-time.Hour)))
+	test.Ok(t, backend.Put(context.TODO(), "recent", strings.NewReader("12345")))
+	test.Ok(t, backend.Put(context.TODO(), "new", strings.NewReader("12345")))
+
+	_, err := os.Stat(filepath.Join(root, "old"))
+	test.Assert(t, os.IsNotExist(err), "least recently used object is not evicted: %v", err)
+	test.Exists(t, filepath.Join(root, "recent"))
+	test.Exists(t, filepath.Join(root, "new"))
+
+	// Evicted objects are still served from remote.
+	var buf bytes.Buffer
+	test.Ok(t, backend.Get(context.TODO(), "old", &buf))
+	test.Equals(t, "12345", buf.String())
+}
+
+func TestEvictionRemovesStaleTemp(t *testing.T) {
+	t.Parallel()
+
+	backend, _, root := setup(t, Config{})
+
+	// Left behind by a build that was killed while filling the local tier.
+	stale := filepath.Join(root, ".test.t.123.tmp")
+	test.Ok(t, os.WriteFile(stale, []byte("Hello"), 0o600))
+	test.Ok(t, os.Chtimes(stale, time.Now().Add(-2*staleTempAge), time.Now().Add(-2*staleTempAge)))
+
+	test.Ok(t, backend.Put(context.TODO(), "test.t", strings.NewReader("Hello world4")))
+
+	_, err := os.Stat(stale)
+	test.Assert(t, os.IsNotExist(err), "stale temporary file is not removed: %v", err)
+}
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	backend, _, _ := setup(t, Config{})
+
+	backendtest.Run(t, backend)
+}
+
+// Helpers
+
+func setup(t *testing.T, c Config) (*Backend, *filesystem.Backend, string) {
+	remoteDir, remoteClean := test.CreateTempDir(t, "tiered-remote")
+	t.Cleanup(remoteClean)
+
+	localDir, localClean := test.CreateTempDir(t, "tiered-local")
+	t.Cleanup(localClean)
+
+	remote, err := filesystem.New(log.NewNopLogger(), filesystem.Config{CacheRoot: remoteDir})
+	test.Ok(t, err)
+
+	c.CacheRoot = localDir
+
+	b, err := New(log.NewNopLogger(), c, remote)
+	test.Ok(t, err)
+
+	return b, remote, localDir
+}
@@ -0,0 +1,101 @@
+package backend
+
+import (
+	"fmt"
+
+	"github.com/meltwater/drone-cache/storage/common"
+)
+
+// ErrInvalidConfig means that the configuration of a backend is invalid, use errors.As with
+// *common.ValidationError to get every problem of it.
+var ErrInvalidConfig = common.ErrInvalidConfig
+
+// Validate reports every problem of the configuration of the given backend type,
+// including the configurations of the backends that it wraps or consists of.
+func Validate(backendType string, cfg Config) error {
+	if _, ok := lookup(backendType); !ok {
+		return fmt.Errorf("<%s>, %w", backendType, ErrUnknownBackend)
+	}
+
+	cfg = cfg.withTransport()
+
+	var v common.Validation
+
+	v.Merge("", validate(backendType, cfg))
+
+	switch backendType {
+	case Tiered:
+		validateWrapped(&v, "remote", Tiered, cfg.Tiered.Remote, cfg)
+	case Fault:
+		validateWrapped(&v, "wrapped backend", Fault, cfg.Fault.Backend, cfg)
+	case Mirror, Shard:
+		for i, spec := range cfg.Backends {
+			name := fmt.Sprintf("backend <%d>", i)
+			if IsComposite(spec.Type) {
+				v.Addf("%s, composite backend <%s> can not be nested", name, spec.Type)
+
+				continue
+			}
+
+			v.Merge(name, Validate(spec.Type, spec.Config))
+		}
+	}
+
+	return v.Err()
+}
+
+// Helpers
+
+// validate validates the own configuration of the given backend type, but not of the backends it consists of,
+// as those are validated when they are initialized.
+func validate(backendType string, cfg Config) error {
+	switch backendType {
+	case Azure:
+		return cfg.Azure.Validate() // nolint: wrapcheck
+	case S3:
+		return cfg.S3.Validate() // nolint: wrapcheck
+	case GCS:
+		return cfg.GCS.Validate() // nolint: wrapcheck
+	case FileSystem:
+		return cfg.FileSystem.Validate() // nolint: wrapcheck
+	case SFTP:
+		return cfg.SFTP.Validate() // nolint: wrapcheck
+	case FTP:
+		return cfg.FTP.Validate() // nolint: wrapcheck
+	case AliOSS:
+		return cfg.Alioss.Validate() // nolint: wrapcheck
+	case HTTP:
+		return cfg.HTTP.Validate() // nolint: wrapcheck
+	case OCI:
+		return cfg.OCI.Validate() // nolint: wrapcheck
+	case Redis:
+		return cfg.Redis.Validate() // nolint: wrapcheck
+	case GHA:
+		return cfg.GHA.Validate() // nolint: wrapcheck
+	case Tiered:
+		return cfg.Tiered.Validate() // nolint: wrapcheck
+	case Fault:
+		return cfg.Fault.Validate() // nolint: wrapcheck
+	case Mirror:
+		return cfg.Mirror.Validate(len(cfg.Backends)) // nolint: wrapcheck
+	case Shard:
+		return cfg.Shard.Validate(len(cfg.Backends)) // nolint: wrapcheck
+	}
+
+	if v, ok := lookupValidator(backendType); ok {
+		return v(cfg)
+	}
+
+	return nil
+}
+
+func validateWrapped(v *common.Validation, name, backendType, wrapped string, cfg Config) {
+	switch wrapped {
+	case "":
+		return
+	case backendType:
+		v.Addf("%s, %s backend can not wrap itself", name, backendType)
+	default:
+		v.Merge(fmt.Sprintf("%s <%s>", name, wrapped), Validate(wrapped, cfg))
+	}
+}
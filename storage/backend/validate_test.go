@@ -0,0 +1,190 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/meltwater/drone-cache/storage/backend/azure"
+	"github.com/meltwater/drone-cache/storage/backend/filesystem"
+	"github.com/meltwater/drone-cache/storage/backend/gcs"
+	"github.com/meltwater/drone-cache/storage/backend/http"
+	"github.com/meltwater/drone-cache/storage/backend/mirror"
+	"github.com/meltwater/drone-cache/storage/backend/s3"
+	"github.com/meltwater/drone-cache/storage/backend/sftp"
+	"github.com/meltwater/drone-cache/storage/backend/tiered"
+	"github.com/meltwater/drone-cache/storage/common"
+	"github.com/meltwater/drone-cache/test"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name        string
+		backendType string
+		cfg         Config
+		problems    []string
+	}{
+		{
+			name:        "valid-s3",
+			backendType: S3,
+			cfg:         Config{S3: s3.Config{Bucket: "bucket", Endpoint: "http://minio:9000", ACL: "private"}},
+		},
+		{
+			name:        "invalid-s3",
+			backendType: S3,
+			cfg:         Config{S3: s3.Config{Key: "key", Endpoint: "ftp://minio", Encryption: "rot13"}},
+			problems: []string{
+				"bucket is required",
+				"<access key> and <secret key> must be set together",
+				"unknown endpoint scheme <ftp>, expected one of <http>, <https>",
+				"unknown encryption <rot13>, expected one of <AES256>, <aws:kms>",
+			},
+		},
+		{
+			name:        "invalid-azure",
+			backendType: Azure,
+			cfg:         Config{Azure: azure.Config{AccountName: "account", AccountKey: "not base64!", BlobStorageURL: "https://blob.core.windows.net"}},
+			problems: []string{
+				"container name is required",
+				"blob storage url <https://blob.core.windows.net> must be a host without scheme",
+				"account key is not base64 encoded",
+			},
+		},
+		{
+			name:        "exclusive-gcs-keys",
+			backendType: GCS,
+			cfg:         Config{GCS: gcs.Config{Bucket: "bucket", APIKey: "key", JSONKey: "{"}},
+			problems: []string{
+				"<api key> and <json key> are mutually exclusive, set only one of them",
+				"json key is not valid JSON",
+			},
+		},
+		{
+			name:        "invalid-http",
+			backendType: HTTP,
+			cfg:         Config{HTTP: http.Config{URL: "cache.local/path", Headers: []string{"X-Team"}}},
+			problems: []string{
+				"url <cache.local/path> is not an absolute URL",
+				`header <X-Team> is not in "Name: value" form`,
+			},
+		},
+		{
+			name:        "shared-transport",
+			backendType: HTTP,
+			cfg: Config{
+				HTTP:      http.Config{URL: "https://cache.local"},
+				Transport: common.TransportConfig{CertFile: "validate_test.go", ProxyURL: "proxy.local:3128"},
+			},
+			problems: []string{
+				"transport, <client certificate file> and <client key file> must be set together",
+				"transport, proxy url <proxy.local:3128> is not an absolute URL",
+			},
+		},
+		{
+			name:        "tiered-with-invalid-remote",
+			backendType: Tiered,
+			cfg:         Config{Tiered: tiered.Config{Remote: S3, CacheRoot: "/tmp/cache"}},
+			problems:    []string{"remote <s3>, bucket is required"},
+		},
+		{
+			name:        "mirror-with-invalid-backends",
+			backendType: Mirror,
+			cfg: Config{
+				Mirror: mirror.Config{WriteQuorum: 3},
+				Backends: []Spec{
+					{Type: FileSystem, Config: Config{FileSystem: filesystem.Config{CacheRoot: "/"}}},
+					{Type: Shard},
+				},
+			},
+			problems: []string{
+				"write quorum <3> must be between 0 and the number of backends <2>",
+				"backend <0>, cache root </> must not be empty or root",
+				"backend <1>, composite backend <shard> can not be nested",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := Validate(tc.backendType, tc.cfg)
+			if len(tc.problems) == 0 {
+				test.Ok(t, err)
+
+				return
+			}
+
+			test.Expected(t, err, ErrInvalidConfig)
+
+			var verr *common.ValidationError
+			test.Assert(t, errors.As(err, &verr), "expected validation error, got <%v>", err)
+			test.Equals(t, tc.problems, verr.Problems)
+		})
+	}
+}
+
+func TestFromConfigInvalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := FromConfig(log.NewNopLogger(), FileSystem, Config{})
+	test.Expected(t, err, ErrInvalidConfig)
+}
+
+func TestFromConfigMirrorInvalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := FromConfig(log.NewNopLogger(), Mirror, Config{
+		Backends: []Spec{
+			{Type: FileSystem, Config: Config{FileSystem: filesystem.Config{CacheRoot: t.TempDir()}}},
+			{Type: FileSystem},
+		},
+	})
+	test.Expected(t, err, ErrInvalidConfig)
+}
+
+func TestFromConfigMirrorUnreachable(t *testing.T) {
+	t.Parallel()
+
+	// NOTICE: Closed right away, so that nothing listens on the port.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	test.Ok(t, err)
+	_, port, err := net.SplitHostPort(l.Addr().String())
+	test.Ok(t, err)
+	test.Ok(t, l.Close())
+
+	unreachable := Spec{Type: SFTP, Config: Config{SFTP: sftp.Config{
+		Host:      "127.0.0.1",
+		Port:      port,
+		Username:  "drone",
+		CacheRoot: "/cache",
+		Auth:      sftp.SSHAuth{Method: sftp.SSHAuthMethodPassword, Password: "s3cr3t"},
+		Timeout:   time.Second,
+	}}}
+	healthy := Spec{Type: FileSystem, Config: Config{FileSystem: filesystem.Config{CacheRoot: t.TempDir()}}}
+
+	// Unreachable backends count against the write quorum, instead of failing the mirror.
+	b, err := FromConfig(log.NewNopLogger(), Mirror, Config{Backends: []Spec{unreachable, healthy}, Mirror: mirror.Config{WriteQuorum: 1}})
+	test.Ok(t, err)
+	test.Ok(t, b.Put(context.TODO(), "test.t", strings.NewReader("Hello world4")))
+
+	var buf bytes.Buffer
+	test.Ok(t, b.Get(context.TODO(), "test.t", &buf))
+	test.Equals(t, "Hello world4", buf.String())
+
+	b, err = FromConfig(log.NewNopLogger(), Mirror, Config{Backends: []Spec{unreachable, healthy}})
+	test.Ok(t, err)
+	test.Expected(t, b.Put(context.TODO(), "test.t", strings.NewReader("Hello world4")), mirror.ErrQuorumNotReached)
+
+	_, err = FromConfig(log.NewNopLogger(), Mirror, Config{Backends: []Spec{unreachable}})
+	test.NotOk(t, err)
+}
@@ -0,0 +1,47 @@
+// Package common provides types and helpers shared by the storage backends.
+package common
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+)
+
+// FileEntry defines a single cache item.
+type FileEntry struct {
+	Path         string
+	Size         int64
+	LastModified time.Time
+}
+
+// Backend implements operations for caching files.
+type Backend interface {
+	// Get writes downloaded content to the given writer.
+	Get(ctx context.Context, p string, w io.Writer) error
+
+	// Put uploads contents of the given reader.
+	Put(ctx context.Context, p string, r io.Reader) error
+
+	// Exists checks if path already exists.
+	Exists(ctx context.Context, p string) (bool, error)
+
+	// List lists all the objects that are the given path or under it, recursively.
+	List(ctx context.Context, p string) ([]FileEntry, error)
+
+	// Delete deletes the object with the given path.
+	Delete(ctx context.Context, p string) error
+}
+
+// InPrefix reports whether the object with given path is the given prefix itself or lives under it.
+// Prefixes are treated as directories, "a/b" matches "a/b" and "a/b/c" but not "a/bc", empty prefix matches all.
+func InPrefix(path, prefix string) bool {
+	prefix = strings.Trim(prefix, "/")
+	path = strings.TrimLeft(path, "/")
+
+	if prefix == "" || path == prefix {
+		return true
+	}
+
+	return strings.HasPrefix(path, prefix+"/")
+}
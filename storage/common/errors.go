@@ -0,0 +1,113 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+)
+
+// Classes of errors that backends map their errors onto, to let callers branch on them with errors.Is.
+var (
+	// ErrNotFound means that the object does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrUnauthorized means that the credentials are missing, invalid or not allowed to access the object.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrTimeout means that the operation did not finish in time.
+	ErrTimeout = errors.New("timeout")
+	// ErrIntegrity means that the transferred content does not match its checksum or expected size.
+	ErrIntegrity = errors.New("integrity check failed")
+	// ErrQuotaExceeded means that the storage is full or a limit of the object size or number of requests is hit.
+	ErrQuotaExceeded = errors.New("quota exceeded")
+)
+
+// ErrNotSupported means that the backend does not support the operation, e.g. deleting objects.
+var ErrNotSupported = errors.New("operation not supported")
+
+// Error is an error of a backend classified as one of the classes of errors, e.g. ErrNotFound.
+type Error struct {
+	// Class is one of the classes of errors.
+	Class error
+	// Err is the original error of the backend.
+	Err error
+}
+
+// NewError classifies the given error as the given class, it returns nil if the error is nil.
+func NewError(class, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if class == nil || errors.Is(err, class) {
+		return err
+	}
+
+	return &Error{Class: class, Err: err}
+}
+
+// Error returns the message of the original error, as backends already describe the failure.
+func (e *Error) Error() string { return e.Err.Error() }
+
+// Unwrap returns the original error of the backend.
+func (e *Error) Unwrap() error { return e.Err }
+
+// Is reports whether the error is of the given class.
+func (e *Error) Is(target error) bool { return target == e.Class } // nolint: errorlint
+
+// Classify classifies the errors of the standard library that all backends may return,
+// such as context deadlines, network timeouts and file system errors. Other errors are returned as is.
+func Classify(err error) error {
+	return NewError(Class(err), err)
+}
+
+// ClassifyStatus classifies the given error with the class of the given HTTP status code.
+func ClassifyStatus(code int, err error) error {
+	return NewError(StatusClass(code), err)
+}
+
+// StatusClass returns the class of errors of the given HTTP status code, nil if it is not one of them.
+func StatusClass(code int) error {
+	switch code {
+	case http.StatusNotFound, http.StatusGone:
+		return ErrNotFound
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusProxyAuthRequired:
+		return ErrUnauthorized
+	case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+		return ErrTimeout
+	case http.StatusRequestEntityTooLarge, http.StatusTooManyRequests, http.StatusInsufficientStorage:
+		return ErrQuotaExceeded
+	default:
+		return nil
+	}
+}
+
+// Class returns the class of the given error, or of the errors of the standard library that it corresponds to.
+// It returns nil if the error is not of any of the classes.
+func Class(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	for _, class := range []error{ErrNotFound, ErrUnauthorized, ErrTimeout, ErrIntegrity, ErrQuotaExceeded} {
+		if errors.Is(err, class) {
+			return class
+		}
+	}
+
+	var nerr net.Error
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &nerr) && nerr.Timeout():
+		return ErrTimeout
+	case errors.Is(err, os.ErrNotExist):
+		return ErrNotFound
+	case errors.Is(err, os.ErrPermission):
+		return ErrUnauthorized
+	case errors.Is(err, syscall.ENOSPC), errors.Is(err, syscall.EDQUOT), errors.Is(err, syscall.EFBIG):
+		return ErrQuotaExceeded
+	default:
+		return nil
+	}
+}
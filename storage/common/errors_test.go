@@ -0,0 +1,60 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/meltwater/drone-cache/test"
+)
+
+func TestClassify(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		err  error
+		want error
+	}{
+		"deadline":   {err: fmt.Errorf("get, %w", context.DeadlineExceeded), want: ErrTimeout},
+		"not exist":  {err: &os.PathError{Op: "open", Path: "a", Err: os.ErrNotExist}, want: ErrNotFound},
+		"permission": {err: &os.PathError{Op: "open", Path: "a", Err: os.ErrPermission}, want: ErrUnauthorized},
+		"classified": {err: NewError(ErrIntegrity, os.ErrNotExist), want: ErrIntegrity},
+	} {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := Classify(tc.err)
+			test.Expected(t, err, tc.want)
+			test.Expected(t, err, tc.err)
+			test.Equals(t, tc.err.Error(), err.Error())
+		})
+	}
+}
+
+func TestClassifyUnknown(t *testing.T) {
+	t.Parallel()
+
+	err := errors.New("unknown")
+
+	test.Assert(t, Classify(err) == err, "unknown errors must be returned as is")                                               // nolint: errorlint
+	test.Assert(t, ClassifyStatus(http.StatusInternalServerError, err) == err, "unknown status codes must not classify errors") // nolint: errorlint
+	test.Ok(t, Classify(nil))
+}
+
+func TestClassifyStatus(t *testing.T) {
+	t.Parallel()
+
+	for code, want := range map[int]error{
+		http.StatusNotFound:            ErrNotFound,
+		http.StatusForbidden:           ErrUnauthorized,
+		http.StatusGatewayTimeout:      ErrTimeout,
+		http.StatusTooManyRequests:     ErrQuotaExceeded,
+		http.StatusInsufficientStorage: ErrQuotaExceeded,
+	} {
+		test.Expected(t, ClassifyStatus(code, errors.New("unexpected status code")), want)
+	}
+}
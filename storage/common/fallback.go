@@ -0,0 +1,19 @@
+package common
+
+import "context"
+
+type fallbackKey struct{}
+
+// WithFallback returns a context that backends report to, with ReportFallback, that Get wrote another object
+// than the requested one, e.g. the object of a restore key. The given function is called with its path.
+func WithFallback(ctx context.Context, report func(p string)) context.Context {
+	return context.WithValue(ctx, fallbackKey{}, report)
+}
+
+// ReportFallback reports that Get wrote the object of the given path instead of the requested one,
+// to the function of WithFallback if the context has one.
+func ReportFallback(ctx context.Context, p string) {
+	if report, ok := ctx.Value(fallbackKey{}).(func(string)); ok {
+		report(p)
+	}
+}
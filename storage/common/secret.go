@@ -0,0 +1,118 @@
+package common
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Redacted replaces secrets when they are formatted.
+const Redacted = "[REDACTED]"
+
+// Secret is a string that is redacted when it is formatted, to keep credentials out of logs and debug output.
+// Convert it to a string to use its value.
+type Secret string
+
+// String returns Redacted, or an empty string if the secret is not set.
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+
+	return Redacted
+}
+
+// GoString returns the quoted Redacted, so that structs printed with %#v do not reveal it.
+func (s Secret) GoString() string {
+	return strconv.Quote(s.String())
+}
+
+// Format formats the secret redacted with all verbs.
+func (s Secret) Format(f fmt.State, verb rune) {
+	switch {
+	case verb == 'q', verb == 'v' && f.Flag('#'):
+		io.WriteString(f, s.GoString()) // nolint: errcheck
+	default:
+		io.WriteString(f, s.String()) // nolint: errcheck
+	}
+}
+
+// SecretFile is a secret that is read from a file, and read again when the file changes, e.g. when an agent rotates it.
+// A SecretFile without a path holds the secret that it is created with.
+type SecretFile struct {
+	path string
+
+	mu      sync.Mutex
+	secret  Secret
+	modTime time.Time
+	size    int64
+}
+
+// NewSecretFile reads the secret from the file of the given path, or holds the given secret if the path is empty.
+func NewSecretFile(secret Secret, path string) (*SecretFile, error) {
+	f := &SecretFile{path: path, secret: secret}
+	if path == "" {
+		return f, nil
+	}
+
+	if _, err := f.Read(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Path returns the path of the file, empty if the secret is not read from a file.
+func (f *SecretFile) Path() string { return f.path }
+
+// Read returns the secret, read again if the file has changed since it was last read.
+// Trailing line breaks are trimmed, as files written by agents often end with one.
+// If the file can not be read, the last secret is returned along with the error.
+func (f *SecretFile) Read() (Secret, error) {
+	if f.path == "" {
+		return f.secret, nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return f.secret, fmt.Errorf("stat secret file <%s>, %w", f.path, err)
+	}
+
+	if info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return f.secret, nil
+	}
+
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return f.secret, fmt.Errorf("read secret file <%s>, %w", f.path, err)
+	}
+
+	f.secret = Secret(strings.TrimRight(string(data), "\r\n"))
+	f.modTime, f.size = info.ModTime(), info.Size()
+
+	return f.secret, nil
+}
+
+// Changed reports whether the file has changed since the secret was last read.
+func (f *SecretFile) Changed() bool {
+	if f.path == "" {
+		return false
+	}
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return !info.ModTime().Equal(f.modTime) || info.Size() != f.size
+}
@@ -0,0 +1,72 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/meltwater/drone-cache/test"
+)
+
+func TestSecretFormat(t *testing.T) {
+	t.Parallel()
+
+	c := struct {
+		User     string
+		Password Secret
+	}{"user", "hunter2"}
+
+	for _, format := range []string{"%s", "%v", "%+v", "%#v", "%q"} {
+		got := fmt.Sprintf(format, c)
+		test.Assert(t, !strings.Contains(got, "hunter2"), "secret is revealed with %s: %s", format, got)
+		test.Assert(t, strings.Contains(got, Redacted), "secret is not redacted with %s: %s", format, got)
+	}
+
+	test.Equals(t, "hunter2", string(c.Password))
+	test.Equals(t, "", Secret("").String())
+}
+
+func TestSecretFile(t *testing.T) {
+	t.Parallel()
+
+	static, err := NewSecretFile("hunter2", "")
+	test.Ok(t, err)
+
+	got, err := static.Read()
+	test.Ok(t, err)
+	test.Equals(t, Secret("hunter2"), got)
+	test.Assert(t, !static.Changed(), "static secret must never change")
+
+	path := filepath.Join(t.TempDir(), "token")
+	test.Ok(t, os.WriteFile(path, []byte("first\n"), 0o600))
+
+	f, err := NewSecretFile("", path)
+	test.Ok(t, err)
+
+	got, err = f.Read()
+	test.Ok(t, err)
+	test.Equals(t, Secret("first"), got)
+	test.Assert(t, !f.Changed(), "file is not changed yet")
+
+	// Rotate the secret, with a distinct modification time as file systems may have coarse timestamps.
+	test.Ok(t, os.WriteFile(path, []byte("second\r\n"), 0o600))
+	test.Ok(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+	test.Assert(t, f.Changed(), "file is changed")
+
+	got, err = f.Read()
+	test.Ok(t, err)
+	test.Equals(t, Secret("second"), got)
+
+	// The last secret is kept if the file disappears.
+	test.Ok(t, os.Remove(path))
+
+	got, err = f.Read()
+	test.NotOk(t, err)
+	test.Equals(t, Secret("second"), got)
+
+	_, err = NewSecretFile("", path)
+	test.NotOk(t, err)
+}
@@ -0,0 +1,144 @@
+package common
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+const defaultKeepAlive = 30 * time.Second
+
+// ErrNoCertificates means that the CA bundle does not contain any PEM encoded certificate.
+var ErrNoCertificates = errors.New("no certificates found")
+
+// TransportConfig configures the HTTP transport of the backends that speak HTTP,
+// e.g. to trust a corporate CA, present a client certificate or go through a proxy.
+// The zero value leaves the transports of the backends as they are.
+type TransportConfig struct {
+	// CAFile is a PEM bundle of certificate authorities to trust, in addition to the ones of the system.
+	CAFile string
+	// CertFile and KeyFile are the PEM encoded client certificate and its key, for mutual TLS.
+	CertFile string
+	KeyFile  string
+	// ProxyURL is the proxy to send requests through, the HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+	// environment variables are used when empty.
+	ProxyURL string
+
+	ConnectTimeout time.Duration
+	IdleTimeout    time.Duration
+	DisableHTTP2   bool
+}
+
+// IsZero reports whether nothing is configured.
+func (c TransportConfig) IsZero() bool {
+	return c == TransportConfig{}
+}
+
+// Validate returns an error listing every problem of the configuration, nil if it is valid.
+func (c TransportConfig) Validate() error {
+	var v Validation
+
+	v.File("ca file", c.CAFile)
+	v.Together("client certificate file", "client key file", c.CertFile != "", c.KeyFile != "")
+	v.File("client certificate file", c.CertFile)
+	v.File("client key file", c.KeyFile)
+	v.URL("proxy url", c.ProxyURL, "http", "https", "socks5")
+	v.NonNegative("connect timeout", int64(c.ConnectTimeout))
+	v.NonNegative("idle timeout", int64(c.IdleTimeout))
+
+	return v.Err()
+}
+
+// NewTransport returns a transport with the defaults of http.DefaultTransport and the configured settings.
+func (c TransportConfig) NewTransport() (*http.Transport, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone() // nolint: forcetypeassert
+
+	if c.ProxyURL != "" {
+		u, err := url.Parse(c.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("parse proxy url, %w", err)
+		}
+
+		t.Proxy = http.ProxyURL(u)
+	}
+
+	if c.ConnectTimeout > 0 {
+		t.DialContext = (&net.Dialer{Timeout: c.ConnectTimeout, KeepAlive: defaultKeepAlive}).DialContext
+	}
+
+	if c.IdleTimeout > 0 {
+		t.IdleConnTimeout = c.IdleTimeout
+	}
+
+	tlsConfig, err := c.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if tlsConfig != nil {
+		t.TLSClientConfig = tlsConfig
+	}
+
+	if c.DisableHTTP2 {
+		// NOTICE: A non-nil empty map is what disables HTTP/2, see the documentation of net/http.
+		t.ForceAttemptHTTP2 = false
+		t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	return t, nil
+}
+
+// NewClient returns a client with the transport of NewTransport.
+func (c TransportConfig) NewClient() (*http.Client, error) {
+	t, err := c.NewTransport()
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Client{Transport: t}, nil
+}
+
+// Helpers
+
+func (c TransportConfig) tlsConfig() (*tls.Config, error) {
+	if c.CAFile == "" && c.CertFile == "" {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read ca file, %w", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("ca file <%s>, %w", c.CAFile, ErrNoCertificates)
+		}
+
+		cfg.RootCAs = pool
+	}
+
+	if c.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate, %w", err)
+		}
+
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,128 @@
+package common
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/meltwater/drone-cache/test"
+)
+
+func TestTransportConfigIsZero(t *testing.T) {
+	t.Parallel()
+
+	test.Assert(t, TransportConfig{}.IsZero(), "empty transport config must be zero")
+	test.Assert(t, !TransportConfig{DisableHTTP2: true}.IsZero(), "transport config with settings must not be zero")
+}
+
+func TestTransportConfigMutualTLS(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	certFile, keyFile, clientCert := writeClientCertificate(t, dir)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	srv.EnableHTTP2 = true
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs, MinVersion: tls.VersionTLS12}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	caFile := filepath.Join(dir, "ca.pem")
+	test.Ok(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0o600))
+
+	c := TransportConfig{CAFile: caFile, CertFile: certFile, KeyFile: keyFile, ConnectTimeout: time.Second}
+	test.Ok(t, c.Validate())
+
+	resp := get(t, c, srv.URL)
+	test.Equals(t, http.StatusNoContent, resp.StatusCode)
+	test.Equals(t, 2, resp.ProtoMajor)
+
+	c.DisableHTTP2 = true
+	resp = get(t, c, srv.URL)
+	test.Equals(t, 1, resp.ProtoMajor)
+
+	// Server requires the client certificate.
+	client, err := TransportConfig{CAFile: caFile}.NewClient()
+	test.Ok(t, err)
+
+	_, err = client.Get(srv.URL) // nolint: noctx
+	test.NotOk(t, err)
+}
+
+func TestTransportConfigInvalid(t *testing.T) {
+	t.Parallel()
+
+	c := TransportConfig{CAFile: "transport_test.go"}
+	test.Ok(t, c.Validate())
+
+	_, err := c.NewTransport()
+	test.Expected(t, err, ErrNoCertificates)
+
+	c = TransportConfig{CertFile: "missing.pem", ProxyURL: "ftp://proxy.local", IdleTimeout: -time.Second}
+	test.Expected(t, c.Validate(), ErrInvalidConfig)
+}
+
+// Helpers
+
+func get(t *testing.T, c TransportConfig, url string) *http.Response {
+	t.Helper()
+
+	client, err := c.NewClient()
+	test.Ok(t, err)
+
+	resp, err := client.Get(url) // nolint: noctx
+	test.Ok(t, err)
+	resp.Body.Close()
+
+	return resp
+}
+
+func writeClientCertificate(t *testing.T, dir string) (string, string, *x509.Certificate) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	test.Ok(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "drone-cache"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	test.Ok(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	test.Ok(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	test.Ok(t, err)
+
+	certFile, keyFile := filepath.Join(dir, "client.pem"), filepath.Join(dir, "client-key.pem")
+	test.Ok(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	test.Ok(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	return certFile, keyFile, cert
+}
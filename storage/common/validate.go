@@ -0,0 +1,171 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidConfig means that the configuration of a backend is invalid.
+var ErrInvalidConfig = errors.New("invalid configuration")
+
+// ValidationError lists every problem of an invalid configuration.
+type ValidationError struct {
+	Problems []string
+}
+
+// Error returns the problems of the configuration, separated by semicolons.
+func (e *ValidationError) Error() string {
+	return ErrInvalidConfig.Error() + ": " + strings.Join(e.Problems, "; ")
+}
+
+// Is reports whether the target is ErrInvalidConfig.
+func (e *ValidationError) Is(target error) bool { return target == ErrInvalidConfig } // nolint: errorlint
+
+// Validation collects the problems of a configuration, so that all of them are reported at once.
+type Validation struct {
+	problems []string
+}
+
+// Addf adds a problem.
+func (v *Validation) Addf(format string, args ...interface{}) {
+	v.problems = append(v.problems, fmt.Sprintf(format, args...))
+}
+
+// Required adds a problem if the value of the given field is empty.
+func (v *Validation) Required(field, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.Addf("%s is required", field)
+	}
+}
+
+// Exclusive adds a problem if both of the given fields are set.
+func (v *Validation) Exclusive(a, b string, aSet, bSet bool) {
+	if aSet && bSet {
+		v.Addf("<%s> and <%s> are mutually exclusive, set only one of them", a, b)
+	}
+}
+
+// Together adds a problem if only some of the given fields are set.
+func (v *Validation) Together(a, b string, aSet, bSet bool) {
+	if aSet != bSet {
+		v.Addf("<%s> and <%s> must be set together", a, b)
+	}
+}
+
+// NonNegative adds a problem if the value of the given field is negative.
+func (v *Validation) NonNegative(field string, value int64) {
+	if value < 0 {
+		v.Addf("%s must not be negative, got <%d>", field, value)
+	}
+}
+
+// OneOf adds a problem if the value of the given field is not one of the allowed values.
+func (v *Validation) OneOf(field, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+
+	v.Addf("unknown %s <%s>, expected one of <%s>", field, value, strings.Join(allowed, ">, <"))
+}
+
+// URL adds a problem if the value of the given field is set and it is not an absolute URL with one of the given schemes.
+func (v *Validation) URL(field, value string, schemes ...string) {
+	if value == "" {
+		return
+	}
+
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		v.Addf("%s <%s> is not an absolute URL", field, value)
+
+		return
+	}
+
+	v.OneOf(field+" scheme", u.Scheme, schemes...)
+}
+
+// Endpoint adds a problem if the value of the given field is set and it is neither an http(s) URL nor a host[:port].
+func (v *Validation) Endpoint(field, value string) {
+	if value == "" {
+		return
+	}
+
+	if strings.Contains(value, "://") {
+		v.URL(field, value, "http", "https")
+
+		return
+	}
+
+	u, err := url.Parse("//" + value)
+	if err != nil || u.Host == "" || strings.ContainsAny(value, " \t") {
+		v.Addf("%s <%s> is neither a URL nor a host", field, value)
+	}
+}
+
+// Port adds a problem if the value of the given field is set and it is not a port number.
+func (v *Validation) Port(field, value string) {
+	if value == "" {
+		return
+	}
+
+	if p, err := strconv.Atoi(value); err != nil || p < 1 || p > 65535 { // nolint: gomnd
+		v.Addf("%s <%s> is not a port number", field, value)
+	}
+}
+
+// SecretFile adds a problem if both the secret and the file to read it from are given, or the file can not be read.
+func (v *Validation) SecretFile(field string, secret Secret, path string) {
+	v.Exclusive(field, field+" file", secret != "", path != "")
+
+	v.File(field+" file", path)
+}
+
+// File adds a problem if the given path is set and the file can not be read.
+func (v *Validation) File(field, path string) {
+	if path == "" {
+		return
+	}
+
+	if f, err := os.Open(path); err != nil {
+		v.Addf("%s <%s> can not be read, %v", field, path, err)
+	} else {
+		f.Close()
+	}
+}
+
+// Merge adds the problems of the given error of a nested configuration, prefixed with the given name if not empty.
+func (v *Validation) Merge(name string, err error) {
+	if err == nil {
+		return
+	}
+
+	problems := []string{err.Error()}
+
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		problems = verr.Problems
+	}
+
+	for _, p := range problems {
+		if name != "" {
+			p = name + ", " + p
+		}
+
+		v.problems = append(v.problems, p)
+	}
+}
+
+// Err returns a ValidationError of the collected problems, nil if there is none.
+func (v *Validation) Err() error {
+	if len(v.problems) == 0 {
+		return nil
+	}
+
+	return &ValidationError{Problems: append([]string{}, v.problems...)}
+}
@@ -0,0 +1,44 @@
+package common
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/meltwater/drone-cache/test"
+)
+
+func TestValidation(t *testing.T) {
+	t.Parallel()
+
+	var nested Validation
+	nested.Required("host", "")
+
+	var v Validation
+	v.Required("name", " ")
+	v.Endpoint("endpoint", "minio:9000")
+	v.Endpoint("endpoint", "https://storage.example.com/path")
+	v.Endpoint("endpoint", "bad host")
+	v.Port("port", "22")
+	v.Port("port", "70000")
+	v.NonNegative("size", -1)
+	v.SecretFile("password", "hunter2", "validate_test.go")
+	v.Merge("backend <0>", nested.Err())
+	v.Merge("backend <1>", errors.New("unknown backend"))
+
+	err := v.Err()
+	test.Expected(t, err, ErrInvalidConfig)
+
+	var verr *ValidationError
+	test.Assert(t, errors.As(err, &verr), "expected validation error, got <%v>", err)
+	test.Equals(t, []string{
+		"name is required",
+		"endpoint <bad host> is neither a URL nor a host",
+		"port <70000> is not a port number",
+		"size must not be negative, got <-1>",
+		"<password> and <password file> are mutually exclusive, set only one of them",
+		"backend <0>, host is required",
+		"backend <1>, unknown backend",
+	}, verr.Problems)
+
+	test.Ok(t, (&Validation{}).Err())
+}
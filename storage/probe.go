@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/meltwater/drone-cache/storage/common"
+)
+
+// ProbePrefix is the prefix of the names of the canary objects that Probe writes.
+const ProbePrefix = ".drone-cache-probe-"
+
+const probeSize = 1024
+
+// Probe writes, checks, reads back and deletes a canary object under the given prefix,
+// to make sure that the storage is reachable and its credentials allow the operations of caching.
+// Backends that do not support deleting objects keep the canary.
+func Probe(s Storage, prefix string) error {
+	canary := make([]byte, probeSize)
+	if _, err := rand.Read(canary); err != nil {
+		return fmt.Errorf("generate canary, %w", err)
+	}
+
+	p := path.Join(prefix, ProbePrefix+hex.EncodeToString(canary[:8]))
+
+	if err := s.Put(p, bytes.NewReader(canary)); err != nil {
+		return fmt.Errorf("write canary <%s>, %w", p, err)
+	}
+
+	if err := check(s, p, canary); err != nil {
+		// NOTICE: Canary is deleted anyway, not to leave it behind.
+		_ = s.Delete(p)
+
+		return err
+	}
+
+	if err := s.Delete(p); err != nil && !errors.Is(err, common.ErrNotSupported) {
+		return fmt.Errorf("delete canary <%s>, %w", p, err)
+	}
+
+	return nil
+}
+
+// Helpers
+
+func check(s Storage, p string, canary []byte) error {
+	ok, err := s.Exists(p)
+	if err != nil {
+		return fmt.Errorf("check canary <%s>, %w", p, err)
+	}
+
+	if !ok {
+		return fmt.Errorf("check canary <%s>, %w", p, common.NewError(common.ErrNotFound, errors.New("written canary does not exist")))
+	}
+
+	var buf bytes.Buffer
+	if err := s.Get(p, &buf); err != nil {
+		return fmt.Errorf("read canary <%s>, %w", p, err)
+	}
+
+	if !bytes.Equal(buf.Bytes(), canary) {
+		return fmt.Errorf("read canary <%s>, %w", p, common.NewError(common.ErrIntegrity,
+			fmt.Errorf("read %d bytes do not match written %d bytes", buf.Len(), len(canary))))
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-kit/log"
+
+	"github.com/meltwater/drone-cache/storage/backend/fault"
+	"github.com/meltwater/drone-cache/storage/backend/memory"
+	"github.com/meltwater/drone-cache/test"
+)
+
+func TestProbe(t *testing.T) {
+	t.Parallel()
+
+	b := memory.New()
+	s := New(log.NewNopLogger(), b, DefaultOperationTimeout)
+
+	test.Ok(t, Probe(s, "repo"))
+
+	entries, err := s.List("")
+	test.Ok(t, err)
+	test.Equals(t, 0, len(entries))
+}
+
+func TestProbeFailures(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name  string
+		cfg   fault.Config
+		class error
+	}{
+		{name: "failing-put", cfg: fault.Config{ErrorRate: 1, Operations: []string{fault.OpPut}}, class: fault.ErrInjected},
+		{name: "failing-get", cfg: fault.Config{ErrorRate: 1, Operations: []string{fault.OpGet}}, class: fault.ErrInjected},
+		{name: "truncated-get", cfg: fault.Config{TruncateAfter: 16}, class: ErrIntegrity},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := memory.New()
+			b, err := fault.New(log.NewNopLogger(), tc.cfg, m)
+			test.Ok(t, err)
+
+			s := New(log.NewNopLogger(), b, DefaultOperationTimeout)
+
+			err = Probe(s, "repo")
+			test.Assert(t, errors.Is(err, tc.class), "expected error of class <%v>, got <%v>", tc.class, err)
+
+			entries, err := New(log.NewNopLogger(), m, DefaultOperationTimeout).List("")
+			test.Ok(t, err)
+			test.Equals(t, 0, len(entries))
+		})
+	}
+}
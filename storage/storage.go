@@ -4,14 +4,26 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"sync"
 	"time"
 
 	"github.com/go-kit/log"
 	"github.com/meltwater/drone-cache/storage/backend"
+	"github.com/meltwater/drone-cache/storage/common"
 )
 
 const DefaultOperationTimeout = 3 * time.Minute
 
+// Classes of errors of the storage operations, backends map their errors onto them.
+// Use errors.Is to check them, e.g. to tell a cache miss from an access denied.
+var (
+	ErrNotFound      = common.ErrNotFound
+	ErrUnauthorized  = common.ErrUnauthorized
+	ErrTimeout       = common.ErrTimeout
+	ErrIntegrity     = common.ErrIntegrity
+	ErrQuotaExceeded = common.ErrQuotaExceeded
+)
+
 // Storage is a place that files can be written to and read from.
 type Storage interface {
 	// Get writes contents of the given object with given key from remote storage to io.Writer.
@@ -30,6 +42,14 @@ type Storage interface {
 	Delete(p string) error
 }
 
+// FallbackGetter is implemented by storages that tell whether Get wrote another object than the requested one,
+// as backends may fall back to, e.g. the object of a restore key.
+type FallbackGetter interface {
+	// GetFallback writes contents like Get, and returns the path of the object that is written
+	// instead of the requested one, empty if it is the requested one.
+	GetFallback(p string, w io.Writer) (string, error)
+}
+
 // Default Storage implementation.
 type storage struct {
 	logger log.Logger
@@ -45,14 +65,36 @@ func New(l log.Logger, b backend.Backend, timeout time.Duration) Storage {
 
 // Get writes contents of the given object with given key from remote storage to io.Writer.
 func (s *storage) Get(p string, w io.Writer) error {
+	_, err := s.GetFallback(p, w)
+
+	return err
+}
+
+// GetFallback writes contents like Get, and returns the path of the object that the backend fell back to, if any.
+func (s *storage) GetFallback(p string, w io.Writer) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
 	defer cancel()
 
+	var (
+		mu       sync.Mutex
+		fallback string
+	)
+
+	ctx = common.WithFallback(ctx, func(matched string) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		fallback = matched
+	})
+
 	if err := s.b.Get(ctx, p, w); err != nil {
-		return fmt.Errorf("storage backend get failure, %w", err)
+		return "", fmt.Errorf("storage backend get failure, %w", common.Classify(err))
 	}
 
-	return nil
+	mu.Lock()
+	defer mu.Unlock()
+
+	return fallback, nil
 }
 
 // Put writes contents of io.Reader to remote storage at given key location.
@@ -61,7 +103,7 @@ func (s *storage) Put(p string, r io.Reader) error {
 	defer cancel()
 
 	if err := s.b.Put(ctx, p, r); err != nil {
-		return fmt.Errorf("storage backend put failure, %w", err)
+		return fmt.Errorf("storage backend put failure, %w", common.Classify(err))
 	}
 
 	return nil
@@ -74,7 +116,7 @@ func (s *storage) Exists(p string) (bool, error) {
 
 	ret, err := s.b.Exists(ctx, p)
 	if err != nil {
-		return ret, fmt.Errorf("storage backend exists failure, %w", err)
+		return ret, fmt.Errorf("storage backend exists failure, %w", common.Classify(err))
 	}
 
 	return ret, nil
@@ -82,14 +124,25 @@ func (s *storage) Exists(p string) (bool, error) {
 
 // List lists contents of the given directory by given key from remote storage.
 func (s *storage) List(p string) ([]backend.FileEntry, error) {
-	// Implement me!
-	// Make sure consumer utilizes context.
-	return []backend.FileEntry{}, nil
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	entries, err := s.b.List(ctx, p)
+	if err != nil {
+		return nil, fmt.Errorf("storage backend list failure, %w", common.Classify(err))
+	}
+
+	return entries, nil
 }
 
 // Delete deletes the object from remote storage.
 func (s *storage) Delete(p string) error {
-	// Implement me!
-	// Make sure consumer utilizes context.
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	if err := s.b.Delete(ctx, p); err != nil {
+		return fmt.Errorf("storage backend delete failure, %w", common.Classify(err))
+	}
+
 	return nil
 }